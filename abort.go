@@ -0,0 +1,61 @@
+package mm010_nrc_api
+
+import (
+	"context"
+
+	"mm010_nrc_api/protocol"
+)
+
+// Abort ends the command exchange in flight, typically a long Dispense, and
+// makes it return ErrAborted. The MM010 has no cancel command and finishes
+// moving the notes already picked, so Abort sends EOT to end the exchange on
+// the device's side and drops whatever response still arrives. LastStatus
+// tells how many notes went out once the device is idle again. Abort returns
+// ErrNothingToAbort if no command is running.
+func (s *MMDispenser) Abort() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.abort == nil {
+		return ErrNothingToAbort
+	}
+
+	s.aborted = true
+	s.abort()
+
+	return nil
+}
+
+// abortable derives the context of an exchange that Abort can cancel. finish
+// must be called when the exchange returned and reports whether it was
+// aborted.
+func (s *MMDispenser) abortable(ctx context.Context) (context.Context, func() bool) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	s.mu.Lock()
+	s.abort, s.aborted = cancel, false
+	s.mu.Unlock()
+
+	return ctx, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		cancel()
+		s.abort = nil
+
+		return s.aborted
+	}
+}
+
+// resync brings the line back to idle after an aborted exchange.
+func (s *MMDispenser) resync() {
+	s.log().Infof("exchange aborted")
+
+	if _, err := s.write([]byte{protocol.Eot}); err != nil {
+		s.log().Errorf("send EOT after abort: %v", err)
+	}
+
+	if err := s.flush(); err != nil {
+		s.log().Errorf("flush after abort: %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package mm010_nrc_api_test
+
+import (
+	"bytes"
+	"context"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/protocol"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAbortDispense(t *testing.T) {
+	var mu sync.Mutex
+	busy := true
+
+	port := newFakePort(func(p []byte) [][]byte {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if busy && len(p) > 3 && p[3] == byte(protocol.CommandDispense) {
+			// the device accepted the dispense and is still moving notes
+			return [][]byte{{protocol.Ack}}
+		}
+
+		return answer(statusPayload)(p)
+	})
+	c := api.NewTransportConnection("abort", port, api.WithTimeout(time.Second),
+		api.WithBlockedSensorPolicy(api.IgnoreBlockedSensor))
+
+	if err := c.Abort(); err != api.ErrNothingToAbort {
+		t.Fatalf("expected ErrNothingToAbort, got %v", err)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = c.Abort()
+	}()
+
+	start := time.Now()
+
+	if _, err := c.DispenseContext(context.Background(), 20); err != api.ErrAborted || time.Since(start) > time.Second {
+		t.Fatalf("expected ErrAborted right away, got %v after %v", err, time.Since(start))
+	}
+
+	port.mu.Lock()
+	last := port.written[len(port.written)-1]
+	port.mu.Unlock()
+
+	if !bytes.Equal(last, []byte{protocol.Eot}) {
+		t.Fatalf("expected EOT after the abort, got %X", last)
+	}
+
+	mu.Lock()
+	busy = false
+	mu.Unlock()
+
+	if _, err := c.StatusContext(context.Background()); err != nil {
+		t.Fatalf("link not usable after abort: %v", err)
+	}
+}
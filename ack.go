@@ -0,0 +1,42 @@
+package mm010_nrc_api
+
+import "mm010_nrc_api/protocol"
+
+// AckFunc decides how the host answers a valid response text: true ACKs it,
+// false NAKs it so the device sends it again. Like observers it is called
+// while the link is held, so it must not block or issue commands.
+type AckFunc func(command Command, text []byte) bool
+
+// WithAckHandler replaces the automatic ACK of every valid response text by
+// fn; nil turns automatic acknowledgement back on. A handler that keeps
+// answering NAK leaves the command to run into its read timeout.
+func WithAckHandler(fn AckFunc) Option {
+	return func(s *MMDispenser) {
+		s.ackHandler = fn
+	}
+}
+
+// acknowledge answers the accepted response text of command and returns it,
+// or nil if it was answered NAK and a repetition is expected.
+func (s *MMDispenser) acknowledge(command Command, text []byte) ([]byte, error) {
+	if s.ackHandler == nil || s.ackHandler(command, text) {
+		return text, s.sendControl(protocol.Ack)
+	}
+
+	s.link.Reject()
+
+	return nil, s.sendControl(protocol.Nack)
+}
+
+func (s *MMDispenser) sendControl(b byte) error {
+	switch b {
+	case protocol.Ack:
+		s.log().Debugf("-> ACK")
+	case protocol.Nack:
+		s.log().Debugf("-> NAK")
+	}
+
+	_, err := s.write([]byte{b})
+
+	return err
+}
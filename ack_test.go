@@ -0,0 +1,37 @@
+package mm010_nrc_api_test
+
+import (
+	api "mm010_nrc_api"
+	"mm010_nrc_api/mm010sim"
+	"mm010_nrc_api/protocol"
+	"testing"
+	"time"
+)
+
+func TestAckHandler(t *testing.T) {
+	sim := mm010sim.New()
+	calls := 0
+
+	// NAK the first dispense response, so the device has to repeat it
+	c := api.NewTransportConnection("sim", sim.Conn(), api.WithTimeout(time.Second), api.WithGuardTime(0),
+		api.WithAckHandler(func(command api.Command, text []byte) bool {
+			if command != protocol.CommandDispense {
+				return true
+			}
+
+			calls++
+
+			return calls > 1
+		}))
+
+	defer sim.Close()
+	defer c.Close()
+
+	if _, dispensed, _, err := c.Dispense(2); err != nil || dispensed != 2 {
+		t.Fatalf("dispense: %d, %v", dispensed, err)
+	}
+
+	if calls != 2 || sim.Notes() != 998 {
+		t.Fatalf("handler called %d times, %d notes left", calls, sim.Notes())
+	}
+}
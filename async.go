@@ -0,0 +1,43 @@
+package mm010_nrc_api
+
+import "context"
+
+type DispenseOutcome struct {
+	Result DispenseResult
+	Err    error
+}
+
+type StatusOutcome struct {
+	Status Status
+	Err    error
+}
+
+// DispenseAsync starts DispenseContext in the background and delivers its
+// outcome on the returned channel, which is buffered and closed afterwards.
+// Cancelling ctx aborts the exchange in flight.
+func (s *MMDispenser) DispenseAsync(ctx context.Context, count byte) <-chan DispenseOutcome {
+	res := make(chan DispenseOutcome, 1)
+
+	go func() {
+		defer close(res)
+
+		r, err := s.DispenseContext(ctx, count)
+		res <- DispenseOutcome{Result: r, Err: err}
+	}()
+
+	return res
+}
+
+// StatusAsync is the background variant of StatusContext, see DispenseAsync.
+func (s *MMDispenser) StatusAsync(ctx context.Context) <-chan StatusOutcome {
+	res := make(chan StatusOutcome, 1)
+
+	go func() {
+		defer close(res)
+
+		status, err := s.StatusContext(ctx)
+		res <- StatusOutcome{Status: status, Err: err}
+	}()
+
+	return res
+}
@@ -0,0 +1,39 @@
+package mm010_nrc_api_test
+
+import (
+	"context"
+	api "mm010_nrc_api"
+	"testing"
+	"time"
+)
+
+func TestAsyncCommands(t *testing.T) {
+	c := api.NewTransportConnection("async", newFakePort(answer(statusPayload)), api.WithTimeout(time.Second))
+
+	if out := <-c.StatusAsync(context.Background()); out.Err != nil || !out.Status.FeedSensorBlocked {
+		t.Fatalf("unexpected status outcome %+v", out)
+	}
+
+	silent := api.NewTransportConnection("silent", newFakePort(func(p []byte) [][]byte { return nil }),
+		api.WithTimeout(time.Minute), api.WithBlockedSensorPolicy(api.IgnoreBlockedSensor))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pending := silent.DispenseAsync(ctx, 1)
+
+	select {
+	case out := <-pending:
+		t.Fatalf("dispense finished early with %+v", out)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancel()
+
+	select {
+	case out := <-pending:
+		if out.Err != context.Canceled {
+			t.Fatalf("expected context.Canceled, got %v", out.Err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("cancel did not abort the dispense")
+	}
+}
@@ -0,0 +1,191 @@
+package mm010_nrc_api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"mm010_nrc_api/protocol"
+)
+
+// AuditRecord describes one cash moving command: Dispense, TestDispense,
+// SingleNoteDispense, SingleNoteEject, Purge or Reset. Records are written
+// whether or not the command succeeded.
+type AuditRecord struct {
+	// Seq increases by one with every record of a connection. AuditLog numbers
+	// the records itself, across all connections sharing the log.
+	Seq uint64 `json:"seq"`
+	// Time is when the command started, or the time given by the time source
+	// when the record was written, see WithAuditTimeSource.
+	Time time.Time `json:"time"`
+	// TimeToken is the proof of Time the time source gave, if any.
+	TimeToken []byte `json:"time_token,omitempty"`
+	// UntrustedTime marks a Time read from the local clock only, because no
+	// time source is set or it failed.
+	UntrustedTime bool   `json:"untrusted_time,omitempty"`
+	Device        string `json:"device"`
+	// Client is the client of the command, see ContextWithClient.
+	Client    string `json:"client,omitempty"`
+	Command   string `json:"command"`
+	Requested int    `json:"requested"`
+	Dispensed int    `json:"dispensed"`
+	// Rejected holds the notes sent to the reject bin, for Purge the purged ones.
+	Rejected int `json:"rejected"`
+	// Status is nil when no status code was received.
+	Status *StatusCode `json:"status,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+type AuditLogger interface {
+	Audit(r AuditRecord) error
+}
+
+// Timestamp is a point in time vouched for by a TimeSource.
+type Timestamp struct {
+	Time time.Time
+	// Token is proof of Time, e.g. an RFC 3161 time stamp token, if the
+	// source issues one.
+	Token []byte
+}
+
+// TimeSource gives the time of audit records, e.g. a clock checked against
+// NTP or a time stamping authority. It is called while the link is held, so
+// it should answer from what it validated before rather than ask the network.
+type TimeSource interface {
+	Timestamp() (Timestamp, error)
+}
+
+// WithAuditTimeSource takes the time of audit records from ts. Records whose
+// time comes from the local clock, because ts failed or none is set, are
+// marked UntrustedTime.
+func WithAuditTimeSource(ts TimeSource) Option {
+	return func(s *MMDispenser) {
+		s.auditTime = ts
+	}
+}
+
+// WithAuditLogger passes a record of every cash moving command to l. It is
+// called after the observers and before the command returns, so a dispense is
+// on record before the caller sees its result. It is called while the link is
+// held, so it must not issue commands.
+func WithAuditLogger(l AuditLogger) Option {
+	return func(s *MMDispenser) {
+		s.auditLogger = l
+		s.auditSeq = 0
+	}
+}
+
+func (s *MMDispenser) audit(ctx context.Context, command Command, started time.Time, data [][]byte, response []byte, err error) {
+	if s.auditLogger == nil || !(movesNotes(command) || command == protocol.CommandPurge || command == protocol.CommandReset) {
+		return
+	}
+
+	s.auditSeq++
+	r := AuditRecord{Seq: s.auditSeq, Time: started, UntrustedTime: true, Device: s.name,
+		Client: ClientFromContext(ctx), Command: command.String()}
+
+	if s.auditTime != nil {
+		if ts, err := s.auditTime.Timestamp(); err != nil {
+			s.log().Errorf("audit record of %v: time source: %v", command, err)
+		} else {
+			r.Time, r.TimeToken, r.UntrustedTime = ts.Time, ts.Token, false
+		}
+	}
+
+	if command != protocol.CommandPurge && command != protocol.CommandReset {
+		r.Requested = s.requestedNotes(command, data...)
+	}
+
+	v, _ := s.decodeText(command, response)
+
+	switch res := v.(type) {
+	case protocol.PurgeResponse:
+		r.Status = &res.Status
+		r.Rejected = int(res.Purged)
+	case protocol.DispenseResponse:
+		r.Status = &res.Status
+		r.Dispensed = int(res.Dispensed)
+		r.Rejected = int(res.Rejected)
+	}
+
+	if err != nil {
+		r.Error = err.Error()
+	}
+
+	if err := s.auditLogger.Audit(r); err != nil {
+		s.log().Errorf("audit record of %v: %v", command, err)
+	}
+}
+
+// AuditLog is an AuditLogger appending one JSON object per line to a file.
+// It gives every record the next sequence number of the log, so several
+// connections, e.g. of a Pool, can share it. Every record is synced to disk
+// before Audit returns.
+type AuditLog struct {
+	mu   sync.Mutex
+	f    *os.File
+	last uint64
+}
+
+// OpenAuditLog opens or creates the log at path and reads the last sequence
+// number from it.
+func OpenAuditLog(path string) (*AuditLog, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+
+	if err != nil {
+		return nil, err
+	}
+
+	l := &AuditLog{f: f}
+	sc := bufio.NewScanner(f)
+
+	for sc.Scan() {
+		var r AuditRecord
+
+		// a torn last line after a crash must not keep the log from opening
+		if json.Unmarshal(sc.Bytes(), &r) == nil && r.Seq > l.last {
+			l.last = r.Seq
+		}
+	}
+
+	if err = sc.Err(); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return l, nil
+}
+
+func (l *AuditLog) Audit(r AuditRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	r.Seq = l.last + 1
+	line, err := json.Marshal(r)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err = l.f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+
+	l.last = r.Seq
+
+	return l.f.Sync()
+}
+
+func (l *AuditLog) LastSeq() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.last
+}
+
+func (l *AuditLog) Close() error {
+	return l.f.Close()
+}
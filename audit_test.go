@@ -0,0 +1,161 @@
+package mm010_nrc_api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/mm010sim"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log, err := api.OpenAuditLog(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sim := mm010sim.New()
+	c := api.NewTransportConnection("sim", sim.Conn(), api.WithTimeout(time.Second), api.WithAuditLogger(log))
+
+	defer sim.Close()
+
+	if _, err := c.DispenseContext(context.Background(), 3); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := c.PurgeContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = c.Close()
+	_ = log.Close()
+
+	if log, err = api.OpenAuditLog(path); err != nil || log.LastSeq() != 2 {
+		t.Fatalf("reopened log at seq %v, %v", log, err)
+	}
+
+	defer log.Close()
+
+	raw, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var first api.AuditRecord
+
+	if err := json.Unmarshal(bytes.SplitN(raw, []byte("\n"), 2)[0], &first); err != nil {
+		t.Fatal(err)
+	}
+
+	if first.Seq != 1 || first.Requested != 3 || first.Dispensed != 3 || first.Status == nil || *first.Status != api.GoodOperation ||
+		!first.UntrustedTime {
+		t.Fatalf("unexpected record %+v", first)
+	}
+}
+
+func TestAuditLogShared(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log, err := api.OpenAuditLog(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer log.Close()
+
+	var conns []*api.MMDispenser
+
+	for i := 0; i < 2; i++ {
+		sim := mm010sim.New()
+		defer sim.Close()
+
+		c := api.NewTransportConnection("sim", sim.Conn(), api.WithTimeout(time.Second), api.WithGuardTime(0), api.WithAuditLogger(log))
+		defer c.Close()
+
+		conns = append(conns, c)
+	}
+
+	for _, c := range conns {
+		if _, _, err := c.PurgeContext(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	raw, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, line := range bytes.Split(bytes.TrimSpace(raw), []byte("\n")) {
+		var r api.AuditRecord
+
+		if err := json.Unmarshal(line, &r); err != nil || r.Seq != uint64(i+1) {
+			t.Fatalf("record %d: %s, %v", i, line, err)
+		}
+	}
+
+	if log.LastSeq() != 2 {
+		t.Fatalf("expected the log at seq 2, got %d", log.LastSeq())
+	}
+}
+
+type auditRecords []api.AuditRecord
+
+func (r *auditRecords) Audit(rec api.AuditRecord) error {
+	*r = append(*r, rec)
+	return nil
+}
+
+type timeSource struct {
+	ts  api.Timestamp
+	err error
+}
+
+func (s *timeSource) Timestamp() (api.Timestamp, error) {
+	return s.ts, s.err
+}
+
+func TestAuditTimeSource(t *testing.T) {
+	var records auditRecords
+
+	trusted := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	source := &timeSource{ts: api.Timestamp{Time: trusted, Token: []byte("tsa")}}
+
+	sim := mm010sim.New()
+	defer sim.Close()
+
+	c := api.NewTransportConnection("sim", sim.Conn(), api.WithTimeout(time.Second), api.WithGuardTime(0),
+		api.WithAuditLogger(&records), api.WithAuditTimeSource(source))
+	defer c.Close()
+
+	if _, _, err := c.PurgeContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	source.err = errors.New("no time stamping authority")
+
+	if _, _, err := c.PurgeContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %+v", records)
+	}
+
+	if r := records[0]; !r.Time.Equal(trusted) || string(r.TimeToken) != "tsa" || r.UntrustedTime {
+		t.Fatalf("expected the time of the source, got %+v", r)
+	}
+
+	if r := records[1]; r.Time.Equal(trusted) || r.TimeToken != nil || !r.UntrustedTime {
+		t.Fatalf("expected the local time marked untrusted, got %+v", r)
+	}
+}
@@ -0,0 +1,79 @@
+package mm010_nrc_api
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tarm/serial"
+)
+
+// PortConfig is the serial port setup handed to a SerialBackend.
+type PortConfig struct {
+	Name        string
+	Baud        int
+	DataBits    byte
+	Parity      ParityMode
+	StopBits    StopBits
+	ReadTimeout time.Duration
+}
+
+// SerialBackend opens serial ports for NewConnection. The default is
+// TarmBackend; package mm010_nrc_api/bugst provides one based on
+// go.bug.st/serial.
+type SerialBackend interface {
+	Open(c PortConfig) (Transport, error)
+}
+
+type tarmBackend struct{}
+
+// TarmBackend opens ports with github.com/tarm/serial.
+var TarmBackend SerialBackend = tarmBackend{}
+
+func (tarmBackend) Open(c PortConfig) (Transport, error) {
+	p, err := serial.OpenPort(&serial.Config{Name: c.Name, Baud: c.Baud, Size: c.DataBits,
+		Parity: serial.Parity(c.Parity), StopBits: serial.StopBits(c.StopBits), ReadTimeout: c.ReadTimeout})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func WithSerialBackend(b SerialBackend) Option {
+	return func(s *MMDispenser) {
+		s.backend = b
+	}
+}
+
+func (s *MMDispenser) openPort() (Transport, error) {
+	b := s.backend
+
+	if b == nil {
+		b = TarmBackend
+	}
+
+	if !s.portLock {
+		return b.Open(s.portConfig())
+	}
+
+	lock, err := lockPort(s.config.Name)
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", s.config.Name, err)
+	}
+
+	p, err := b.Open(s.portConfig())
+
+	if err != nil {
+		lock.Close()
+		return nil, err
+	}
+
+	return &lockedPort{Transport: p, lock: lock}, nil
+}
+
+func (s *MMDispenser) portConfig() PortConfig {
+	return PortConfig{Name: s.config.Name, Baud: s.config.Baud, DataBits: s.config.Size,
+		Parity: ParityMode(s.config.Parity), StopBits: StopBits(s.config.StopBits), ReadTimeout: s.config.ReadTimeout}
+}
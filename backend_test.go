@@ -0,0 +1,36 @@
+package mm010_nrc_api_test
+
+import (
+	"context"
+	api "mm010_nrc_api"
+	"testing"
+	"time"
+)
+
+type backendFunc func(c api.PortConfig) (api.Transport, error)
+
+func (f backendFunc) Open(c api.PortConfig) (api.Transport, error) { return f(c) }
+
+func TestSerialBackendOption(t *testing.T) {
+	var got api.PortConfig
+
+	backend := backendFunc(func(c api.PortConfig) (api.Transport, error) {
+		got = c
+		return newFakePort(answer(statusPayload)), nil
+	})
+
+	c, err := api.NewConnection("COM9", api.WithSerialBackend(backend), api.WithBaud(api.Baud4800),
+		api.WithParity(api.ParityOdd), api.WithTimeout(time.Second))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Name != "COM9" || got.Baud != 4800 || got.Parity != api.ParityOdd || got.DataBits != 7 || got.ReadTimeout != time.Second {
+		t.Fatalf("unexpected port config %+v", got)
+	}
+
+	if _, err := c.StatusContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,175 @@
+package mm010_nrc_api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"mm010_nrc_api/protocol"
+)
+
+// ParameterBackupVersion is the version of the format BackupParameters
+// writes. RestoreParameters reads this version and older ones.
+const ParameterBackupVersion = 1
+
+// ParameterBackup is the file written by BackupParameters. Parameters maps
+// the registry names of the writable data items to their values.
+type ParameterBackup struct {
+	Version    int               `json:"version"`
+	Created    time.Time         `json:"created"`
+	ProgramID  string            `json:"program_id"`
+	MachineID  string            `json:"machine_id"`
+	Parameters map[string]string `json:"parameters"`
+}
+
+// BackupParameters reads every writable data item the device supports and
+// writes them to w as JSON, for RestoreParameters to push to a replacement
+// unit.
+func (s *MMDispenser) BackupParameters(ctx context.Context, w io.Writer) error {
+	backup, err := s.backupParameters(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(backup)
+}
+
+func (s *MMDispenser) backupParameters(ctx context.Context) (ParameterBackup, error) {
+	if err := s.acquire(ctx); err != nil {
+		return ParameterBackup{}, err
+	}
+	defer s.release()
+
+	backup := ParameterBackup{Version: ParameterBackupVersion, Created: time.Now().UTC(), Parameters: map[string]string{}}
+	var err error
+
+	if backup.ProgramID, err = s.readData(ctx, ProgramID, ""); err != nil {
+		return backup, fmt.Errorf("data item %v: %w", ProgramID, err)
+	}
+
+	if backup.MachineID, err = s.readData(ctx, MachineID, ""); err != nil {
+		return backup, fmt.Errorf("data item %v: %w", MachineID, err)
+	}
+
+	for _, info := range protocol.DataItems() {
+		if info.Access != protocol.ReadWrite || info.Param {
+			continue
+		}
+
+		v, err := s.readData(ctx, info.Item, "")
+
+		if err == ErrIllegalCommand {
+			continue
+		}
+
+		if err != nil {
+			return backup, fmt.Errorf("data item %v: %w", info.Item, err)
+		}
+
+		backup.Parameters[info.Name] = v
+	}
+
+	return backup, nil
+}
+
+// RestoreParameters writes the data items of a backup made by
+// BackupParameters to the device. The whole backup is checked before the
+// first write. Baudrate and Parity go last and only if they differ, through
+// SetBaudrate and SetParity, so the connection follows the device.
+func (s *MMDispenser) RestoreParameters(ctx context.Context, r io.Reader) error {
+	var backup ParameterBackup
+
+	if err := json.NewDecoder(r).Decode(&backup); err != nil {
+		return fmt.Errorf("parameter backup: %w", err)
+	}
+
+	if backup.Version < 1 || backup.Version > ParameterBackupVersion {
+		return fmt.Errorf("parameter backup version %d: %w", backup.Version, ErrBackupVersion)
+	}
+
+	names := map[string]protocol.DataItemInfo{}
+
+	for _, info := range protocol.DataItems() {
+		names[info.Name] = info
+	}
+
+	items := map[DataItem]string{}
+
+	for name, v := range backup.Parameters {
+		info, ok := names[name]
+
+		if !ok || info.Param {
+			return fmt.Errorf("parameter backup: unknown parameter %q", name)
+		}
+
+		if err := validateWrite(info.Item, v); err != nil {
+			return err
+		}
+
+		items[info.Item] = v
+	}
+
+	for _, info := range protocol.DataItems() {
+		v, ok := items[info.Item]
+
+		if !ok || info.Item == Baudrate || info.Item == Parity {
+			continue
+		}
+
+		if err := s.WriteDataContext(ctx, info.Item, v); err != nil {
+			return fmt.Errorf("parameter %s: %w", info.Name, err)
+		}
+	}
+
+	if err := s.restoreLineSetting(ctx, Parity, items); err != nil {
+		return err
+	}
+
+	return s.restoreLineSetting(ctx, Baudrate, items)
+}
+
+// restoreLineSetting restores Baudrate or Parity if the backup holds a value
+// other than the device's.
+func (s *MMDispenser) restoreLineSetting(ctx context.Context, item DataItem, items map[DataItem]string) error {
+	v, ok := items[item]
+
+	if !ok {
+		return nil
+	}
+
+	current, err := s.ReadDataContext(ctx, item, "")
+
+	if err == nil && current == v {
+		return nil
+	}
+
+	if item == Baudrate {
+		baud, _ := strconv.Atoi(v)
+		err = s.SetBaudrate(ctx, Baud(baud))
+	} else {
+		err = s.SetParity(ctx, parityOf(v))
+	}
+
+	if err != nil {
+		return fmt.Errorf("data item %v: %w", item, err)
+	}
+
+	return nil
+}
+
+func parityOf(value string) ParityMode {
+	for mode, v := range parityValues {
+		if v == value {
+			return mode
+		}
+	}
+
+	return ParityEven
+}
@@ -0,0 +1,50 @@
+package mm010_nrc_api_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	api "mm010_nrc_api"
+	"strings"
+	"testing"
+)
+
+func TestParameterBackup(t *testing.T) {
+	old, c := connect(t)
+	replacement, r := connect(t)
+	ctx := context.Background()
+
+	old.SetData(api.MaxNumberOfNotesInOneTransaction, "40")
+	old.SetData(api.ThroatSensorCalibrationValue, "42")
+	old.SetData(api.DispenseCounterTrip, "5")
+
+	var buf bytes.Buffer
+
+	if err := c.BackupParameters(ctx, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	backup := buf.String()
+
+	if err := r.RestoreParameters(ctx, strings.NewReader(backup)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, item := range []api.DataItem{api.MaxNumberOfNotesInOneTransaction, api.ThroatSensorCalibrationValue, api.DispenseCounterTrip} {
+		want, _ := old.Data(item)
+
+		if got, _ := replacement.Data(item); got != want {
+			t.Fatalf("%v restored as %q, want %q", item, got, want)
+		}
+	}
+
+	if err := r.RestoreParameters(ctx, strings.NewReader(`{"version": 2}`)); !errors.Is(err, api.ErrBackupVersion) {
+		t.Fatalf("expected ErrBackupVersion, got %v", err)
+	}
+
+	bad := strings.Replace(backup, `"42"`, `"4x"`, 1)
+
+	if err := r.RestoreParameters(ctx, strings.NewReader(bad)); !errors.Is(err, api.ErrInvalidValue) {
+		t.Fatalf("expected ErrInvalidValue, got %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package mm010_nrc_api
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type BudgetStep struct {
+	Name string
+	Run  func(ctx context.Context, s *MMDispenser) error
+	// Limit caps the part of the budget the step may use. Zero lets it use
+	// whatever is left.
+	Limit time.Duration
+}
+
+type BudgetExceededError struct {
+	Step    string
+	Budget  time.Duration
+	Elapsed time.Duration
+	Err     error
+}
+
+func (e *BudgetExceededError) Error() string {
+	msg := fmt.Sprintf("time budget of %v exceeded at step %q after %v", e.Budget, e.Step, e.Elapsed)
+
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+
+	return msg
+}
+
+func (e *BudgetExceededError) Unwrap() error {
+	return e.Err
+}
+
+// RunWithBudget runs steps in order within a single total time budget. Each
+// step runs with a context whose deadline is the end of the budget, or the end
+// of its Limit if that comes first, so no response read inside it waits past
+// its share and a step overrunning it is cut short. The sequence stops at the
+// first step that runs out of time.
+func (s *MMDispenser) RunWithBudget(ctx context.Context, budget time.Duration, steps ...BudgetStep) error {
+	start := time.Now()
+	deadline := start.Add(budget)
+
+	for i, step := range steps {
+		if !time.Now().Before(deadline) {
+			return &BudgetExceededError{Step: step.Name, Budget: budget, Elapsed: time.Since(start)}
+		}
+
+		overrun, err := step.run(ctx, s, deadline)
+
+		if overrun || (time.Now().After(deadline) && (err != nil || i < len(steps)-1)) {
+			return &BudgetExceededError{Step: step.Name, Budget: budget, Elapsed: time.Since(start), Err: err}
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// run runs the step until deadline or its Limit. overrun is whether it failed
+// because its time was up rather than because ctx was cancelled.
+func (step BudgetStep) run(ctx context.Context, s *MMDispenser, deadline time.Time) (overrun bool, err error) {
+	if step.Limit > 0 {
+		if limit := time.Now().Add(step.Limit); limit.Before(deadline) {
+			deadline = limit
+		}
+	}
+
+	stepCtx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+
+	err = step.Run(stepCtx, s)
+
+	return err != nil && ctx.Err() == nil && stepCtx.Err() == context.DeadlineExceeded, err
+}
@@ -0,0 +1,87 @@
+package mm010_nrc_api_test
+
+import (
+	"context"
+	"errors"
+	api "mm010_nrc_api"
+	"testing"
+	"time"
+)
+
+func TestRunWithBudgetNamesExceededStep(t *testing.T) {
+	c := api.MMDispenser{}
+
+	var ran []string
+
+	step := func(name string, d time.Duration) api.BudgetStep {
+		return api.BudgetStep{Name: name, Run: func(ctx context.Context, s *api.MMDispenser) error {
+			ran = append(ran, name)
+			time.Sleep(d)
+			return nil
+		}}
+	}
+
+	err := c.RunWithBudget(context.Background(), 50*time.Millisecond,
+		step("preflight", 0),
+		step("dispense", 80*time.Millisecond),
+		step("verify", 0))
+
+	var exceeded *api.BudgetExceededError
+
+	if !errors.As(err, &exceeded) {
+		t.Fatalf("expected BudgetExceededError, got %v", err)
+	}
+
+	if exceeded.Step != "dispense" {
+		t.Fatalf("expected step dispense, got %q", exceeded.Step)
+	}
+
+	if len(ran) != 2 {
+		t.Fatalf("expected verify to be skipped, ran %v", ran)
+	}
+}
+
+func TestRunWithBudgetCutsStepShort(t *testing.T) {
+	c := api.MMDispenser{}
+
+	wait := func(ctx context.Context, s *api.MMDispenser) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	started := time.Now()
+	err := c.RunWithBudget(context.Background(), time.Second,
+		api.BudgetStep{Name: "preflight", Run: wait, Limit: 20 * time.Millisecond})
+
+	var exceeded *api.BudgetExceededError
+
+	if !errors.As(err, &exceeded) || exceeded.Step != "preflight" || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the step to overrun its limit, got %v", err)
+	}
+
+	if time.Since(started) > 500*time.Millisecond {
+		t.Fatalf("expected the step to be cut short at its limit, took %v", time.Since(started))
+	}
+
+	// cancelling the caller's context is not running out of budget
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = c.RunWithBudget(ctx, time.Second, api.BudgetStep{Name: "dispense", Run: wait})
+
+	if !errors.Is(err, context.Canceled) || errors.As(err, &exceeded) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestRunWithBudgetWithinBudget(t *testing.T) {
+	c := api.MMDispenser{}
+
+	err := c.RunWithBudget(context.Background(), time.Second, api.BudgetStep{Name: "noop", Run: func(ctx context.Context, s *api.MMDispenser) error {
+		return nil
+	}})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
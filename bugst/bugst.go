@@ -0,0 +1,67 @@
+// Package bugst provides a SerialBackend based on go.bug.st/serial, which
+// unlike the default tarm backend supports buffer resets, DTR/RTS control and
+// port enumeration.
+package bugst
+
+import (
+	api "mm010_nrc_api"
+
+	"go.bug.st/serial"
+)
+
+type backend struct{}
+
+// Backend is passed to api.WithSerialBackend.
+var Backend api.SerialBackend = backend{}
+
+var parities = map[api.ParityMode]serial.Parity{
+	api.ParityNone: serial.NoParity,
+	api.ParityOdd:  serial.OddParity,
+	api.ParityEven: serial.EvenParity,
+}
+
+func (backend) Open(c api.PortConfig) (api.Transport, error) {
+	mode := &serial.Mode{BaudRate: c.Baud, DataBits: int(c.DataBits), Parity: parities[c.Parity], StopBits: serial.OneStopBit}
+
+	if c.StopBits == api.Stop2 {
+		mode.StopBits = serial.TwoStopBits
+	}
+
+	p, err := serial.Open(c.Name, mode)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if c.ReadTimeout > 0 {
+		if err = p.SetReadTimeout(c.ReadTimeout); err != nil {
+			p.Close()
+			return nil, err
+		}
+	}
+
+	return port{p}, nil
+}
+
+// port adds the Flush the library expects from a serial port.
+type port struct {
+	serial.Port
+}
+
+func (p port) Flush() error {
+	if err := p.ResetInputBuffer(); err != nil {
+		return err
+	}
+
+	return p.ResetOutputBuffer()
+}
+
+// Ports lists the serial ports of the system.
+func Ports() ([]string, error) {
+	return serial.GetPortsList()
+}
+
+// Ports makes the backend an api.PortLister, so failed opens list the ports.
+func (backend) Ports() ([]string, error) {
+	return Ports()
+}
@@ -0,0 +1,49 @@
+package mm010_nrc_api
+
+// Bus shares one port between several dispensers on a multi-drop RS-485 line,
+// each answering to its own communication identify. Exchanges of all
+// dispensers on the bus are serialized.
+type Bus struct {
+	name string
+	port Transport
+	lock chan struct{}
+	// reader is guarded by lock
+	reader *portReader
+}
+
+// NewBus opens the serial port at path. The options configure the port; they
+// are not passed on to the dispensers.
+func NewBus(path string, opts ...Option) (*Bus, error) {
+	c, err := NewConnection(path, opts...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTransportBus(path, c.port), nil
+}
+
+func NewTransportBus(name string, t Transport) *Bus {
+	return &Bus{name: name, port: t, lock: make(chan struct{}, 1)}
+}
+
+// Dispenser returns the dispenser with the given communication identify.
+// Closing it only detaches it from the bus; the port stays open until the bus
+// is closed.
+func (b *Bus) Dispenser(identify byte, opts ...Option) *MMDispenser {
+	res := newDispenser(b.name, b.port)
+	res.lock = b.lock
+	res.bus = b
+	res.identify = identify
+	res.open = true
+
+	for _, opt := range opts {
+		opt(res)
+	}
+
+	return res
+}
+
+func (b *Bus) Close() error {
+	return b.port.Close()
+}
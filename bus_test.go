@@ -0,0 +1,52 @@
+package mm010_nrc_api_test
+
+import (
+	api "mm010_nrc_api"
+	"mm010_nrc_api/protocol"
+	"testing"
+	"time"
+)
+
+func TestBusAddressesDispensers(t *testing.T) {
+	port := newFakePort(func(p []byte) [][]byte {
+		if len(p) > 3 && p[0] == protocol.RequestStart {
+			// the thickness reports which dispenser answered
+			payload := []byte{0x20, 0x20, 0x20 + p[1] - protocol.CommunicationIdentify, 0x20}
+			return [][]byte{{protocol.Ack}, protocol.EncodeResponse(p[1], protocol.Command(p[3]), payload)}
+		}
+
+		if len(p) == 1 && p[0] == protocol.Ack {
+			return [][]byte{{protocol.Eot}}
+		}
+
+		return nil
+	})
+
+	bus := api.NewTransportBus("rs485", port)
+	first := bus.Dispenser(0x30, api.WithTimeout(time.Second))
+	second := bus.Dispenser(0x32, api.WithTimeout(time.Second))
+
+	for i, d := range []*api.MMDispenser{first, second, first} {
+		status, err := d.Status()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if expected := byte(i % 2 * 2); status.AverageThickness != expected {
+			t.Fatalf("request %d: expected the answer of dispenser %d, got %d", i, expected, status.AverageThickness)
+		}
+	}
+
+	if err := second.Close(); err != nil || port.closed {
+		t.Fatalf("closing a bus dispenser must not close the port: %v %v", err, port.closed)
+	}
+
+	if _, err := first.Status(); err != nil {
+		t.Fatalf("expected the other dispenser to keep working, got %v", err)
+	}
+
+	if err := bus.Close(); err != nil || !port.closed {
+		t.Fatalf("expected the bus to close the port: %v %v", err, port.closed)
+	}
+}
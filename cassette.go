@@ -0,0 +1,240 @@
+package mm010_nrc_api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+type CassetteEventKind int
+
+const (
+	LowCashEvent CassetteEventKind = iota
+	EmptyEvent
+	// RefilledEvent follows Fill.
+	RefilledEvent
+)
+
+func (k CassetteEventKind) String() string {
+	switch k {
+	case LowCashEvent:
+		return "low cash"
+	case EmptyEvent:
+		return "empty"
+	case RefilledEvent:
+		return "refilled"
+	}
+
+	return "unknown"
+}
+
+type CassetteEvent struct {
+	Kind      CassetteEventKind
+	Time      time.Time
+	Remaining int
+}
+
+type CassetteConfig struct {
+	// LowThreshold raises LowCashEvent once the estimate drops to it.
+	LowThreshold int
+	// StatePath, if set, is the file that keeps the estimate across restarts.
+	StatePath string
+	// Handler receives the events. It is called from the command observer
+	// while the link is held, so it must not block or issue commands.
+	Handler func(CassetteEvent)
+}
+
+// cassetteState is what the monitor persists.
+type cassetteState struct {
+	Remaining int  `json:"remaining"`
+	Low       bool `json:"low"`
+	Empty     bool `json:"empty"`
+	// Processed is TotalProcessedCounterLifelong at the last Fill or Sync.
+	Processed uint64    `json:"processed"`
+	Updated   time.Time `json:"updated"`
+}
+
+// CassetteMonitor estimates the notes left in the cassette. It starts from
+// the count given to Fill and subtracts every note picked, dispensed or
+// rejected, as reported by the commands it observes; a feed failure means
+// the cassette ran empty. Sync corrects the estimate with the device's
+// processed counter, e.g. after commands sent by another program. The status
+// response has no cassette level bit, so sensors are not used.
+type CassetteMonitor struct {
+	cfg CassetteConfig
+
+	mu      sync.Mutex
+	state   cassetteState
+	saveErr error
+}
+
+// NewCassetteMonitor loads the estimate from cfg.StatePath if that exists.
+// Register the monitor with WithObserver(m.Observe).
+func NewCassetteMonitor(cfg CassetteConfig) (*CassetteMonitor, error) {
+	m := &CassetteMonitor{cfg: cfg}
+
+	if cfg.StatePath == "" {
+		return m, nil
+	}
+
+	raw, err := ioutil.ReadFile(cfg.StatePath)
+
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return m, nil
+	case err != nil:
+		return nil, err
+	}
+
+	if err = json.Unmarshal(raw, &m.state); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Fill sets the estimate after the cassette was loaded with notes and takes
+// the processed counter of d as the baseline for Sync.
+func (m *CassetteMonitor) Fill(ctx context.Context, d *MMDispenser, notes int) error {
+	processed, err := d.ReadCounter(ctx, TotalProcessedCounterLifelong)
+
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.state = cassetteState{Remaining: notes, Processed: processed}
+	events := m.check([]CassetteEvent{{Kind: RefilledEvent, Time: time.Now(), Remaining: notes}})
+	err = m.save()
+	m.mu.Unlock()
+
+	m.emit(events)
+
+	return err
+}
+
+// Sync sets the estimate from the notes d processed since the last Fill or
+// Sync.
+func (m *CassetteMonitor) Sync(ctx context.Context, d *MMDispenser) error {
+	processed, err := d.ReadCounter(ctx, TotalProcessedCounterLifelong)
+
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+
+	if processed >= m.state.Processed {
+		m.take(int(processed - m.state.Processed))
+	}
+
+	m.state.Processed = processed
+	events := m.check(nil)
+	err = m.save()
+	m.mu.Unlock()
+
+	m.emit(events)
+
+	return err
+}
+
+func (m *CassetteMonitor) Remaining() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.state.Remaining
+}
+
+// Err returns the error of the last save made by Observe. The estimate in
+// memory stays right, and the next save tries again.
+func (m *CassetteMonitor) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.saveErr
+}
+
+// Observe updates the estimate from a finished command.
+func (m *CassetteMonitor) Observe(e CommandEvent) {
+	if e.Result == nil {
+		return
+	}
+
+	picked := int(e.Result.NotesDispensed) + int(e.Result.NotesRejected)
+
+	m.mu.Lock()
+	m.take(picked)
+	m.state.Processed += uint64(picked)
+
+	if e.Result.Status == FeedFailure {
+		m.state.Remaining = 0
+	}
+
+	events := m.check(nil)
+	m.saveErr = m.save()
+	m.mu.Unlock()
+
+	m.emit(events)
+}
+
+func (m *CassetteMonitor) take(n int) {
+	m.state.Remaining -= n
+
+	if m.state.Remaining < 0 {
+		m.state.Remaining = 0
+	}
+}
+
+// check appends the events the current estimate raises to events.
+func (m *CassetteMonitor) check(events []CassetteEvent) []CassetteEvent {
+	now := time.Now()
+
+	if !m.state.Low && m.state.Remaining <= m.cfg.LowThreshold {
+		m.state.Low = true
+		events = append(events, CassetteEvent{Kind: LowCashEvent, Time: now, Remaining: m.state.Remaining})
+	}
+
+	if !m.state.Empty && m.state.Remaining == 0 {
+		m.state.Empty = true
+		events = append(events, CassetteEvent{Kind: EmptyEvent, Time: now, Remaining: 0})
+	}
+
+	return events
+}
+
+func (m *CassetteMonitor) emit(events []CassetteEvent) {
+	if m.cfg.Handler == nil {
+		return
+	}
+
+	for _, e := range events {
+		m.cfg.Handler(e)
+	}
+}
+
+// save writes the state through a temporary file, so a crash leaves either
+// the old or the new estimate.
+func (m *CassetteMonitor) save() error {
+	if m.cfg.StatePath == "" {
+		return nil
+	}
+
+	m.state.Updated = time.Now()
+	raw, err := json.Marshal(m.state)
+
+	if err != nil {
+		return err
+	}
+
+	tmp := m.cfg.StatePath + ".tmp"
+
+	if err = ioutil.WriteFile(tmp, raw, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, m.cfg.StatePath)
+}
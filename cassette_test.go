@@ -0,0 +1,56 @@
+package mm010_nrc_api_test
+
+import (
+	"context"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/mm010sim"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCassetteMonitor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	var events []api.CassetteEvent
+
+	m, err := api.NewCassetteMonitor(api.CassetteConfig{LowThreshold: 3, StatePath: path,
+		Handler: func(e api.CassetteEvent) { events = append(events, e) }})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sim := mm010sim.New()
+	c := api.NewTransportConnection("sim", sim.Conn(), api.WithTimeout(time.Second), api.WithObserver(m.Observe))
+	ctx := context.Background()
+
+	defer sim.Close()
+
+	sim.SetNotes(5)
+
+	if err := m.Fill(ctx, c, 5); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.DispenseContext(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if m.Remaining() != 3 || len(events) != 2 || events[1].Kind != api.LowCashEvent {
+		t.Fatalf("expected low cash at 3 notes, got %d and %v", m.Remaining(), events)
+	}
+
+	if _, err := c.DispenseContext(ctx, 5); err != nil {
+		t.Fatal(err)
+	}
+
+	if m.Remaining() != 0 || events[len(events)-1].Kind != api.EmptyEvent {
+		t.Fatalf("expected the feed failure to empty the cassette, got %d and %v", m.Remaining(), events)
+	}
+
+	restored, err := api.NewCassetteMonitor(api.CassetteConfig{StatePath: path})
+
+	if err != nil || restored.Remaining() != 0 {
+		t.Fatalf("restored estimate %v, %v", restored.Remaining(), err)
+	}
+}
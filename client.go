@@ -0,0 +1,20 @@
+package mm010_nrc_api
+
+import "context"
+
+type clientKey struct{}
+
+// ContextWithClient marks the commands run with the returned context as
+// those of client, e.g. one of several applications sharing the dispenser
+// through mm010agentd. The client shows in CommandEvent and AuditRecord.
+func ContextWithClient(ctx context.Context, client string) context.Context {
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+// ClientFromContext returns the client set with ContextWithClient, "" if
+// there is none.
+func ClientFromContext(ctx context.Context) string {
+	client, _ := ctx.Value(clientKey{}).(string)
+
+	return client
+}
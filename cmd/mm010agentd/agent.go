@@ -0,0 +1,378 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/httpapi"
+	"mm010_nrc_api/metrics"
+)
+
+type config struct {
+	port         string
+	baud         int
+	timeout      time.Duration
+	listen       string
+	keysPath     string
+	stateDir     string
+	saveInterval time.Duration
+	verbose      bool
+	mdns         bool
+	mdnsName     string
+	logger       *log.Logger
+}
+
+// savedCounters is counters.json.
+type savedCounters struct {
+	Time      time.Time    `json:"time"`
+	MachineID string       `json:"machine_id"`
+	Counters  api.Counters `json:"counters"`
+	// Stats are those of the line since the agent started.
+	Stats api.Stats `json:"stats"`
+}
+
+type agent struct {
+	cfg       config
+	log       *log.Logger
+	d         *api.MMDispenser
+	metrics   *metrics.Collector
+	machineID string
+}
+
+func newAgent(cfg config) *agent {
+	return &agent{cfg: cfg, log: cfg.logger, metrics: metrics.NewCollector(cfg.port)}
+}
+
+// run serves the dispenser until ctx is done, then lets the HTTP requests
+// and the command in flight finish before it releases the port.
+func (a *agent) run(ctx context.Context, ready func()) error {
+	keys, clients, err := readKeys(a.cfg.keysPath)
+
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(a.cfg.stateDir, 0o755); err != nil {
+		return err
+	}
+
+	audit, err := api.OpenAuditLog(filepath.Join(a.cfg.stateDir, "audit.jsonl"))
+
+	if err != nil {
+		return err
+	}
+
+	defer audit.Close()
+
+	if a.d, err = a.connect(ctx, audit); err != nil {
+		if ctx.Err() != nil {
+			// stopped before the port showed up
+			return nil
+		}
+
+		return err
+	}
+
+	a.checkCounters(ctx)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(a.metrics)
+
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", api.HealthHandler(a.d, 5*time.Second))
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	mux.Handle("/", httpapi.NewHandler(a.d, httpapi.Config{APIKeys: keys, Clients: clients}))
+
+	srv := &http.Server{Addr: a.cfg.listen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	served := make(chan error, 1)
+
+	go func() {
+		served <- srv.ListenAndServe()
+	}()
+
+	a.log.Printf("serving %s on %s", a.cfg.port, a.cfg.listen)
+
+	var ad *advertiser
+
+	if a.cfg.mdns {
+		if ad, err = newAdvertiser(a.cfg.listen, a.mdnsName(), a.txt(ctx), a.log); err != nil {
+			a.log.Printf("mDNS: %v", err)
+		}
+	}
+
+	ready()
+
+	save := time.NewTicker(a.cfg.saveInterval)
+	defer save.Stop()
+
+loop:
+	for {
+		select {
+		case <-save.C:
+			a.saveCounters(ctx)
+
+			if ad != nil {
+				ad.setTXT(a.txt(ctx))
+			}
+		case err = <-served:
+			break loop
+		case <-ctx.Done():
+			break loop
+		}
+	}
+
+	a.log.Printf("stopping")
+
+	if ad != nil {
+		ad.close()
+	}
+
+	// a dispense still running may take a while, it must not be cut short
+	stop, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	if shutdownErr := srv.Shutdown(stop); shutdownErr != nil {
+		a.log.Printf("stop HTTP API: %v", shutdownErr)
+	}
+
+	a.saveCounters(stop)
+
+	if shutdownErr := a.d.Shutdown(stop); shutdownErr != nil {
+		a.log.Printf("release %s: %v", a.cfg.port, shutdownErr)
+	}
+
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+
+	return err
+}
+
+// connect opens the port, retrying until it shows up. Once open, the library
+// reopens it whenever the link fails.
+func (a *agent) connect(ctx context.Context, audit *api.AuditLog) (*api.MMDispenser, error) {
+	policy := api.ReconnectPolicy{Delay: time.Second, Multiplier: 2, MaxDelay: 30 * time.Second}
+	opts := []api.Option{
+		api.WithBaud(api.Baud(a.cfg.baud)),
+		api.WithTimeout(a.cfg.timeout),
+		api.WithLogger(logger{a.log, a.cfg.verbose}),
+		api.WithPortLock(true),
+		api.WithAutoReconnect(policy),
+		api.WithAuditLogger(audit),
+		api.WithObserver(a.metrics.Observe),
+		api.WithConnectionStateHandler(func(state api.ConnectionState) {
+			a.log.Printf("%s %v", a.cfg.port, state)
+		}),
+	}
+
+	delay := policy.Delay
+
+	for {
+		d, err := api.NewConnection(a.cfg.port, opts...)
+
+		if err == nil {
+			return d, nil
+		}
+
+		if errors.Is(err, api.ErrInvalidPortPath) {
+			return nil, err
+		}
+
+		a.log.Printf("open %s: %v, retrying in %v", a.cfg.port, err, delay)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		if delay *= 2; delay > policy.MaxDelay {
+			delay = policy.MaxDelay
+		}
+	}
+}
+
+// checkCounters compares the device with the counters saved last, so notes
+// moved while the agent was not running, or a swapped unit, show in the log.
+func (a *agent) checkCounters(ctx context.Context) {
+	var last savedCounters
+
+	raw, err := ioutil.ReadFile(a.countersPath())
+
+	if err == nil {
+		err = json.Unmarshal(raw, &last)
+	}
+
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		a.log.Printf("saved counters: %v", err)
+	}
+
+	if a.machineID, err = a.d.ReadDataContext(ctx, api.MachineID, ""); err != nil {
+		a.log.Printf("read machine ID: %v", err)
+	}
+
+	a.machineID = strings.TrimSpace(a.machineID)
+	current := a.saveCounters(ctx)
+
+	switch {
+	case current == nil || last.Time.IsZero():
+	case last.MachineID != a.machineID:
+		a.log.Printf("dispenser changed from machine %q to %q", last.MachineID, a.machineID)
+	case current.TotalProcessedLifelong < last.Counters.TotalProcessedLifelong:
+		a.log.Printf("lifelong counters went back since %v, the controller may have been replaced", last.Time.Format(time.RFC3339))
+	case current.TotalProcessedLifelong > last.Counters.TotalProcessedLifelong:
+		a.log.Printf("%d notes dispensed and %d rejected since %v while the agent was not running",
+			current.DispenseLifelong-last.Counters.DispenseLifelong, current.RejectLifelong-last.Counters.RejectLifelong,
+			last.Time.Format(time.RFC3339))
+	}
+}
+
+// saveCounters reads the counters and writes them to counters.json,
+// replacing it only once the new file is complete. It returns nil if the
+// device could not be read; the saved counters stay as they were.
+func (a *agent) saveCounters(ctx context.Context) *api.Counters {
+	counters, err := a.d.Counters(ctx)
+
+	if err != nil {
+		a.log.Printf("read counters: %v", err)
+		return nil
+	}
+
+	raw, err := json.MarshalIndent(savedCounters{Time: time.Now(), MachineID: a.machineID, Counters: counters, Stats: a.d.Stats()}, "", "  ")
+
+	if err == nil {
+		err = writeFile(a.countersPath(), raw)
+	}
+
+	if err != nil {
+		a.log.Printf("save counters: %v", err)
+	}
+
+	return &counters
+}
+
+// mdnsName is the DNS-SD instance name of the agent, mm010-<machine ID> by
+// default.
+func (a *agent) mdnsName() string {
+	switch {
+	case a.cfg.mdnsName != "":
+		return a.cfg.mdnsName
+	case a.machineID != "":
+		return "mm010-" + a.machineID
+	}
+
+	host, _ := os.Hostname()
+
+	return "mm010-" + strings.SplitN(host, ".", 2)[0]
+}
+
+// txt is the DNS-SD TXT record of the agent, the health of the device is
+// that of the last check.
+func (a *agent) txt(ctx context.Context) []string {
+	check, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	health := api.Healthy
+	var healthErr *api.HealthError
+
+	if err := a.d.Healthy(check); errors.As(err, &healthErr) {
+		health = healthErr.State
+	} else if err != nil {
+		health = api.Down
+	}
+
+	return []string{"txtvers=1", "machine_id=" + a.machineID, "port=" + a.cfg.port, "health=" + health.String()}
+}
+
+func (a *agent) countersPath() string {
+	return filepath.Join(a.cfg.stateDir, "counters.json")
+}
+
+func writeFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+
+	if err := ioutil.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// readKeys reads the API keys, one per line, optionally preceded by the name
+// of the client using the key: "kiosk-ui 3f9c...".
+func readKeys(path string) ([]string, map[string]string, error) {
+	f, err := os.Open(path)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer f.Close()
+
+	var keys []string
+	clients := map[string]string{}
+
+	sc := bufio.NewScanner(f)
+
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		switch fields := strings.Fields(line); len(fields) {
+		case 1:
+			keys = append(keys, fields[0])
+		case 2:
+			keys = append(keys, fields[1])
+			clients[fields[1]] = fields[0]
+		default:
+			return nil, nil, fmt.Errorf("%s: expected a key or a client name and a key, got %d fields", path, len(fields))
+		}
+	}
+
+	if err = sc.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if len(keys) == 0 {
+		return nil, nil, fmt.Errorf("%s: no API keys", path)
+	}
+
+	return keys, clients, nil
+}
+
+// logger passes the library's log to the agent's, the frames only if
+// verbose.
+type logger struct {
+	l       *log.Logger
+	verbose bool
+}
+
+func (l logger) Debugf(format string, args ...interface{}) {
+	if l.verbose {
+		l.l.Printf("DEBUG "+format, args...)
+	}
+}
+
+func (l logger) Infof(format string, args ...interface{}) {
+	l.l.Printf("INFO "+format, args...)
+}
+
+func (l logger) Errorf(format string, args ...interface{}) {
+	l.l.Printf("ERROR "+format, args...)
+}
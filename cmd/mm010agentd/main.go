@@ -0,0 +1,101 @@
+// Command mm010agentd owns the serial port of an MM010 NRC dispenser and
+// serves it to the applications of the host over the HTTP API of package
+// httpapi, so several of them can share the dispenser without fighting over
+// the port.
+//
+//	mm010agentd --port /dev/ttyUSB0 --api-keys /etc/mm010/keys --state /var/lib/mm010
+//
+// The port is held with the library's port lock and reopened whenever the
+// link fails, also when the adapter is missing at startup. GET /healthz
+// answers without a key, see HealthHandler, and so does GET /metrics with the
+// Prometheus metrics of package metrics.
+//
+// The --api-keys file holds one key per line, optionally preceded by the name
+// of the client using it. Commands, dispensed notes and audit records are
+// attributed to that client:
+//
+//	kiosk-ui 6b1f0c...
+//	reporting 90d2e4...
+//
+// With --mdns the agent advertises the API on the LAN as a DNS-SD service of
+// type _mm010._tcp, with the machine ID, the serial port and the health of
+// the device in the TXT record. The health is checked every --save-interval.
+// The API must then listen on an address the LAN can reach:
+//
+//	mm010agentd --port /dev/ttyUSB0 --api-keys /etc/mm010/keys --listen :8010 --mdns
+//
+// The device counters are saved to
+// counters.json in the state directory every --save-interval and on
+// shutdown, next to the audit log of every cash moving command. At startup
+// the agent reports notes the device moved while it was not running.
+//
+// Under systemd it runs as a Type=notify service:
+//
+//	[Service]
+//	Type=notify
+//	ExecStart=/usr/local/bin/mm010agentd --port /dev/ttyUSB0 --api-keys /etc/mm010/keys --state /var/lib/mm010
+//	Restart=on-failure
+//
+// On Windows it runs under the service control manager when started by it:
+//
+//	sc create mm010agentd start= auto binPath= "C:\mm010\mm010agentd.exe --port COM4 --api-keys C:\mm010\keys --state C:\mm010 --log C:\mm010\agent.log"
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	api "mm010_nrc_api"
+)
+
+func main() {
+	var cfg config
+
+	flag.StringVar(&cfg.port, "port", "", "serial port, e.g. COM4 or /dev/ttyUSB0")
+	flag.IntVar(&cfg.baud, "baud", int(api.Baud9600), "baud rate (1200, 2400, 4800 or 9600)")
+	flag.DurationVar(&cfg.timeout, "timeout", 3*time.Second, "read timeout")
+	flag.StringVar(&cfg.listen, "listen", "127.0.0.1:8010", "address of the HTTP API")
+	flag.StringVar(&cfg.keysPath, "api-keys", "", "file with the accepted API keys, one per line")
+	flag.StringVar(&cfg.stateDir, "state", ".", "directory of the saved counters and the audit log")
+	flag.DurationVar(&cfg.saveInterval, "save-interval", time.Minute, "how often the counters are saved")
+	logPath := flag.String("log", "", "append the log to this file instead of stderr")
+	flag.BoolVar(&cfg.verbose, "v", false, "log frames")
+	flag.BoolVar(&cfg.mdns, "mdns", false, "advertise the API over mDNS as _mm010._tcp")
+	flag.StringVar(&cfg.mdnsName, "mdns-name", "", "mDNS instance name, mm010-<machine ID> if empty")
+	flag.Parse()
+
+	if cfg.port == "" || cfg.keysPath == "" || flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var out io.Writer = os.Stderr
+
+	if *logPath != "" {
+		f, err := os.OpenFile(*logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+
+		if err != nil {
+			fail(err)
+		}
+
+		defer f.Close()
+
+		out = f
+	}
+
+	cfg.logger = log.New(out, "", log.LstdFlags)
+
+	if err := runService(newAgent(cfg).run); err != nil {
+		cfg.logger.Println(err)
+		os.Exit(1)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "mm010agentd:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,397 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The agent advertises its HTTP API with multicast DNS service discovery,
+// RFC 6762 and RFC 6763, as an instance of _mm010._tcp.local, so software on
+// the LAN can find the dispensers without a static configuration. The TXT
+// record carries the machine ID, the serial port and the health of the
+// device. Only the few records of the agent are answered, there is no cache,
+// no probing and no known answer suppression.
+
+const (
+	dnsTypeA   = 1
+	dnsTypePTR = 12
+	dnsTypeTXT = 16
+	dnsTypeSRV = 33
+	dnsTypeANY = 255
+
+	dnsClassIN = 1
+	// dnsCacheFlush marks the records only this host answers.
+	dnsCacheFlush = 0x8000
+
+	// the TTLs RFC 6762 recommends for records with and without host names
+	hostTTL  = 120
+	otherTTL = 4500
+)
+
+var (
+	mdnsGroup = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+	serviceName  = []string{"_mm010", "_tcp", "local"}
+	servicesName = []string{"_services", "_dns-sd", "_udp", "local"}
+
+	errMalformed = errors.New("malformed DNS message")
+)
+
+type dnsRecord struct {
+	name  []string
+	typ   uint16
+	class uint16
+	ttl   uint32
+	data  []byte
+}
+
+type advertiser struct {
+	conn     *net.UDPConn
+	log      *log.Logger
+	instance []string
+	host     []string
+	port     uint16
+	ips      []net.IP
+
+	mu       sync.Mutex
+	txt      []string
+	lastSent time.Time
+}
+
+// newAdvertiser starts answering the queries for instance, the agent serving
+// its HTTP API on listen.
+func newAdvertiser(listen, instance string, txt []string, l *log.Logger) (*advertiser, error) {
+	hostPort, portStr, err := net.SplitHostPort(listen)
+
+	if err != nil {
+		return nil, err
+	}
+
+	port, err := strconv.ParseUint(portStr, 10, 16)
+
+	if err != nil {
+		return nil, fmt.Errorf("listen port %q: %w", portStr, err)
+	}
+
+	ips, err := advertisedIPs(hostPort)
+
+	if err != nil {
+		return nil, err
+	}
+
+	hostname, err := os.Hostname()
+
+	if err != nil {
+		return nil, err
+	}
+
+	hostname = strings.SplitN(hostname, ".", 2)[0]
+
+	if len(instance) > 63 || len(hostname) > 63 || instance == "" {
+		return nil, fmt.Errorf("mDNS name %q of host %q out of range", instance, hostname)
+	}
+
+	conn, err := net.ListenMulticastUDP("udp4", nil, mdnsGroup)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ad := &advertiser{conn: conn, log: l, instance: append([]string{instance}, serviceName...),
+		host: []string{hostname, "local"}, port: uint16(port), ips: ips, txt: txt}
+
+	go ad.serve()
+
+	ad.announce()
+
+	return ad, nil
+}
+
+// advertisedIPs returns host if the API listens on one address, else the
+// IPv4 addresses of the interfaces, except the loopback ones.
+func advertisedIPs(host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil && !ip.IsUnspecified() {
+		if ip.To4() == nil || ip.IsLoopback() {
+			return nil, fmt.Errorf("%s can not be advertised over mDNS", host)
+		}
+
+		return []net.IP{ip.To4()}, nil
+	}
+
+	addrs, err := net.InterfaceAddrs()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var ips []net.IP
+
+	for _, a := range addrs {
+		if n, ok := a.(*net.IPNet); ok && n.IP.To4() != nil && !n.IP.IsLoopback() {
+			ips = append(ips, n.IP.To4())
+		}
+	}
+
+	if len(ips) == 0 {
+		return nil, errors.New("no IPv4 address to advertise over mDNS")
+	}
+
+	return ips, nil
+}
+
+// setTXT replaces the TXT record and announces it if it changed.
+func (ad *advertiser) setTXT(txt []string) {
+	ad.mu.Lock()
+	changed := strings.Join(ad.txt, "\x00") != strings.Join(txt, "\x00")
+	ad.txt = txt
+	ad.mu.Unlock()
+
+	if changed {
+		ad.send(ad.records(false), nil)
+	}
+}
+
+// announce sends the records twice, a second apart, as RFC 6762 asks.
+func (ad *advertiser) announce() {
+	ad.send(ad.records(false), nil)
+
+	go func() {
+		time.Sleep(time.Second)
+		ad.send(ad.records(false), nil)
+	}()
+}
+
+// close withdraws the records and stops answering.
+func (ad *advertiser) close() {
+	ad.send(ad.records(true), nil)
+	_ = ad.conn.Close()
+}
+
+func (ad *advertiser) serve() {
+	buf := make([]byte, 9000)
+
+	for {
+		n, _, err := ad.conn.ReadFromUDP(buf)
+
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				ad.log.Printf("mDNS: %v", err)
+			}
+
+			return
+		}
+
+		questions, err := parseQuery(buf[:n])
+
+		if err != nil {
+			continue
+		}
+
+		var answers, additionals []dnsRecord
+
+		for _, r := range ad.records(false) {
+			if asked(questions, r) {
+				answers = append(answers, r)
+			} else {
+				additionals = append(additionals, r)
+			}
+		}
+
+		if len(answers) > 0 {
+			ad.mu.Lock()
+			recent := time.Since(ad.lastSent) < time.Second
+			ad.mu.Unlock()
+
+			// a record must not be multicast more than once a second
+			if !recent {
+				ad.send(answers, additionals)
+			}
+		}
+	}
+}
+
+func (ad *advertiser) send(answers, additionals []dnsRecord) {
+	ad.mu.Lock()
+	ad.lastSent = time.Now()
+	ad.mu.Unlock()
+
+	if _, err := ad.conn.WriteToUDP(response(answers, additionals), mdnsGroup); err != nil && !errors.Is(err, net.ErrClosed) {
+		ad.log.Printf("mDNS: %v", err)
+	}
+}
+
+// records returns the records of the agent, with a TTL of 0 to withdraw them
+// if goodbye.
+func (ad *advertiser) records(goodbye bool) []dnsRecord {
+	ttl := func(t uint32) uint32 {
+		if goodbye {
+			return 0
+		}
+
+		return t
+	}
+
+	ad.mu.Lock()
+	txt := ad.txt
+	ad.mu.Unlock()
+
+	var txtData []byte
+
+	for _, s := range txt {
+		if len(s) > 255 {
+			s = s[:255]
+		}
+
+		txtData = append(append(txtData, byte(len(s))), s...)
+	}
+
+	srv := make([]byte, 6)
+	binary.BigEndian.PutUint16(srv[4:], ad.port)
+
+	records := []dnsRecord{
+		{servicesName, dnsTypePTR, dnsClassIN, ttl(otherTTL), appendName(nil, serviceName)},
+		{serviceName, dnsTypePTR, dnsClassIN, ttl(otherTTL), appendName(nil, ad.instance)},
+		{ad.instance, dnsTypeSRV, dnsClassIN | dnsCacheFlush, ttl(hostTTL), appendName(srv, ad.host)},
+		{ad.instance, dnsTypeTXT, dnsClassIN | dnsCacheFlush, ttl(otherTTL), txtData},
+	}
+
+	for _, ip := range ad.ips {
+		records = append(records, dnsRecord{ad.host, dnsTypeA, dnsClassIN | dnsCacheFlush, ttl(hostTTL), ip})
+	}
+
+	return records
+}
+
+type dnsQuestion struct {
+	name []string
+	typ  uint16
+}
+
+func asked(questions []dnsQuestion, r dnsRecord) bool {
+	for _, q := range questions {
+		if (q.typ == r.typ || q.typ == dnsTypeANY) && sameName(q.name, r.name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sameName(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if !strings.EqualFold(a[i], b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// response encodes an mDNS response, without name compression.
+func response(answers, additionals []dnsRecord) []byte {
+	msg := make([]byte, 12)
+	// QR and AA
+	binary.BigEndian.PutUint16(msg[2:], 0x8400)
+	binary.BigEndian.PutUint16(msg[6:], uint16(len(answers)))
+	binary.BigEndian.PutUint16(msg[10:], uint16(len(additionals)))
+
+	for _, r := range append(append([]dnsRecord(nil), answers...), additionals...) {
+		msg = appendName(msg, r.name)
+
+		var fixed [10]byte
+		binary.BigEndian.PutUint16(fixed[0:], r.typ)
+		binary.BigEndian.PutUint16(fixed[2:], r.class)
+		binary.BigEndian.PutUint32(fixed[4:], r.ttl)
+		binary.BigEndian.PutUint16(fixed[8:], uint16(len(r.data)))
+
+		msg = append(append(msg, fixed[:]...), r.data...)
+	}
+
+	return msg
+}
+
+func appendName(b []byte, labels []string) []byte {
+	for _, l := range labels {
+		b = append(append(b, byte(len(l))), l...)
+	}
+
+	return append(b, 0)
+}
+
+// parseQuery returns the questions of a query, an error for a response or a
+// malformed message.
+func parseQuery(msg []byte) ([]dnsQuestion, error) {
+	if len(msg) < 12 || binary.BigEndian.Uint16(msg[2:])&0x8000 != 0 {
+		return nil, errMalformed
+	}
+
+	count := int(binary.BigEndian.Uint16(msg[4:]))
+	off := 12
+	questions := make([]dnsQuestion, 0, count)
+
+	for i := 0; i < count; i++ {
+		name, next, err := readName(msg, off)
+
+		if err != nil || next+4 > len(msg) {
+			return nil, errMalformed
+		}
+
+		questions = append(questions, dnsQuestion{name: name, typ: binary.BigEndian.Uint16(msg[next:])})
+		off = next + 4
+	}
+
+	return questions, nil
+}
+
+// readName reads the name at off, following compression pointers, and
+// returns the offset after it.
+func readName(msg []byte, off int) ([]string, int, error) {
+	var labels []string
+
+	end := -1
+
+	for jumps := 0; ; {
+		if off >= len(msg) {
+			return nil, 0, errMalformed
+		}
+
+		n := int(msg[off])
+
+		switch {
+		case n == 0:
+			if end < 0 {
+				end = off + 1
+			}
+
+			return labels, end, nil
+		case n&0xC0 == 0xC0:
+			if off+1 >= len(msg) || jumps > 10 {
+				return nil, 0, errMalformed
+			}
+
+			if end < 0 {
+				end = off + 2
+			}
+
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3FFF)
+			jumps++
+		case n > 63 || off+1+n > len(msg):
+			return nil, 0, errMalformed
+		default:
+			labels = append(labels, string(msg[off+1:off+1+n]))
+			off += 1 + n
+		}
+	}
+}
@@ -0,0 +1,43 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// runService runs the agent until SIGTERM or SIGINT and tells systemd when
+// it is ready, if started as a Type=notify service.
+func runService(run func(ctx context.Context, ready func()) error) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	return run(ctx, func() {
+		notify("READY=1")
+	})
+}
+
+// notify sends state to the socket systemd passes in NOTIFY_SOCKET, see
+// sd_notify(3). Without it there is nothing to tell.
+func notify(state string) {
+	addr := os.Getenv("NOTIFY_SOCKET")
+
+	if addr == "" {
+		return
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: addr, Net: "unixgram"})
+
+	if err != nil {
+		return
+	}
+
+	defer conn.Close()
+
+	_, _ = conn.Write([]byte(state))
+}
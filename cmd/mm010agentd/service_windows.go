@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+
+	"golang.org/x/sys/windows/svc"
+)
+
+// runService runs the agent under the service control manager if started by
+// it, and otherwise like on the console until Ctrl+C.
+func runService(run func(ctx context.Context, ready func()) error) error {
+	isService, err := svc.IsWindowsService()
+
+	if err != nil {
+		return err
+	}
+
+	if !isService {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		return run(ctx, func() {})
+	}
+
+	s := &service{run: run}
+
+	if err = svc.Run("mm010agentd", s); err != nil {
+		return err
+	}
+
+	return s.err
+}
+
+type service struct {
+	run func(ctx context.Context, ready func()) error
+	err error
+}
+
+func (s *service) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- s.run(ctx, func() {
+			status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+		})
+	}()
+
+	for {
+		select {
+		case s.err = <-done:
+			status <- svc.Status{State: svc.Stopped}
+
+			if s.err != nil && !errors.Is(s.err, context.Canceled) {
+				// a service specific exit code makes the SCM apply the
+				// recovery actions
+				return true, 1
+			}
+
+			return false, 0
+		case c := <-requests:
+			switch c.Cmd {
+			case svc.Interrogate:
+				status <- c.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				cancel()
+			}
+		}
+	}
+}
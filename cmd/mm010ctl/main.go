@@ -0,0 +1,289 @@
+// Command mm010ctl runs single MM010 NRC dispenser operations from a shell.
+//
+//	mm010ctl --port /dev/ttyUSB0 --baud 9600 status
+//	mm010ctl --port COM4 --json dispense 5
+//	mm010ctl --port COM4 read-data MachineID
+//	mm010ctl --port COM4 write-data 306 0
+//	mm010ctl --port COM4 --trace session.jsonl dispense 1
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	api "mm010_nrc_api"
+)
+
+const usage = `usage: mm010ctl [flags] <command> [args]
+
+commands:
+  status                   poll sensor status
+  info                     show firmware and machine identity
+  discover                 list ports a dispenser answers on (no --port needed)
+  dispense N               dispense N notes
+  purge                    purge the transport path
+  reset                    reset the dispenser
+  read-data ITEM [PARAM]   read a data item by number or name
+  write-data ITEM VALUE    write a data item by number or name
+
+flags:
+`
+
+type config struct {
+	port      string
+	baud      int
+	timeout   time.Duration
+	asJSON    bool
+	verbose   bool
+	tracePath string
+}
+
+func main() {
+	var cfg config
+
+	flag.StringVar(&cfg.port, "port", "", "serial port, e.g. COM4 or /dev/ttyUSB0")
+	flag.IntVar(&cfg.baud, "baud", int(api.Baud9600), "baud rate (1200, 2400, 4800 or 9600)")
+	flag.DurationVar(&cfg.timeout, "timeout", 3*time.Second, "read timeout")
+	flag.BoolVar(&cfg.asJSON, "json", false, "print results as JSON")
+	flag.BoolVar(&cfg.verbose, "v", false, "log frames to stdout")
+	flag.StringVar(&cfg.tracePath, "trace", "", "record the traffic as JSON lines to this file")
+
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 || (cfg.port == "" && flag.Arg(0) != "discover") {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// the deferred closes in execute must run before the exit
+	if err := execute(cfg, flag.Arg(0), flag.Args()[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "mm010ctl:", err)
+		os.Exit(1)
+	}
+}
+
+func execute(cfg config, command string, args []string) (err error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if command == "discover" {
+		ports := api.DiscoverDispensers(ctx, api.WithBaud(api.Baud(cfg.baud)))
+
+		if cfg.asJSON {
+			return json.NewEncoder(os.Stdout).Encode(ports)
+		}
+
+		for _, p := range ports {
+			fmt.Println(p)
+		}
+
+		return nil
+	}
+
+	opts := []api.Option{api.WithBaud(api.Baud(cfg.baud)), api.WithTimeout(cfg.timeout), api.WithLogging(cfg.verbose)}
+
+	if cfg.tracePath != "" {
+		f, err := os.Create(cfg.tracePath)
+
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+
+		opts = append(opts, api.WithTrace(api.NewTraceRecorder(f)))
+	}
+
+	c, err := api.NewConnection(cfg.port, opts...)
+
+	if err != nil {
+		return err
+	}
+
+	defer c.Close()
+
+	result, err := run(ctx, c, command, args)
+
+	if err != nil {
+		return err
+	}
+
+	if cfg.asJSON {
+		printJSON(result)
+	} else {
+		printTable(result)
+	}
+
+	return nil
+}
+
+type row struct {
+	Field string
+	Value string
+}
+
+func run(ctx context.Context, c *api.MMDispenser, command string, args []string) ([]row, error) {
+	switch command {
+	case "status":
+		s, err := c.StatusContext(ctx)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return []row{
+			{"feed_sensor_blocked", strconv.FormatBool(s.FeedSensorBlocked)},
+			{"exit_sensor_blocked", strconv.FormatBool(s.ExitSensorBlocked)},
+			{"reset_since_last_status", strconv.FormatBool(s.ResetSinceLastStatusMessage)},
+			{"timing_wheel_blocked", strconv.FormatBool(s.TimingWheelSensorBlocked)},
+			{"calibrating_double_detect", strconv.FormatBool(s.CalibratingDoubleDetect)},
+			{"average_thickness", strconv.Itoa(int(s.AverageThickness))},
+			{"average_length", strconv.Itoa(int(s.AverageLength))},
+			{"sensors", fmt.Sprintf("0x%02X", s.Sensors)},
+			{"flags", fmt.Sprintf("0x%02X", s.Flags)},
+		}, nil
+	case "dispense":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("dispense needs a note count")
+		}
+
+		n, err := strconv.Atoi(args[0])
+
+		if err != nil || n < 1 || n > api.MaxNotesPerDispense {
+			return nil, fmt.Errorf("invalid note count %q", args[0])
+		}
+
+		res, err := c.DispenseContext(ctx, byte(n))
+
+		if err != nil {
+			return nil, err
+		}
+
+		return []row{
+			{"status", res.Status.String()},
+			{"dispensed", strconv.Itoa(int(res.NotesDispensed))},
+			{"rejected", strconv.Itoa(int(res.NotesRejected))},
+		}, nil
+	case "info":
+		info, err := c.DeviceInfo(ctx)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return []row{
+			{"program_id", info.ProgramID},
+			{"machine_id", info.MachineID},
+			{"max_notes_per_transaction", strconv.Itoa(info.MaxNotesPerTransaction)},
+			{"configuration", fmt.Sprintf("0x%02X 0x%02X", info.Configuration[0], info.Configuration[1])},
+		}, nil
+	case "purge":
+		status, purged, err := c.PurgeContext(ctx)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return []row{{"status", status.String()}, {"purged", strconv.Itoa(int(purged))}}, nil
+	case "reset":
+		if err := c.ResetContext(ctx); err != nil {
+			return nil, err
+		}
+
+		return []row{{"reset", "ok"}}, nil
+	case "read-data":
+		if len(args) < 1 || len(args) > 2 {
+			return nil, fmt.Errorf("read-data needs an item and an optional parameter")
+		}
+
+		item, err := parseItem(args[0])
+
+		if err != nil {
+			return nil, err
+		}
+
+		param := ""
+
+		if len(args) == 2 {
+			param = args[1]
+		}
+
+		v, err := c.ReadDataContext(ctx, item, param)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return []row{{item.String(), v}}, nil
+	case "write-data":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("write-data needs an item and a value")
+		}
+
+		item, err := parseItem(args[0])
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err := c.WriteDataContext(ctx, item, args[1]); err != nil {
+			return nil, err
+		}
+
+		return []row{{item.String(), args[1]}}, nil
+	}
+
+	return nil, fmt.Errorf("unknown command %q", command)
+}
+
+func parseItem(s string) (api.DataItem, error) {
+	if n, err := strconv.ParseUint(s, 10, 16); err == nil {
+		return api.DataItem(n), nil
+	}
+
+	for _, info := range api.ListDataItems() {
+		if strings.EqualFold(info.Name, s) {
+			return info.Item, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown data item %q", s)
+}
+
+func printJSON(rows []row) {
+	out := make(map[string]string, len(rows))
+
+	for _, r := range rows {
+		out[r.Field] = r.Value
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(out)
+}
+
+func printTable(rows []row) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+
+	for _, r := range rows {
+		fmt.Fprintf(w, "%s\t%s\n", r.Field, r.Value)
+	}
+
+	_ = w.Flush()
+}
@@ -0,0 +1,170 @@
+// Command mm010soak runs dispense, purge and status cycles against an MM010
+// NRC dispenser for hours and summarizes how often it failed, as vendors ask
+// for during warranty claims.
+//
+//	mm010soak --port /dev/ttyUSB0 --duration 8h --out soak.csv
+//	mm010soak --port COM4 --cycle status,dispense,purge --notes 5 --out soak.jsonl
+//
+// Each operation is recorded as one CSV row or JSON line as soon as it ran,
+// so the file survives the tool being killed. The summary is printed when the
+// duration or cycle count is reached or on interrupt.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	api "mm010_nrc_api"
+)
+
+const usage = `usage: mm010soak [flags]
+
+operations of --cycle:
+  status     poll sensor status
+  dispense   dispense --notes notes
+  purge      purge the transport path
+  reset      reset the dispenser
+
+flags:
+`
+
+func main() {
+	port := flag.String("port", "", "serial port, e.g. COM4 or /dev/ttyUSB0")
+	baud := flag.Int("baud", int(api.Baud9600), "baud rate (1200, 2400, 4800 or 9600)")
+	timeout := flag.Duration("timeout", 3*time.Second, "read timeout")
+	duration := flag.Duration("duration", time.Hour, "how long to run, 0 for no limit")
+	cycles := flag.Int("cycles", 0, "how many cycles to run, 0 for no limit")
+	sequence := flag.String("cycle", "status,dispense,purge", "comma separated operations of one cycle")
+	notes := flag.Int("notes", 1, "notes per dispense")
+	interval := flag.Duration("interval", time.Second, "pause between cycles")
+	outPath := flag.String("out", "", "record operations to this file, CSV or JSON lines by extension")
+	format := flag.String("format", "", "csv or jsonl, overrides the extension of --out")
+	recoverFailures := flag.Bool("recover", true, "reset the dispenser after a failed operation")
+	stopOnFailure := flag.Bool("stop-on-failure", false, "stop at the first failure")
+	asJSON := flag.Bool("json", false, "print the summary as JSON")
+	verbose := flag.Bool("v", false, "log frames to stdout")
+
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *port == "" || flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ops, err := parseCycle(*sequence)
+
+	if err != nil {
+		fail(err)
+	}
+
+	if *notes < 1 || *notes > api.MaxNotesPerDispense {
+		fail(fmt.Errorf("invalid note count %d", *notes))
+	}
+
+	var rec recorder = nopRecorder{}
+
+	if *outPath != "" {
+		f, err := os.Create(*outPath)
+
+		if err != nil {
+			fail(err)
+		}
+
+		defer f.Close()
+
+		if rec, err = newRecorder(f, *outPath, *format); err != nil {
+			fail(err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if *duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *duration)
+		defer cancel()
+	}
+
+	c, err := api.NewConnection(*port, api.WithBaud(api.Baud(*baud)), api.WithTimeout(*timeout), api.WithLogging(*verbose))
+
+	if err != nil {
+		fail(err)
+	}
+
+	s := soak{
+		dispenser:     c,
+		ops:           ops,
+		notes:         byte(*notes),
+		cycles:        *cycles,
+		interval:      *interval,
+		recover:       *recoverFailures,
+		stopOnFailure: *stopOnFailure,
+		rec:           rec,
+		summary:       newSummary(),
+	}
+
+	runErr := s.run(ctx)
+	s.summary.finish()
+
+	// the context may be done already, the link still deserves a clean end
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	if err := c.Shutdown(shutdownCtx); err != nil {
+		fmt.Fprintln(os.Stderr, "mm010soak: shutdown:", err)
+	}
+
+	if err := rec.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, "mm010soak: record:", err)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(s.summary)
+	} else {
+		s.summary.print(os.Stdout)
+	}
+
+	if runErr != nil {
+		fail(runErr)
+	}
+}
+
+func parseCycle(s string) ([]string, error) {
+	var ops []string
+
+	for _, op := range strings.Split(s, ",") {
+		op = strings.TrimSpace(op)
+
+		switch op {
+		case "status", "dispense", "purge", "reset":
+			ops = append(ops, op)
+		case "":
+		default:
+			return nil, fmt.Errorf("unknown operation %q", op)
+		}
+	}
+
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("the cycle has no operations")
+	}
+
+	return ops, nil
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "mm010soak:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,412 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	api "mm010_nrc_api"
+)
+
+type soak struct {
+	dispenser     *api.MMDispenser
+	ops           []string
+	notes         byte
+	cycles        int
+	interval      time.Duration
+	recover       bool
+	stopOnFailure bool
+	rec           recorder
+	summary       *summary
+}
+
+// record is one operation of a cycle.
+type record struct {
+	Cycle     int       `json:"cycle"`
+	Operation string    `json:"operation"`
+	Time      time.Time `json:"time"`
+	Latency   seconds   `json:"latency_s"`
+	Status    string    `json:"status,omitempty"`
+	Dispensed int       `json:"dispensed"`
+	Rejected  int       `json:"rejected"`
+	Purged    int       `json:"purged"`
+	Failed    bool      `json:"failed"`
+	// Recovery marks the reset after a failure, which is not counted as an
+	// operation of the cycle.
+	Recovery bool   `json:"recovery,omitempty"`
+	Error    string `json:"error,omitempty"`
+	// cause groups failures in the summary
+	cause string
+}
+
+// run repeats the cycle until ctx is done or the cycle count is reached. A
+// failure ends its cycle, after a reset if recover is set.
+func (s *soak) run(ctx context.Context) error {
+	for cycle := 1; s.cycles == 0 || cycle <= s.cycles; cycle++ {
+		for _, op := range s.ops {
+			r := s.do(ctx, cycle, op)
+
+			if ctx.Err() != nil {
+				// an operation cut short by the end of the run is no failure
+				return nil
+			}
+
+			if err := s.record(r); err != nil {
+				return err
+			}
+
+			if !r.Failed {
+				continue
+			}
+
+			if s.stopOnFailure {
+				s.summary.Cycles++
+				return nil
+			}
+
+			if s.recover {
+				r = s.do(ctx, cycle, "reset")
+				r.Recovery = true
+
+				if ctx.Err() != nil {
+					return nil
+				}
+
+				if err := s.record(r); err != nil {
+					return err
+				}
+			}
+
+			break
+		}
+
+		s.summary.Cycles++
+
+		if err := pause(ctx, s.interval); err != nil {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (s *soak) record(r record) error {
+	s.summary.add(r)
+
+	if err := s.rec.Record(r); err != nil {
+		return fmt.Errorf("record cycle %d: %w", r.Cycle, err)
+	}
+
+	return nil
+}
+
+func (s *soak) do(ctx context.Context, cycle int, op string) record {
+	r := record{Cycle: cycle, Operation: op, Time: time.Now()}
+
+	// operations without a status code pass unless they fail
+	status := api.GoodOperation
+
+	var err error
+
+	switch op {
+	case "status":
+		_, err = s.dispenser.StatusContext(ctx)
+	case "dispense":
+		var res api.DispenseResult
+
+		res, err = s.dispenser.DispenseContext(ctx, s.notes)
+		status, r.Dispensed, r.Rejected = res.Status, int(res.NotesDispensed), int(res.NotesRejected)
+	case "purge":
+		var purged byte
+
+		status, purged, err = s.dispenser.PurgeContext(ctx)
+		r.Purged = int(purged)
+	case "reset":
+		err = s.dispenser.ResetContext(ctx)
+	}
+
+	r.Latency = seconds(time.Since(r.Time))
+
+	switch {
+	case err != nil:
+		r.Failed, r.Error, r.cause = true, err.Error(), rootCause(err).Error()
+	case op == "dispense" || op == "purge":
+		r.Status = status.String()
+		r.Failed, r.cause = status != api.GoodOperation, r.Status
+	}
+
+	return r
+}
+
+// rootCause strips the context wrapped around err, so failures of one kind
+// are counted together, whatever frame or item they were reported with.
+func rootCause(err error) error {
+	for {
+		next := errors.Unwrap(err)
+
+		if next == nil {
+			return err
+		}
+
+		err = next
+	}
+}
+
+func pause(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// seconds is a duration shown and encoded as fractional seconds.
+type seconds time.Duration
+
+func (d seconds) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.FormatFloat(time.Duration(d).Seconds(), 'f', 3, 64)), nil
+}
+
+func (d seconds) String() string {
+	return time.Duration(d).Round(time.Millisecond).String()
+}
+
+type recorder interface {
+	Record(r record) error
+	Close() error
+}
+
+// newRecorder picks the format from format or else the extension of path.
+func newRecorder(w io.Writer, path, format string) (recorder, error) {
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(path), ".")
+	}
+
+	switch strings.ToLower(format) {
+	case "csv":
+		c := csv.NewWriter(w)
+
+		return &csvRecorder{w: c}, c.Write(csvHeader)
+	case "jsonl", "json", "ndjson":
+		return jsonRecorder{enc: json.NewEncoder(w)}, nil
+	}
+
+	return nil, fmt.Errorf("unknown record format %q, use csv or jsonl", format)
+}
+
+type nopRecorder struct{}
+
+func (nopRecorder) Record(record) error { return nil }
+
+func (nopRecorder) Close() error { return nil }
+
+type jsonRecorder struct {
+	enc *json.Encoder
+}
+
+func (j jsonRecorder) Record(r record) error {
+	return j.enc.Encode(r)
+}
+
+func (jsonRecorder) Close() error { return nil }
+
+var csvHeader = []string{"cycle", "operation", "time", "latency_s", "status", "dispensed", "rejected", "purged",
+	"failed", "recovery", "error"}
+
+type csvRecorder struct {
+	w *csv.Writer
+}
+
+// Record flushes every row, so a killed run loses nothing.
+func (c *csvRecorder) Record(r record) error {
+	latency, _ := r.Latency.MarshalJSON()
+
+	err := c.w.Write([]string{
+		strconv.Itoa(r.Cycle),
+		r.Operation,
+		r.Time.Format(time.RFC3339Nano),
+		string(latency),
+		r.Status,
+		strconv.Itoa(r.Dispensed),
+		strconv.Itoa(r.Rejected),
+		strconv.Itoa(r.Purged),
+		strconv.FormatBool(r.Failed),
+		strconv.FormatBool(r.Recovery),
+		r.Error,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	c.w.Flush()
+
+	return c.w.Error()
+}
+
+func (c *csvRecorder) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// summary are the statistics of a run. The times between failures are wall
+// clock, pauses between cycles included.
+type summary struct {
+	Started        time.Time                  `json:"started"`
+	Elapsed        seconds                    `json:"elapsed_s"`
+	Cycles         int                        `json:"cycles"`
+	Operations     int                        `json:"operations"`
+	Failures       int                        `json:"failures"`
+	Recoveries     int                        `json:"recoveries"`
+	NotesDispensed int                        `json:"notes_dispensed"`
+	NotesRejected  int                        `json:"notes_rejected"`
+	RejectRate     float64                    `json:"reject_rate"`
+	FirstFailure   seconds                    `json:"first_failure_s,omitempty"`
+	MTBF           seconds                    `json:"mtbf_s,omitempty"`
+	MCBF           float64                    `json:"mcbf,omitempty"`
+	NotesPerFail   float64                    `json:"notes_per_failure,omitempty"`
+	ByOperation    map[string]*operationStats `json:"operations_by_kind"`
+	Causes         map[string]int             `json:"failure_causes"`
+}
+
+type operationStats struct {
+	Count       int     `json:"count"`
+	Failures    int     `json:"failures"`
+	MeanLatency seconds `json:"mean_latency_s"`
+	MaxLatency  seconds `json:"max_latency_s"`
+	total       time.Duration
+}
+
+func newSummary() *summary {
+	return &summary{Started: time.Now(), ByOperation: map[string]*operationStats{}, Causes: map[string]int{}}
+}
+
+func (s *summary) add(r record) {
+	s.Elapsed = seconds(time.Since(s.Started))
+
+	if r.Recovery {
+		s.Recoveries++
+		return
+	}
+
+	op := s.ByOperation[r.Operation]
+
+	if op == nil {
+		op = &operationStats{}
+		s.ByOperation[r.Operation] = op
+	}
+
+	op.Count++
+	op.total += time.Duration(r.Latency)
+	op.MeanLatency = seconds(op.total / time.Duration(op.Count))
+
+	if r.Latency > op.MaxLatency {
+		op.MaxLatency = r.Latency
+	}
+
+	s.Operations++
+	s.NotesDispensed += r.Dispensed
+	s.NotesRejected += r.Rejected
+
+	if processed := s.NotesDispensed + s.NotesRejected; processed > 0 {
+		s.RejectRate = float64(s.NotesRejected) / float64(processed)
+	}
+
+	if !r.Failed {
+		return
+	}
+
+	op.Failures++
+	s.Failures++
+	s.Causes[r.Operation+": "+r.cause]++
+
+	if s.Failures == 1 {
+		s.FirstFailure = s.Elapsed
+	}
+}
+
+// finish stamps the elapsed time at the end of the run and derives the
+// figures between failures from it.
+func (s *summary) finish() {
+	s.Elapsed = seconds(time.Since(s.Started))
+
+	if s.Failures == 0 {
+		return
+	}
+
+	s.MTBF = s.Elapsed / seconds(s.Failures)
+	s.MCBF = float64(s.Cycles) / float64(s.Failures)
+	s.NotesPerFail = float64(s.NotesDispensed) / float64(s.Failures)
+}
+
+func (s *summary) print(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "started\t%s\n", s.Started.Format(time.RFC3339))
+	fmt.Fprintf(tw, "elapsed\t%v\n", s.Elapsed)
+	fmt.Fprintf(tw, "cycles\t%d\n", s.Cycles)
+	fmt.Fprintf(tw, "operations\t%d\n", s.Operations)
+	fmt.Fprintf(tw, "failures\t%d\n", s.Failures)
+	fmt.Fprintf(tw, "recoveries\t%d\n", s.Recoveries)
+	fmt.Fprintf(tw, "notes dispensed\t%d\n", s.NotesDispensed)
+	fmt.Fprintf(tw, "notes rejected\t%d (%.2f%%)\n", s.NotesRejected, s.RejectRate*100)
+
+	if s.Failures > 0 {
+		fmt.Fprintf(tw, "first failure after\t%v\n", s.FirstFailure)
+		fmt.Fprintf(tw, "mean time between failures\t%v\n", s.MTBF)
+		fmt.Fprintf(tw, "mean cycles between failures\t%.1f\n", s.MCBF)
+		fmt.Fprintf(tw, "notes per failure\t%.1f\n", s.NotesPerFail)
+	}
+
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "operation\tcount\tfailures\tmean latency\tmax latency")
+
+	names := make([]string, 0, len(s.ByOperation))
+
+	for name := range s.ByOperation {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		op := s.ByOperation[name]
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%v\t%v\n", name, op.Count, op.Failures, op.MeanLatency, op.MaxLatency)
+	}
+
+	causes := make([]string, 0, len(s.Causes))
+
+	for cause := range s.Causes {
+		causes = append(causes, cause)
+	}
+
+	sort.Strings(causes)
+
+	if len(causes) > 0 {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "failure cause\tcount")
+	}
+
+	for _, cause := range causes {
+		fmt.Fprintf(tw, "%s\t%d\n", cause, s.Causes[cause])
+	}
+
+	_ = tw.Flush()
+}
@@ -0,0 +1,87 @@
+package mm010_nrc_api
+
+import (
+	"context"
+	"time"
+)
+
+// The methods below keep the original context-free API working on top of the
+// context-aware commands. New code should call the Context variants.
+
+// Deprecated: use NewConnection with WithBaud, WithLogging and WithTimeout.
+func NewSerialConnection(path string, baud Baud, logging bool, timeout time.Duration, opts ...Option) (*MMDispenser, error) {
+	return NewConnection(path, append([]Option{WithBaud(baud), WithLogging(logging), WithTimeout(timeout)}, opts...)...)
+}
+
+// Deprecated: use StatusContext.
+func (s *MMDispenser) Status() (Status, error) {
+	return s.StatusContext(context.Background())
+}
+
+// Deprecated: use PurgeContext.
+func (s *MMDispenser) Purge() (StatusCode, byte, error) {
+	return s.PurgeContext(context.Background())
+}
+
+// Deprecated: use DispenseContext.
+func (s *MMDispenser) Dispense(count byte) (StatusCode, byte, byte, error) {
+	res, err := s.DispenseContext(context.Background(), count)
+	return res.Status, res.NotesDispensed, res.NotesRejected, err
+}
+
+// Deprecated: use TestDispenseContext.
+func (s *MMDispenser) TestDispense(count byte) (StatusCode, byte, byte, error) {
+	res, err := s.TestDispenseContext(context.Background(), count)
+	return res.Status, res.NotesDispensed, res.NotesRejected, err
+}
+
+// Deprecated: use ResetContext.
+func (s *MMDispenser) Reset() error {
+	return s.ResetContext(context.Background())
+}
+
+// Deprecated: use LastStatusContext.
+func (s *MMDispenser) LastStatus() (StatusCode, byte, byte, error) {
+	res, err := s.LastStatusContext(context.Background())
+	return res.Status, res.NotesDispensed, res.NotesRejected, err
+}
+
+// Deprecated: use ConfigurationStatusContext.
+func (s *MMDispenser) ConfigurationStatus() (byte, byte, error) {
+	return s.ConfigurationStatusContext(context.Background())
+}
+
+// Deprecated: use DoubleDetectDiagnosticsContext.
+func (s *MMDispenser) DoubleDetectDiagnostics() (StatusCode, byte, byte, error) {
+	return s.DoubleDetectDiagnosticsContext(context.Background())
+}
+
+// Deprecated: use SensorDiagnosticsContext.
+func (s *MMDispenser) SensorDiagnostics() (StatusCode, byte, byte, error) {
+	return s.SensorDiagnosticsContext(context.Background())
+}
+
+// Deprecated: use SingleNoteDispenseContext.
+func (s *MMDispenser) SingleNoteDispense() (StatusCode, byte, byte, error) {
+	return s.SingleNoteDispenseContext(context.Background())
+}
+
+// Deprecated: use SingleNoteEjectContext.
+func (s *MMDispenser) SingleNoteEject() (StatusCode, byte, byte, error) {
+	return s.SingleNoteEjectContext(context.Background())
+}
+
+// Deprecated: use TestModeContext.
+func (s *MMDispenser) TestMode() (StatusCode, error) {
+	return s.TestModeContext(context.Background())
+}
+
+// Deprecated: use ReadDataContext.
+func (s *MMDispenser) ReadData(item DataItem, param string) (string, error) {
+	return s.ReadDataContext(context.Background(), item, param)
+}
+
+// Deprecated: use WriteDataContext.
+func (s *MMDispenser) WriteData(item DataItem, data string) error {
+	return s.WriteDataContext(context.Background(), item, data)
+}
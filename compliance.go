@@ -0,0 +1,73 @@
+package mm010_nrc_api
+
+import (
+	"context"
+	"fmt"
+)
+
+// ComplianceProfile is the firmware and configuration every unit of a fleet
+// is expected to run.
+type ComplianceProfile struct {
+	// ProgramID is the expected firmware, not checked if empty.
+	ProgramID string `json:"program_id"`
+	// Configuration is the expected ConfigurationStatus, not checked if nil.
+	Configuration *Configuration `json:"configuration,omitempty"`
+}
+
+// UnitCompliance is the row of a compliance report for one unit.
+type UnitCompliance struct {
+	Unit          string        `json:"unit"`
+	MachineID     string        `json:"machine_id"`
+	ProgramID     string        `json:"program_id"`
+	Configuration Configuration `json:"configuration"`
+	Pass          bool          `json:"pass"`
+	// Findings lists how the unit differs from the profile.
+	Findings []string `json:"findings,omitempty"`
+	// Error is set if the unit could not be read, Pass is false then.
+	Error string `json:"error,omitempty"`
+}
+
+// ComplianceReport reads the firmware and configuration of every unit, also
+// those out of service, and compares them with profile. The report has a row
+// per unit in the order the units were added.
+func (p *Pool) ComplianceReport(ctx context.Context, profile ComplianceProfile) []UnitCompliance {
+	p.mu.Lock()
+	units := append([]*poolUnit(nil), p.units...)
+	p.mu.Unlock()
+
+	res := make([]UnitCompliance, len(units))
+
+	for i, u := range units {
+		res[i] = profile.check(ctx, u.name, u.d)
+	}
+
+	return res
+}
+
+func (profile ComplianceProfile) check(ctx context.Context, name string, d Dispenser) UnitCompliance {
+	row := UnitCompliance{Unit: name}
+
+	info, err := d.DeviceInfo(ctx)
+
+	if err != nil {
+		row.Error = err.Error()
+		return row
+	}
+
+	row.MachineID, row.ProgramID = info.MachineID, info.ProgramID
+	row.Configuration = ParseConfiguration(info.Configuration[0], info.Configuration[1])
+
+	if profile.ProgramID != "" && info.ProgramID != profile.ProgramID {
+		row.Findings = append(row.Findings, fmt.Sprintf("program: expected %q, got %q", profile.ProgramID, info.ProgramID))
+	}
+
+	if profile.Configuration != nil {
+		for _, c := range profile.Configuration.Diff(row.Configuration) {
+			row.Findings = append(row.Findings, "configuration "+c.String())
+		}
+	}
+
+	row.Pass = len(row.Findings) == 0
+
+	return row
+}
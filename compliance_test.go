@@ -0,0 +1,54 @@
+package mm010_nrc_api_test
+
+import (
+	"context"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/protocol"
+	"testing"
+)
+
+func TestPoolComplianceReport(t *testing.T) {
+	_, a := connect(t)
+	outdated, b := connect(t)
+	drifted, c := connect(t)
+	broken, d := connect(t)
+
+	outdated.SetData(protocol.ProgramID, "MM010OLD")
+	drifted.SetConfiguration(0x00, 0x01)
+	_ = broken.Close()
+
+	pool := api.NewPool(api.PoolConfig{})
+
+	_ = pool.Add("a", 10, a)
+	_ = pool.Add("b", 10, b)
+	_ = pool.Add("c", 20, c)
+	_ = pool.Add("d", 20, d)
+
+	golden, err := a.Configuration(context.Background())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := pool.ComplianceReport(context.Background(), api.ComplianceProfile{ProgramID: "MM010SIM", Configuration: &golden})
+
+	if len(report) != 4 {
+		t.Fatalf("expected a row per unit, got %+v", report)
+	}
+
+	if r := report[0]; r.Unit != "a" || !r.Pass || r.ProgramID != "MM010SIM" || len(r.Findings) != 0 {
+		t.Fatalf("expected unit a to pass, got %+v", r)
+	}
+
+	if r := report[1]; r.Pass || len(r.Findings) != 1 || r.ProgramID != "MM010OLD" {
+		t.Fatalf("expected a program finding for unit b, got %+v", r)
+	}
+
+	if r := report[2]; r.Pass || len(r.Findings) == 0 || r.Configuration.Raw != [2]byte{0x00, 0x01} {
+		t.Fatalf("expected a configuration finding for unit c, got %+v", r)
+	}
+
+	if r := report[3]; r.Pass || r.Error == "" {
+		t.Fatalf("expected unit d to fail with an error, got %+v", r)
+	}
+}
@@ -0,0 +1,88 @@
+package mm010_nrc_api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Configuration is the ConfigurationStatus of a unit. The meaning of its
+// bits is not documented in this tree, so both bytes are kept as they are.
+type Configuration struct {
+	Raw [2]byte `json:"raw"`
+}
+
+func ParseConfiguration(c1, c2 byte) Configuration {
+	return Configuration{Raw: [2]byte{c1, c2}}
+}
+
+// ConfigurationChange is a byte that differs between two configurations.
+type ConfigurationChange struct {
+	Field    string `json:"field"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+func (c ConfigurationChange) String() string {
+	return fmt.Sprintf("%s: expected %s, got %s", c.Field, c.Expected, c.Actual)
+}
+
+// Diff lists the bytes in which actual differs from c, nil if they match.
+func (c Configuration) Diff(actual Configuration) []ConfigurationChange {
+	var changes []ConfigurationChange
+
+	for i := range c.Raw {
+		if c.Raw[i] != actual.Raw[i] {
+			changes = append(changes, ConfigurationChange{Field: fmt.Sprintf("byte %d", i+1),
+				Expected: fmt.Sprintf("0x%02X", c.Raw[i]), Actual: fmt.Sprintf("0x%02X", actual.Raw[i])})
+		}
+	}
+
+	return changes
+}
+
+// ConfigurationDriftError is returned by CompareConfiguration if the device
+// is not configured as expected.
+type ConfigurationDriftError struct {
+	Expected Configuration
+	Actual   Configuration
+	Changes  []ConfigurationChange
+}
+
+func (e *ConfigurationDriftError) Error() string {
+	changes := make([]string, len(e.Changes))
+
+	for i, c := range e.Changes {
+		changes[i] = c.String()
+	}
+
+	return "configuration drift: " + strings.Join(changes, "; ")
+}
+
+// Configuration reads ConfigurationStatus.
+func (s *MMDispenser) Configuration(ctx context.Context) (Configuration, error) {
+	c1, c2, err := s.ConfigurationStatusContext(ctx)
+
+	if err != nil {
+		return Configuration{}, err
+	}
+
+	return ParseConfiguration(c1, c2), nil
+}
+
+// CompareConfiguration reads the configuration and returns a
+// *ConfigurationDriftError if it differs from expected, e.g. a snapshot taken
+// before maintenance.
+func (s *MMDispenser) CompareConfiguration(ctx context.Context, expected Configuration) error {
+	actual, err := s.Configuration(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	if changes := expected.Diff(actual); len(changes) > 0 {
+		return &ConfigurationDriftError{Expected: expected, Actual: actual, Changes: changes}
+	}
+
+	return nil
+}
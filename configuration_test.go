@@ -0,0 +1,54 @@
+package mm010_nrc_api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	api "mm010_nrc_api"
+	"testing"
+)
+
+func TestCompareConfiguration(t *testing.T) {
+	sim, c := connect(t)
+	ctx := context.Background()
+
+	sim.SetConfiguration(0x09, 0x09)
+
+	snapshot, err := c.Configuration(ctx)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if snapshot.Raw != [2]byte{0x09, 0x09} {
+		t.Fatalf("unexpected configuration %+v", snapshot)
+	}
+
+	// the snapshot survives being stored
+	b, _ := json.Marshal(snapshot)
+
+	var expected api.Configuration
+
+	if err := json.Unmarshal(b, &expected); err != nil || expected != snapshot {
+		t.Fatalf("round trip gave %+v, %v", expected, err)
+	}
+
+	if err := c.CompareConfiguration(ctx, expected); err != nil {
+		t.Fatal(err)
+	}
+
+	sim.SetConfiguration(0x00, 0x09)
+
+	err = c.CompareConfiguration(ctx, expected)
+
+	var drift *api.ConfigurationDriftError
+
+	if !errors.As(err, &drift) || len(drift.Changes) != 1 || drift.Changes[0].Field != "byte 1" ||
+		drift.Changes[0].Expected != "0x09" || drift.Changes[0].Actual != "0x00" {
+		t.Fatalf("expected drift in the first byte, got %v", err)
+	}
+
+	if changes := expected.Diff(api.ParseConfiguration(0x29, 0x0B)); len(changes) != 2 {
+		t.Fatalf("expected both bytes to differ, got %v", changes)
+	}
+}
@@ -0,0 +1,46 @@
+package mm010_nrc_api
+
+import (
+	"bytes"
+
+	"mm010_nrc_api/protocol"
+)
+
+type CountEncoding = protocol.CountEncoding
+
+const (
+	SingleCount = protocol.SingleCount
+	WideCount   = protocol.WideCount
+)
+
+// WithCountEncoding sets how the device encodes note counts. Firmware that
+// moves more than MaxNotesPerDispense notes at once uses WideCount; the API
+// still caps a dispense at 255 notes.
+func WithCountEncoding(enc CountEncoding) Option {
+	return func(s *MMDispenser) {
+		s.countEncoding = enc
+	}
+}
+
+// maxNotes is the largest count of one dispense.
+func (s *MMDispenser) maxNotes() int {
+	return s.countEncoding.Max()
+}
+
+func (s *MMDispenser) decodeText(command Command, text []byte) (interface{}, error) {
+	return protocol.DecodeTextWith(s.countEncoding, command, text)
+}
+
+// requestedNotes is the number of notes a note moving request asks for.
+func (s *MMDispenser) requestedNotes(command Command, data ...[]byte) int {
+	switch command {
+	case protocol.CommandDispense, protocol.CommandTestDispense:
+		if text := bytes.Join(data, nil); len(text) >= s.countEncoding.Size() {
+			return s.countEncoding.Decode(text)
+		}
+
+		return 0
+	}
+
+	return 1
+}
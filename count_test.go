@@ -0,0 +1,31 @@
+package mm010_nrc_api_test
+
+import (
+	"context"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/mm010sim"
+	"mm010_nrc_api/protocol"
+	"testing"
+	"time"
+)
+
+func TestWideCounts(t *testing.T) {
+	sim := mm010sim.New()
+	sim.SetCountEncoding(protocol.WideCount)
+
+	c := api.NewTransportConnection("sim", sim.Conn(), api.WithTimeout(time.Second), api.WithGuardTime(0),
+		api.WithCountEncoding(api.WideCount))
+
+	defer sim.Close()
+	defer c.Close()
+
+	res, err := c.DispenseContext(context.Background(), 120)
+
+	if err != nil || res.Status != api.GoodOperation || res.NotesDispensed != 120 || sim.Notes() != 880 {
+		t.Fatalf("got %+v, %v, %d notes left", res, err, sim.Notes())
+	}
+
+	if last, err := c.LastStatusContext(context.Background()); err != nil || last.NotesDispensed != 120 {
+		t.Fatalf("last status %+v, %v", last, err)
+	}
+}
@@ -0,0 +1,179 @@
+package mm010_nrc_api
+
+import (
+	"context"
+
+	"mm010_nrc_api/protocol"
+)
+
+type TransactionCounters struct {
+	Lifelong uint64 `json:"lifelong"`
+	Trip     uint64 `json:"trip"`
+}
+
+type Counters struct {
+	DispenseLifelong       uint64 `json:"dispense_lifelong"`
+	RejectLifelong         uint64 `json:"reject_lifelong"`
+	TotalProcessedLifelong uint64 `json:"total_processed_lifelong"`
+	DispenseTrip           uint64 `json:"dispense_trip"`
+	RejectTrip             uint64 `json:"reject_trip"`
+	TotalProcessedTrip     uint64 `json:"total_processed_trip"`
+	TransactionLifelong    uint64 `json:"transaction_lifelong"`
+	TransactionTrip        uint64 `json:"transaction_trip"`
+}
+
+// ReadCounter reads a numeric data item and parses its decimal ASCII value.
+// A value that is not a number fails with ErrInvalidValue.
+func (s *MMDispenser) ReadCounter(ctx context.Context, item DataItem) (uint64, error) {
+	return ReadDataAs[uint64](ctx, s, item)
+}
+
+// parseCounter parses a counter read with readData like ReadCounter does.
+func parseCounter(item DataItem, v string) (uint64, error) {
+	n, err := parseDataValue(item, v, uint64(0))
+
+	if err != nil {
+		return 0, err
+	}
+
+	return n.(uint64), nil
+}
+
+func (s *MMDispenser) DispenseCounterLifelong(ctx context.Context) (uint64, error) {
+	return s.ReadCounter(ctx, DispenseCounterLifelong)
+}
+
+func (s *MMDispenser) RejectCounterLifelong(ctx context.Context) (uint64, error) {
+	return s.ReadCounter(ctx, RejectCounterLifelong)
+}
+
+func (s *MMDispenser) TotalProcessedCounterLifelong(ctx context.Context) (uint64, error) {
+	return s.ReadCounter(ctx, TotalProcessedCounterLifelong)
+}
+
+func (s *MMDispenser) DispenseCounterTrip(ctx context.Context) (uint64, error) {
+	return s.ReadCounter(ctx, DispenseCounterTrip)
+}
+
+func (s *MMDispenser) RejectCounterTrip(ctx context.Context) (uint64, error) {
+	return s.ReadCounter(ctx, RejectCounterTrip)
+}
+
+func (s *MMDispenser) TotalProcessedCounterTrip(ctx context.Context) (uint64, error) {
+	return s.ReadCounter(ctx, TotalProcessedCcounterTrip)
+}
+
+func (s *MMDispenser) TransactionCounters(ctx context.Context) (TransactionCounters, error) {
+	var res TransactionCounters
+	var err error
+
+	if res.Lifelong, err = s.ReadCounter(ctx, TransactionCounterLifelong); err != nil {
+		return res, err
+	}
+
+	res.Trip, err = s.ReadCounter(ctx, TransactionCounterTrip)
+
+	return res, err
+}
+
+// Counters reads all lifelong and trip counters.
+func (s *MMDispenser) Counters(ctx context.Context) (Counters, error) {
+	var res Counters
+
+	fields := []struct {
+		item DataItem
+		dst  *uint64
+	}{
+		{DispenseCounterLifelong, &res.DispenseLifelong},
+		{RejectCounterLifelong, &res.RejectLifelong},
+		{TotalProcessedCounterLifelong, &res.TotalProcessedLifelong},
+		{DispenseCounterTrip, &res.DispenseTrip},
+		{RejectCounterTrip, &res.RejectTrip},
+		{TotalProcessedCcounterTrip, &res.TotalProcessedTrip},
+		{TransactionCounterLifelong, &res.TransactionLifelong},
+		{TransactionCounterTrip, &res.TransactionTrip},
+	}
+
+	for _, f := range fields {
+		n, err := s.ReadCounter(ctx, f.item)
+
+		if err != nil {
+			return res, err
+		}
+
+		*f.dst = n
+	}
+
+	return res, nil
+}
+
+// RejectReasonReport reads the RejectReasonCounter of every known reject
+// reason. Reasons the device does not count are left out.
+func (s *MMDispenser) RejectReasonReport(ctx context.Context) (map[RejectReason]uint64, error) {
+	reasons := protocol.RejectReasons()
+	codes := make([]byte, len(reasons))
+
+	for i, r := range reasons {
+		codes[i] = byte(r)
+	}
+
+	counts, err := s.readCodeCounters(ctx, RejectReasonCounter, codes)
+	res := make(map[RejectReason]uint64, len(counts))
+
+	for code, n := range counts {
+		res[RejectReason(code)] = n
+	}
+
+	return res, err
+}
+
+// ErrorStatusReport reads the ErrorStatusCounter of every known error status.
+// Codes the device does not count are left out.
+func (s *MMDispenser) ErrorStatusReport(ctx context.Context) (map[StatusCode]uint64, error) {
+	var codes []byte
+
+	for _, c := range protocol.StatusCodes() {
+		if c.IsError() {
+			codes = append(codes, byte(c))
+		}
+	}
+
+	counts, err := s.readCodeCounters(ctx, ErrorStatusCounter, codes)
+	res := make(map[StatusCode]uint64, len(counts))
+
+	for code, n := range counts {
+		res[StatusCode(code)] = n
+	}
+
+	return res, err
+}
+
+// readCodeCounters reads a per code counter item for each code while holding
+// the link. The code is passed as the item parameter, encoded as in command
+// responses.
+func (s *MMDispenser) readCodeCounters(ctx context.Context, item DataItem, codes []byte) (map[byte]uint64, error) {
+	if err := s.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer s.release()
+
+	res := make(map[byte]uint64, len(codes))
+
+	for _, code := range codes {
+		v, err := s.readData(ctx, item, string([]byte{code}))
+
+		if err == ErrIllegalCommand {
+			continue
+		}
+
+		if err != nil {
+			return res, err
+		}
+
+		if res[code], err = parseCounter(item, v); err != nil {
+			return res, err
+		}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,84 @@
+package mm010_nrc_api_test
+
+import (
+	"context"
+	"errors"
+	api "mm010_nrc_api"
+	"testing"
+)
+
+func TestDispenseAndCounters(t *testing.T) {
+	sim, c := connect(t)
+
+	status, dispensed, _, err := c.Dispense(5)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status != api.GoodOperation || dispensed != 5 || sim.Notes() != 995 {
+		t.Fatalf("unexpected dispense %v %d, notes left %d", status, dispensed, sim.Notes())
+	}
+
+	counter, err := c.ReadData(api.DispenseCounterLifelong, "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if counter != "5" {
+		t.Fatalf("unexpected counter %q", counter)
+	}
+}
+
+func TestTypedCounters(t *testing.T) {
+	sim, c := connect(t)
+	ctx := context.Background()
+
+	if _, err := c.DispenseContext(ctx, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	counters, err := c.Counters(ctx)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if counters.DispenseLifelong != 3 || counters.TransactionTrip != 1 {
+		t.Fatalf("unexpected counters %+v", counters)
+	}
+
+	sim.SetData(api.RejectCounterTrip, "garbage")
+
+	if _, err := c.RejectCounterTrip(ctx); !errors.Is(err, api.ErrInvalidValue) {
+		t.Fatalf("expected ErrInvalidValue for a non-numeric counter, got %v", err)
+	}
+}
+
+func TestRejectAndErrorReports(t *testing.T) {
+	sim, c := connect(t)
+	ctx := context.Background()
+
+	sim.SetParamData(api.RejectReasonCounter, string(api.RejectDoubleDetect), "7")
+	sim.SetParamData(api.RejectReasonCounter, string(api.RejectTooLong), "2")
+	sim.SetParamData(api.ErrorStatusCounter, string(api.FeedFailure), "3")
+
+	rejects, err := c.RejectReasonReport(ctx)
+
+	if err != nil || len(rejects) != 2 || rejects[api.RejectDoubleDetect] != 7 || rejects[api.RejectTooLong] != 2 {
+		t.Fatalf("unexpected reject report %v %v", rejects, err)
+	}
+
+	errs, err := c.ErrorStatusReport(ctx)
+
+	if err != nil || len(errs) != 1 || errs[api.FeedFailure] != 3 {
+		t.Fatalf("unexpected error status report %v %v", errs, err)
+	}
+
+	sim.SetParamData(api.ErrorStatusCounter, string(api.FeedFailure), "three")
+
+	if _, err = c.ErrorStatusReport(ctx); !errors.Is(err, api.ErrInvalidValue) {
+		t.Fatalf("expected ErrInvalidValue for a non-numeric counter, got %v", err)
+	}
+}
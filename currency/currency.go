@@ -0,0 +1,198 @@
+// Package currency pays out amounts from a pool of dispensers, each holding
+// one denomination.
+//
+//	pool := mm010_nrc_api.NewPool(mm010_nrc_api.PoolConfig{})
+//	pool.Add("cassette-1", 50, c1)
+//	pool.Add("cassette-2", 20, c2)
+//	b, err := currency.New(pool).DispenseAmount(ctx, 90)
+package currency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	api "mm010_nrc_api"
+)
+
+// ErrAmountNotPayable is returned when the denominations in service can not
+// make up the amount.
+var ErrAmountNotPayable = errors.New("amount can not be paid with the available denominations")
+
+// Strategy splits amount into a note count per denomination. denominations
+// are distinct and sorted highest first.
+type Strategy func(amount int, denominations []int) (map[int]int, error)
+
+// validate checks the arguments of a Strategy: amount and every denomination
+// must be positive.
+func validate(amount int, denominations []int) error {
+	if amount <= 0 {
+		return fmt.Errorf("amount %d out of range", amount)
+	}
+
+	for _, d := range denominations {
+		if d <= 0 {
+			return fmt.Errorf("denomination %d out of range", d)
+		}
+	}
+
+	return nil
+}
+
+// Greedy takes as many notes of the highest denomination as fit, then of the
+// next one. It is optimal for the usual 1-2-5 series but may miss a solution
+// otherwise, e.g. 60 from 50 and 20.
+func Greedy(amount int, denominations []int) (map[int]int, error) {
+	if err := validate(amount, denominations); err != nil {
+		return nil, err
+	}
+
+	mix := map[int]int{}
+
+	for _, d := range denominations {
+		if n := amount / d; n > 0 {
+			mix[d] = n
+			amount -= n * d
+		}
+	}
+
+	if amount != 0 {
+		return nil, ErrAmountNotPayable
+	}
+
+	return mix, nil
+}
+
+// FewestNotes finds the mix with the least notes for any set of
+// denominations.
+func FewestNotes(amount int, denominations []int) (map[int]int, error) {
+	if err := validate(amount, denominations); err != nil {
+		return nil, err
+	}
+
+	// notes[a] is the least number of notes making up a, last[a] the
+	// denomination added last to get there
+	notes := make([]int, amount+1)
+	last := make([]int, amount+1)
+
+	for a := 1; a <= amount; a++ {
+		notes[a] = -1
+
+		for _, d := range denominations {
+			if d <= a && notes[a-d] >= 0 && (notes[a] < 0 || notes[a-d]+1 < notes[a]) {
+				notes[a], last[a] = notes[a-d]+1, d
+			}
+		}
+	}
+
+	if notes[amount] < 0 {
+		return nil, ErrAmountNotPayable
+	}
+
+	mix := map[int]int{}
+
+	for a := amount; a > 0; a -= last[a] {
+		mix[last[a]]++
+	}
+
+	return mix, nil
+}
+
+type Option func(p *Payer)
+
+func WithStrategy(s Strategy) Option {
+	return func(p *Payer) {
+		p.strategy = s
+	}
+}
+
+// Payer pays amounts from the units of a pool.
+type Payer struct {
+	pool     *api.Pool
+	strategy Strategy
+}
+
+// New returns a Payer using the Greedy strategy unless set otherwise.
+func New(pool *api.Pool, opts ...Option) *Payer {
+	p := &Payer{pool: pool, strategy: Greedy}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+type Breakdown struct {
+	Requested int
+	Paid      int
+	// Notes is the number of notes dispensed per denomination.
+	Notes   map[int]int
+	Results []api.PoolResult
+}
+
+// DispenseAmount plans the note mix for amount and dispenses it, highest
+// denomination first. When a denomination runs short, what is still owed is
+// planned again without it. An error other than a short dispense stops the
+// payout; Breakdown then holds what was paid so far.
+func (p *Payer) DispenseAmount(ctx context.Context, amount int) (Breakdown, error) {
+	b := Breakdown{Requested: amount, Notes: map[int]int{}}
+	exhausted := map[int]bool{}
+
+	if amount <= 0 {
+		return b, fmt.Errorf("amount %d out of range", amount)
+	}
+
+	for b.Paid < amount {
+		var denominations []int
+
+		for _, d := range p.pool.Denominations() {
+			if !exhausted[d] {
+				denominations = append(denominations, d)
+			}
+		}
+
+		mix, err := p.strategy(amount-b.Paid, denominations)
+
+		if err != nil {
+			return b, fmt.Errorf("%w: %d of %d still owed", err, amount-b.Paid, amount)
+		}
+
+		if err = p.dispense(ctx, &b, mix, exhausted); err != nil {
+			return b, err
+		}
+	}
+
+	return b, nil
+}
+
+// dispense pays mix until a denomination runs short, which it marks exhausted.
+func (p *Payer) dispense(ctx context.Context, b *Breakdown, mix map[int]int, exhausted map[int]bool) error {
+	var denominations []int
+
+	for d := range mix {
+		denominations = append(denominations, d)
+	}
+
+	sort.Sort(sort.Reverse(sort.IntSlice(denominations)))
+
+	for _, d := range denominations {
+		res, err := p.pool.Dispense(ctx, d, mix[d])
+
+		b.Results = append(b.Results, res)
+		b.Notes[d] += res.Dispensed
+		b.Paid += res.Dispensed * d
+
+		switch {
+		case err == nil:
+		case errors.Is(err, api.ErrPartialDispense), errors.Is(err, api.ErrNoUnit):
+			exhausted[d] = true
+			return nil
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
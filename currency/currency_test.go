@@ -0,0 +1,94 @@
+package currency_test
+
+import (
+	"context"
+	"errors"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/currency"
+	"mm010_nrc_api/mm010sim"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStrategies(t *testing.T) {
+	mix, err := currency.Greedy(170, []int{100, 50, 20})
+
+	if err != nil || !reflect.DeepEqual(mix, map[int]int{100: 1, 50: 1, 20: 1}) {
+		t.Fatalf("greedy: %v %v", mix, err)
+	}
+
+	if _, err = currency.Greedy(60, []int{50, 20}); !errors.Is(err, currency.ErrAmountNotPayable) {
+		t.Fatalf("greedy should miss 60 from 50 and 20, got %v", err)
+	}
+
+	if mix, err = currency.FewestNotes(60, []int{50, 20}); err != nil || !reflect.DeepEqual(mix, map[int]int{20: 3}) {
+		t.Fatalf("fewest notes: %v %v", mix, err)
+	}
+
+	if _, err = currency.FewestNotes(15, []int{50, 20}); !errors.Is(err, currency.ErrAmountNotPayable) {
+		t.Fatalf("expected ErrAmountNotPayable, got %v", err)
+	}
+
+	for _, strategy := range []currency.Strategy{currency.Greedy, currency.FewestNotes} {
+		for _, bad := range []struct {
+			amount        int
+			denominations []int
+		}{
+			{0, []int{50, 20}},
+			{-20, []int{50, 20}},
+			{60, []int{50, 0}},
+			{60, []int{50, -20}},
+		} {
+			if _, err = strategy(bad.amount, bad.denominations); err == nil || errors.Is(err, currency.ErrAmountNotPayable) {
+				t.Fatalf("expected an argument error for %d from %v, got %v", bad.amount, bad.denominations, err)
+			}
+		}
+	}
+}
+
+func TestDispenseAmountRejectsNonPositiveAmount(t *testing.T) {
+	_, c := unit(t, 10)
+
+	pool := api.NewPool(api.PoolConfig{})
+	_ = pool.Add("20", 20, c)
+
+	for _, amount := range []int{0, -40} {
+		if b, err := currency.New(pool).DispenseAmount(context.Background(), amount); err == nil || len(b.Results) != 0 {
+			t.Fatalf("expected an error for amount %d, got %+v %v", amount, b, err)
+		}
+	}
+}
+
+func unit(t *testing.T, notes int) (*mm010sim.Simulator, *api.MMDispenser) {
+	sim := mm010sim.New()
+	sim.SetNotes(notes)
+	c := api.NewTransportConnection("sim", sim.Conn(), api.WithTimeout(time.Second))
+
+	t.Cleanup(func() {
+		_ = c.Close()
+		_ = sim.Close()
+	})
+
+	return sim, c
+}
+
+func TestDispenseAmountReplansShortDenomination(t *testing.T) {
+	hundreds, c100 := unit(t, 1)
+	twenties, c20 := unit(t, 10)
+
+	pool := api.NewPool(api.PoolConfig{})
+	_ = pool.Add("100", 100, c100)
+	_ = pool.Add("20", 20, c20)
+
+	b, err := currency.New(pool).DispenseAmount(context.Background(), 240)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// two hundreds were planned, the missing one is paid with twenties
+	if b.Paid != 240 || b.Notes[100] != 1 || b.Notes[20] != 7 || hundreds.Notes() != 0 || twenties.Notes() != 3 {
+		t.Fatalf("unexpected breakdown %+v", b)
+	}
+}
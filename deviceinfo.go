@@ -0,0 +1,44 @@
+package mm010_nrc_api
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type DeviceInfo struct {
+	// ProgramID identifies the firmware the device runs.
+	ProgramID string `json:"program_id"`
+	MachineID string `json:"machine_id"`
+	// MaxNotesPerTransaction is the MaxNumberOfNotesInOneTransaction setting.
+	MaxNotesPerTransaction int `json:"max_notes_per_transaction"`
+	// Configuration holds the two hardware option bytes of ConfigurationStatus.
+	Configuration [2]byte `json:"configuration"`
+}
+
+// DeviceInfo reads the identity and configuration of the device, e.g. to make
+// sure the expected firmware is installed before dispensing.
+func (s *MMDispenser) DeviceInfo(ctx context.Context) (DeviceInfo, error) {
+	var info DeviceInfo
+
+	values, err := s.ReadDataBatch(ctx, []DataItem{ProgramID, MachineID, MaxNumberOfNotesInOneTransaction})
+
+	if err != nil {
+		return info, err
+	}
+
+	info.ProgramID = strings.TrimSpace(values[ProgramID])
+	info.MachineID = strings.TrimSpace(values[MachineID])
+
+	max := strings.TrimSpace(values[MaxNumberOfNotesInOneTransaction])
+
+	if info.MaxNotesPerTransaction, err = strconv.Atoi(max); err != nil {
+		return info, fmt.Errorf("data item %v: invalid value %q", MaxNumberOfNotesInOneTransaction, max)
+	}
+
+	c1, c2, err := s.ConfigurationStatusContext(ctx)
+	info.Configuration = [2]byte{c1, c2}
+
+	return info, err
+}
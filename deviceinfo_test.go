@@ -0,0 +1,16 @@
+package mm010_nrc_api_test
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDeviceInfo(t *testing.T) {
+	_, c := connect(t)
+
+	info, err := c.DeviceInfo(context.Background())
+
+	if err != nil || info.ProgramID != "MM010SIM" || info.MachineID != "000001" || info.MaxNotesPerTransaction != 50 {
+		t.Fatalf("unexpected device info %+v %v", info, err)
+	}
+}
@@ -0,0 +1,189 @@
+package mm010_nrc_api
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+
+	return "unknown"
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Severity) UnmarshalText(text []byte) error {
+	v, err := parseEnum(text, "severity", int(SeverityError), func(i int) string { return Severity(i).String() })
+
+	if err != nil {
+		return err
+	}
+
+	*s = Severity(v)
+
+	return nil
+}
+
+// Finding is one observation made by RunDiagnostics.
+type Finding struct {
+	Check    string   `json:"check"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+// ValueRange bounds a diagnostic reading. A zero range is not checked.
+type ValueRange struct {
+	Min int
+	Max int
+}
+
+func (r ValueRange) contains(v int) bool {
+	return r == ValueRange{} || (v >= r.Min && v <= r.Max)
+}
+
+type DiagnosticsLimits struct {
+	Sensor       ValueRange
+	DoubleDetect ValueRange
+	// ThroatCalibration bounds the ThroatSensorCalibrationValue data item.
+	ThroatCalibration ValueRange
+}
+
+// DefaultDiagnosticsLimits only flags readings at either end of the scale,
+// which point to a dark or saturated sensor. Tighten them with
+// WithDiagnosticsLimits using the values of the machine's service manual.
+var DefaultDiagnosticsLimits = DiagnosticsLimits{
+	Sensor:       ValueRange{Min: 1, Max: 0x5D},
+	DoubleDetect: ValueRange{Min: 1, Max: 0x5D},
+}
+
+func WithDiagnosticsLimits(limits DiagnosticsLimits) Option {
+	return func(s *MMDispenser) {
+		s.diagnosticsLimits = &limits
+	}
+}
+
+type DiagnosticsReport struct {
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+
+	SensorStatus       StatusCode `json:"sensor_status"`
+	SensorValues       [2]byte    `json:"sensor_values"`
+	DoubleDetectStatus StatusCode `json:"double_detect_status"`
+	DoubleDetectValues [2]byte    `json:"double_detect_values"`
+	TestModeStatus     StatusCode `json:"test_mode_status"`
+	Configuration      [2]byte    `json:"configuration"`
+	ThroatCalibration  uint64     `json:"throat_calibration"`
+
+	Findings []Finding `json:"findings"`
+}
+
+// OK reports whether no finding is an error.
+func (r DiagnosticsReport) OK() bool {
+	for _, f := range r.Findings {
+		if f.Severity == SeverityError {
+			return false
+		}
+	}
+
+	return true
+}
+
+// RunDiagnostics runs sensor diagnostics, double detect diagnostics, test mode
+// and configuration status in that order and checks the results. It stops at
+// the first command that fails and returns the report gathered so far.
+func (s *MMDispenser) RunDiagnostics(ctx context.Context) (report DiagnosticsReport, err error) {
+	limits := DefaultDiagnosticsLimits
+
+	if s.diagnosticsLimits != nil {
+		limits = *s.diagnosticsLimits
+	}
+
+	report.Started = time.Now()
+
+	defer func() {
+		report.Finished = time.Now()
+	}()
+
+	status, v1, v2, err := s.SensorDiagnosticsContext(ctx)
+
+	if err != nil {
+		return report, err
+	}
+
+	report.SensorStatus, report.SensorValues = status, [2]byte{v1, v2}
+	report.checkReadings("sensor", status, limits.Sensor, v1, v2)
+
+	status, v1, v2, err = s.DoubleDetectDiagnosticsContext(ctx)
+
+	if err != nil {
+		return report, err
+	}
+
+	report.DoubleDetectStatus, report.DoubleDetectValues = status, [2]byte{v1, v2}
+	report.checkReadings("double detect", status, limits.DoubleDetect, v1, v2)
+
+	if report.TestModeStatus, err = s.TestModeContext(ctx); err != nil {
+		return report, err
+	}
+
+	report.checkStatus("test mode", report.TestModeStatus)
+
+	if v1, v2, err = s.ConfigurationStatusContext(ctx); err != nil {
+		return report, err
+	}
+
+	report.Configuration = [2]byte{v1, v2}
+	report.add("configuration", SeverityInfo, fmt.Sprintf("configuration bytes %d, %d", v1, v2))
+
+	if limits.ThroatCalibration != (ValueRange{}) {
+		if report.ThroatCalibration, err = s.ReadCounter(ctx, ThroatSensorCalibrationValue); err != nil {
+			return report, err
+		}
+
+		if !limits.ThroatCalibration.contains(int(report.ThroatCalibration)) {
+			report.add("throat calibration", SeverityWarning, fmt.Sprintf("calibration value %d outside %d..%d",
+				report.ThroatCalibration, limits.ThroatCalibration.Min, limits.ThroatCalibration.Max))
+		}
+	}
+
+	return report, nil
+}
+
+func (r *DiagnosticsReport) checkReadings(check string, status StatusCode, limits ValueRange, values ...byte) {
+	r.checkStatus(check, status)
+
+	for i, v := range values {
+		if !limits.contains(int(v)) {
+			r.add(check, SeverityWarning, fmt.Sprintf("reading %d is %d, outside %d..%d", i+1, v, limits.Min, limits.Max))
+		}
+	}
+}
+
+func (r *DiagnosticsReport) checkStatus(check string, status StatusCode) {
+	if status.IsError() {
+		r.add(check, SeverityError, status.String())
+	}
+}
+
+func (r *DiagnosticsReport) add(check string, severity Severity, message string) {
+	r.Findings = append(r.Findings, Finding{Check: check, Severity: severity, Message: message})
+}
@@ -0,0 +1,40 @@
+package mm010_nrc_api_test
+
+import (
+	"context"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/protocol"
+	"testing"
+)
+
+func TestRunDiagnostics(t *testing.T) {
+	sim, c := connect(t)
+	ctx := context.Background()
+
+	report, err := c.RunDiagnostics(ctx)
+
+	if err != nil || !report.OK() || report.SensorValues != [2]byte{10, 60} {
+		t.Fatalf("unexpected healthy report %+v %v", report, err)
+	}
+
+	sim.FailNext(protocol.CommandSensorDiagnostics, protocol.TransportError)
+
+	if report, err = c.RunDiagnostics(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var errs, warnings int
+
+	for _, f := range report.Findings {
+		switch f.Severity {
+		case api.SeverityError:
+			errs++
+		case api.SeverityWarning:
+			warnings++
+		}
+	}
+
+	if report.OK() || errs != 1 || warnings != 2 {
+		t.Fatalf("expected the failed status and both zero readings to be flagged, got %+v", report.Findings)
+	}
+}
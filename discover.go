@@ -0,0 +1,83 @@
+package mm010_nrc_api
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// discoverTimeout bounds the status poll sent to every candidate port.
+const discoverTimeout = 500 * time.Millisecond
+
+// CandidatePorts lists the serial ports a dispenser may be attached to: COM1
+// to COM32 on Windows, /dev/ttyUSB*, /dev/ttyACM* and /dev/ttyS* elsewhere.
+func CandidatePorts() []string {
+	if runtime.GOOS == "windows" {
+		res := make([]string, 0, 32)
+
+		for i := 1; i <= 32; i++ {
+			res = append(res, fmt.Sprintf("COM%d", i))
+		}
+
+		return res
+	}
+
+	var res []string
+
+	for _, pattern := range []string{"/dev/ttyUSB*", "/dev/ttyACM*", "/dev/ttyS*"} {
+		matches, _ := filepath.Glob(pattern)
+		res = append(res, matches...)
+	}
+
+	return res
+}
+
+// DiscoverDispensers probes every candidate port with a short status poll and
+// returns the ports a dispenser answered on. opts configure the probe
+// connections, e.g. WithBaud; ports that can not be opened are skipped.
+func DiscoverDispensers(ctx context.Context, opts ...Option) []string {
+	var (
+		mu    sync.Mutex
+		found []string
+		wg    sync.WaitGroup
+	)
+
+	opts = append(append([]Option(nil), opts...), WithTimeout(discoverTimeout), WithRetryPolicy(NoRetry))
+
+	for _, port := range CandidatePorts() {
+		wg.Add(1)
+
+		go func(port string) {
+			defer wg.Done()
+
+			if probe(ctx, port, opts) {
+				mu.Lock()
+				found = append(found, port)
+				mu.Unlock()
+			}
+		}(port)
+	}
+
+	wg.Wait()
+	sort.Strings(found)
+
+	return found
+}
+
+func probe(ctx context.Context, port string, opts []Option) bool {
+	c, err := NewConnection(port, opts...)
+
+	if err != nil {
+		return false
+	}
+
+	defer c.Close()
+
+	_, err = c.StatusContext(ctx)
+
+	return err == nil
+}
@@ -0,0 +1,76 @@
+package mm010_nrc_api
+
+import (
+	"context"
+	"io"
+)
+
+//go:generate mockgen -destination mm010mock/mm010mock.go -package mm010mock mm010_nrc_api Dispenser
+
+// Dispenser is the set of operations of MMDispenser, for code that wants to
+// be tested against a mock (see package mm010mock) instead of a device. The
+// deprecated methods without a context and RunWithBudget, whose steps take
+// the concrete type, are left out.
+type Dispenser interface {
+	Open() error
+	Close() error
+	Shutdown(ctx context.Context) error
+
+	StatusContext(ctx context.Context) (Status, error)
+	PurgeContext(ctx context.Context) (StatusCode, byte, error)
+	DispenseContext(ctx context.Context, count byte) (DispenseResult, error)
+	TestDispenseContext(ctx context.Context, count byte) (DispenseResult, error)
+	ResetContext(ctx context.Context) error
+	LastStatusContext(ctx context.Context) (DispenseResult, error)
+	ConfigurationStatusContext(ctx context.Context) (byte, byte, error)
+	DoubleDetectDiagnosticsContext(ctx context.Context) (StatusCode, byte, byte, error)
+	SensorDiagnosticsContext(ctx context.Context) (StatusCode, byte, byte, error)
+	SingleNoteDispenseContext(ctx context.Context) (StatusCode, byte, byte, error)
+	SingleNoteEjectContext(ctx context.Context) (StatusCode, byte, byte, error)
+	TestModeContext(ctx context.Context) (StatusCode, error)
+	ReadDataContext(ctx context.Context, item DataItem, param string) (string, error)
+	WriteDataContext(ctx context.Context, item DataItem, data string) error
+	DispenseAsync(ctx context.Context, count byte) <-chan DispenseOutcome
+	StatusAsync(ctx context.Context) <-chan StatusOutcome
+	ReadDataBatch(ctx context.Context, items []DataItem) (map[DataItem]string, error)
+	ReadDataInt(ctx context.Context, item DataItem) (int, error)
+	ReadDataBytes(ctx context.Context, item DataItem) ([]byte, error)
+	WriteDataInt(ctx context.Context, item DataItem, v int) error
+	ExecuteRaw(ctx context.Context, code byte, payload []byte) ([]byte, error)
+	Flush(ctx context.Context) error
+	Abort() error
+	Stats() Stats
+	DumpRecentTraffic(w io.Writer) error
+	EnterMaintenanceMode(ctx context.Context) error
+	ExitMaintenanceMode()
+	InMaintenanceMode() bool
+
+	DeviceInfo(ctx context.Context) (DeviceInfo, error)
+	Configuration(ctx context.Context) (Configuration, error)
+	CompareConfiguration(ctx context.Context, expected Configuration) error
+	MachineStatus(ctx context.Context) (MachineStatusInfo, error)
+	SetBaudrate(ctx context.Context, baud Baud) error
+	AutoDetectBaud(ctx context.Context) (Baud, error)
+	SetParity(ctx context.Context, parity ParityMode) error
+	BackupParameters(ctx context.Context, w io.Writer) error
+	RestoreParameters(ctx context.Context, r io.Reader) error
+	ReadCounter(ctx context.Context, item DataItem) (uint64, error)
+	TransactionCounters(ctx context.Context) (TransactionCounters, error)
+	Counters(ctx context.Context) (Counters, error)
+	RejectReasonReport(ctx context.Context) (map[RejectReason]uint64, error)
+	ErrorStatusReport(ctx context.Context) (map[StatusCode]uint64, error)
+	BeginRejectSession(ctx context.Context) error
+	RejectAnalysis(ctx context.Context) (RejectAnalysis, error)
+
+	Healthy(ctx context.Context) error
+	Initialize(ctx context.Context) (ReadyReport, error)
+	RunDiagnostics(ctx context.Context) (DiagnosticsReport, error)
+	DispenseTransaction(ctx context.Context, count byte, policy TransactionPolicy) (Transaction, error)
+	DispenseNotes(ctx context.Context, total int) (Transaction, error)
+	DispenseIdempotent(ctx context.Context, id string, count byte) (DispenseResult, error)
+	DispenseOneByOne(ctx context.Context, n int) (OneByOneReport, error)
+	EjectOneByOne(ctx context.Context, n int) (OneByOneReport, error)
+	Watch(ctx context.Context) (<-chan StatusEvent, error)
+}
+
+var _ Dispenser = (*MMDispenser)(nil)
@@ -0,0 +1,62 @@
+package mm010_nrc_api
+
+import "context"
+
+// SetEchoSuppression enables stripping of our own transmitted bytes from the
+// receive stream, for RS-485 adapters that echo everything written to the bus.
+// It waits for the command in flight.
+func (s *MMDispenser) SetEchoSuppression(enabled bool) {
+	_ = s.acquire(context.Background())
+	defer s.release()
+
+	s.suppressEcho = enabled
+	s.echo = nil
+}
+
+func (s *MMDispenser) write(p []byte) (int, error) {
+	if s.suppressEcho {
+		s.echo = append(s.echo, p...)
+	}
+
+	n, err := s.port.Write(p)
+	s.countBytes(0, n)
+
+	if s.trace != nil {
+		s.trace.record(TraceTx, p[:n])
+	}
+
+	s.traffic.add(TraceTx, p[:n])
+
+	return n, err
+}
+
+// received records a chunk read from the port and strips our echo from it.
+func (s *MMDispenser) received(p []byte) []byte {
+	s.countBytes(len(p), 0)
+
+	if s.trace != nil {
+		s.trace.record(TraceRx, p)
+	}
+
+	if len(p) > 0 && len(s.echo) > 0 {
+		p = p[:s.stripEcho(p)]
+	}
+
+	return p
+}
+
+func (s *MMDispenser) stripEcho(p []byte) int {
+	i := 0
+
+	for i < len(p) && len(s.echo) > 0 && p[i] == s.echo[0] {
+		s.echo = s.echo[1:]
+		i++
+	}
+
+	if i < len(p) {
+		// the device is talking, whatever echo is left will not arrive anymore
+		s.echo = nil
+	}
+
+	return copy(p, p[i:])
+}
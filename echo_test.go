@@ -0,0 +1,27 @@
+package mm010_nrc_api_test
+
+import (
+	api "mm010_nrc_api"
+	"testing"
+	"time"
+)
+
+func TestTransportConnectionEchoSuppression(t *testing.T) {
+	device := answer(statusPayload)
+	port := newFakePort(func(p []byte) [][]byte {
+		return append([][]byte{append([]byte(nil), p...)}, device(p)...)
+	})
+
+	c := api.NewTransportConnection("echo", port, api.WithTimeout(time.Second))
+	c.SetEchoSuppression(true)
+
+	status, err := c.Status()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status.AverageThickness != 5 {
+		t.Fatalf("unexpected status %+v", status)
+	}
+}
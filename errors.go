@@ -0,0 +1,63 @@
+package mm010_nrc_api
+
+import (
+	"errors"
+	"fmt"
+
+	"mm010_nrc_api/protocol"
+)
+
+var (
+	ErrPortClosed          = errors.New("serial port is closed")
+	ErrPortAlreadyOpen     = errors.New("port already opened")
+	ErrReopenNotSupported  = errors.New("transport can not be reopened")
+	ErrNack                = errors.New("device answered NAK")
+	ErrUnexpectedResponse  = errors.New("unexpected response")
+	ErrNoResponse          = errors.New("device ended the exchange without a response")
+	ErrChecksumMismatch    = protocol.ErrFrameChecksum
+	ErrResponseFormat      = protocol.ErrFrameFormat
+	ErrReadTimeout         = errors.New("timeout")
+	ErrInterByteTimeout    = errors.New("inter-byte timeout")
+	ErrIllegalCommand      = errors.New("illegal command")
+	ErrItemReadOnly        = errors.New("data item is read-only")
+	ErrInvalidValue        = errors.New("invalid data item value")
+	ErrPartialDispense     = errors.New("partial dispense")
+	ErrVerificationFailed  = errors.New("dispense verification failed")
+	ErrReplayMismatch      = errors.New("write does not match the trace")
+	ErrBaudNotDetected     = errors.New("device did not answer at any baud rate")
+	ErrDispenseInDoubt     = errors.New("outcome of dispense unknown")
+	ErrAborted             = errors.New("exchange aborted")
+	ErrNothingToAbort      = errors.New("no exchange to abort")
+	ErrNoUnit              = errors.New("no dispenser in service for denomination")
+	ErrPortBusy            = errors.New("serial port is in use by another process")
+	ErrMaintenanceMode     = errors.New("dispensing is blocked in maintenance mode")
+	ErrValueOutOfRange     = errors.New("data item value out of range")
+	ErrRateLimited         = errors.New("dispenser duty cycle exceeded")
+	ErrNoRejectSession     = errors.New("no reject session begun")
+	ErrBackupVersion       = errors.New("unsupported parameter backup version")
+	ErrInvalidPortPath     = errors.New("invalid serial port path")
+	ErrCommandNotAllowed   = errors.New("command not allowed")
+	ErrCountRange          = protocol.ErrCountRange
+	ErrDoubleDetectAnomaly = errors.New("double detect anomaly")
+	ErrRejectCapExceeded   = errors.New("consecutive reject cap exceeded")
+)
+
+// ProtocolError carries the raw bytes received when a response could not be
+// understood. Err is one of the sentinel errors above or an *IdentityError.
+type ProtocolError struct {
+	Op    string
+	Frame []byte
+	Err   error
+}
+
+func (e *ProtocolError) Error() string {
+	return fmt.Sprintf("%s: %v [% X]", e.Op, e.Err, e.Frame)
+}
+
+func (e *ProtocolError) Unwrap() error {
+	return e.Err
+}
+
+// IdentityError reports a response from a unit answering with another
+// CommunicationIdentify than configured with WithCommunicationIdentify.
+type IdentityError = protocol.IdentityError
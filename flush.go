@@ -0,0 +1,48 @@
+package mm010_nrc_api
+
+import "context"
+
+// Flusher is implemented by transports that can discard the bytes pending in
+// their input and output buffers, like the ports of both serial backends.
+type Flusher interface {
+	Flush() error
+}
+
+// Flush discards everything received but not read yet and, if the transport
+// is a Flusher, whatever the port still buffers in either direction. Commands
+// do this on their own before each request and after each EOT.
+func (s *MMDispenser) Flush(ctx context.Context) error {
+	if err := s.acquire(ctx); err != nil {
+		return err
+	}
+
+	defer s.release()
+
+	if !s.isOpen() {
+		return ErrPortClosed
+	}
+
+	return s.flush()
+}
+
+func (s *MMDispenser) flush() error {
+	s.rx = append(s.rx, s.reader().discard()...)
+	s.traffic.add(TraceRx, s.rx)
+
+	switch {
+	case len(s.rx) > 0 && len(s.redactors) > 0:
+		s.log().Debugf("<- %d bytes discarded", len(s.rx))
+	case len(s.rx) > 0:
+		s.log().Debugf("<- %X discarded", s.rx)
+	}
+
+	s.rx = nil
+	s.echo = nil
+	s.link.Reset()
+
+	if f, ok := s.port.(Flusher); ok {
+		return f.Flush()
+	}
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package mm010_nrc_api_test
+
+import (
+	"context"
+	api "mm010_nrc_api"
+	"testing"
+	"time"
+)
+
+type flushingPort struct {
+	*fakePort
+	flushes int
+}
+
+func (f *flushingPort) Flush() error {
+	f.flushes++
+	return nil
+}
+
+func TestFlushAroundCommands(t *testing.T) {
+	port := &flushingPort{fakePort: newFakePort(answer(statusPayload))}
+	c := api.NewTransportConnection("fake", port, api.WithTimeout(time.Second))
+
+	if _, err := c.StatusContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if port.flushes != 2 {
+		t.Fatalf("expected a flush before the request and after EOT, got %d", port.flushes)
+	}
+
+	if err := c.Flush(context.Background()); err != nil || port.flushes != 3 {
+		t.Fatalf("Flush: %v, %d flushes", err, port.flushes)
+	}
+}
@@ -0,0 +1,78 @@
+package mm010_nrc_api
+
+import (
+	"context"
+	"fmt"
+
+	"mm010_nrc_api/protocol"
+)
+
+// FrameFormat selects between the standard frames of the MM010 and the
+// extended frames with a length field that newer firmware uses for large
+// data responses.
+type FrameFormat int
+
+const (
+	StandardFrames FrameFormat = iota
+	ExtendedFrames
+	// AutoFrames probes the firmware on the first command and uses extended
+	// frames if it understands them.
+	AutoFrames
+)
+
+func (f FrameFormat) String() string {
+	switch f {
+	case StandardFrames:
+		return "standard"
+	case ExtendedFrames:
+		return "extended"
+	case AutoFrames:
+		return "auto"
+	}
+
+	return "unknown"
+}
+
+// WithFrameFormat sets the frame format of requests, StandardFrames unless
+// set. Responses are decoded in whichever format the device sends.
+func WithFrameFormat(f FrameFormat) Option {
+	return func(s *MMDispenser) {
+		s.frameFormat = f
+		s.extended = f == ExtendedFrames
+	}
+}
+
+// negotiateFrameFormat settles AutoFrames before the first command: the
+// device is asked for its program ID in an extended frame, and firmware that
+// answers NAK only speaks standard frames. Other errors leave the format
+// open for the next command to probe again.
+func (s *MMDispenser) negotiateFrameFormat(ctx context.Context) error {
+	if s.frameFormat != AutoFrames || s.negotiated {
+		return nil
+	}
+
+	s.extended = true
+
+	err := sendRequest(ctx, s, protocol.CommandReadData, []byte(fmt.Sprintf("D/%3d", ProgramID)))
+
+	if err == nil {
+		_, err = readResponse(ctx, s, s.deadline(protocol.CommandReadData))
+	}
+
+	switch err {
+	case nil:
+	case ErrNack:
+		s.extended = false
+	default:
+		s.extended = false
+		return fmt.Errorf("frame format probe: %w", err)
+	}
+
+	s.negotiated = true
+
+	if s.extended {
+		s.log().Infof("using extended frames")
+	}
+
+	return nil
+}
@@ -0,0 +1,29 @@
+package mm010_nrc_api_test
+
+import (
+	api "mm010_nrc_api"
+	"mm010_nrc_api/mm010sim"
+	"mm010_nrc_api/protocol"
+	"testing"
+	"time"
+)
+
+func TestFrameFormatNegotiation(t *testing.T) {
+	// only an extended frame can carry TextEnd in its data
+	for extended, want := range map[bool]string{false: "000001", true: "00\x0301"} {
+		sim := mm010sim.New()
+		sim.SetExtendedFrames(extended)
+		sim.SetData(protocol.MachineID, want)
+
+		c := api.NewTransportConnection("sim", sim.Conn(), api.WithTimeout(time.Second), api.WithGuardTime(0), api.WithFrameFormat(api.AutoFrames))
+
+		v, err := c.ReadData(api.MachineID, "")
+
+		c.Close()
+		sim.Close()
+
+		if err != nil || v != want {
+			t.Fatalf("extended %v: got %q, %v", extended, v, err)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package mm010_nrc_api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+type HealthState int
+
+const (
+	Healthy HealthState = iota
+	// Degraded means the device answers but can not dispense right now.
+	Degraded
+	// Down means the device does not answer.
+	Down
+)
+
+func (h HealthState) String() string {
+	switch h {
+	case Healthy:
+		return "healthy"
+	case Degraded:
+		return "degraded"
+	case Down:
+		return "down"
+	}
+
+	return "unknown"
+}
+
+func (h HealthState) MarshalText() ([]byte, error) {
+	return []byte(h.String()), nil
+}
+
+func (h *HealthState) UnmarshalText(text []byte) error {
+	v, err := parseEnum(text, "health state", int(Down), func(i int) string { return HealthState(i).String() })
+
+	if err != nil {
+		return err
+	}
+
+	*h = HealthState(v)
+
+	return nil
+}
+
+type HealthError struct {
+	State  HealthState
+	Status Status
+	Err    error
+}
+
+func (e *HealthError) Error() string {
+	if e.Err != nil {
+		return e.State.String() + ": " + e.Err.Error()
+	}
+
+	return e.State.String()
+}
+
+func (e *HealthError) Unwrap() error {
+	return e.Err
+}
+
+// Healthy polls Status and returns nil if the device answered with clear
+// sensors. Otherwise it returns a *HealthError: Degraded for blocked sensors
+// or a garbled answer, Down if the device could not be reached.
+func (s *MMDispenser) Healthy(ctx context.Context) error {
+	status, err := s.StatusContext(ctx)
+
+	var protoErr *ProtocolError
+
+	switch {
+	case errors.As(err, &protoErr):
+		return &HealthError{State: Degraded, Err: err}
+	case err != nil:
+		return &HealthError{State: Down, Err: err}
+	case sensorsBlocked(status):
+		return &HealthError{State: Degraded, Status: status, Err: &BlockedSensorError{Status: status}}
+	}
+
+	return nil
+}
+
+// HealthHandler serves the result of Healthy as JSON for liveness probes. It
+// answers 200 while the device is healthy or degraded and 503 when it is down.
+func HealthHandler(s *MMDispenser, timeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		body := struct {
+			State string `json:"state"`
+			Error string `json:"error,omitempty"`
+		}{State: Healthy.String()}
+
+		code := http.StatusOK
+
+		if err := s.Healthy(ctx); err != nil {
+			state := Down
+			var healthErr *HealthError
+
+			if errors.As(err, &healthErr) {
+				state = healthErr.State
+			}
+
+			body.State, body.Error = state.String(), err.Error()
+
+			if state == Down {
+				code = http.StatusServiceUnavailable
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(body)
+	})
+}
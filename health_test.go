@@ -0,0 +1,41 @@
+package mm010_nrc_api_test
+
+import (
+	"context"
+	"errors"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/protocol"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHealth(t *testing.T) {
+	var healthErr *api.HealthError
+
+	healthy := api.NewTransportConnection("healthy", newFakePort(answer(func(cmd protocol.Command) []byte {
+		return []byte{0x20, 0x20, 0x20, 0x20}
+	})), api.WithTimeout(time.Second))
+
+	if err := healthy.Healthy(context.Background()); err != nil {
+		t.Fatalf("expected a healthy device, got %v", err)
+	}
+
+	blocked := api.NewTransportConnection("blocked", newFakePort(answer(statusPayload)), api.WithTimeout(time.Second))
+
+	if err := blocked.Healthy(context.Background()); !errors.As(err, &healthErr) || healthErr.State != api.Degraded {
+		t.Fatalf("expected a degraded device, got %v", err)
+	}
+
+	silent := api.NewTransportConnection("silent", newFakePort(func(p []byte) [][]byte { return nil }),
+		api.WithTimeout(50*time.Millisecond))
+
+	rec := httptest.NewRecorder()
+	api.HealthHandler(silent, time.Second).ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), `"state":"down"`) {
+		t.Fatalf("expected 503 for a silent device, got %d %s", rec.Code, rec.Body.String())
+	}
+}
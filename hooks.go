@@ -0,0 +1,106 @@
+package mm010_nrc_api
+
+import (
+	"context"
+	"fmt"
+)
+
+// WireFrame is a text frame as it goes over the wire. Raw is the whole frame
+// with framing and checksum, Text the request parameters or the response
+// text. Neither is redacted, and hooks must not keep them past the call.
+type WireFrame struct {
+	Command Command
+	Raw     []byte
+	Text    []byte
+}
+
+// BeforeSendHook sees every request frame before it is written, including
+// the repeats after a NAK and the probe of AutoFrames. An error stops the
+// request; it is returned wrapped in a *HookError.
+type BeforeSendHook func(ctx context.Context, f WireFrame) error
+
+// AfterReceiveHook sees every response frame once it was decoded, with err
+// set and no Text if it could not be. Commands answered by a bare ACK, like
+// Reset, have no response frame.
+type AfterReceiveHook func(ctx context.Context, f WireFrame, err error)
+
+// WithBeforeSend adds a hook on outgoing request frames, e.g. to feed a
+// protocol analyzer or to enforce an allowlist with AllowCommands. Hooks run
+// in the order they were added while the link is held, so they must not issue
+// commands on the same connection.
+func WithBeforeSend(hook BeforeSendHook) Option {
+	return func(s *MMDispenser) {
+		s.beforeSend = append(s.beforeSend, hook)
+	}
+}
+
+// WithAfterReceive adds a hook on incoming response frames, see
+// WithBeforeSend.
+func WithAfterReceive(hook AfterReceiveHook) Option {
+	return func(s *MMDispenser) {
+		s.afterReceive = append(s.afterReceive, hook)
+	}
+}
+
+// AllowCommands is a BeforeSendHook that refuses every command but commands
+// with ErrCommandNotAllowed.
+func AllowCommands(commands ...Command) BeforeSendHook {
+	allowed := make(map[Command]bool, len(commands))
+
+	for _, c := range commands {
+		allowed[c] = true
+	}
+
+	return func(ctx context.Context, f WireFrame) error {
+		if !allowed[f.Command] {
+			return ErrCommandNotAllowed
+		}
+
+		return nil
+	}
+}
+
+// HookError is returned when a BeforeSendHook refused a request. Nothing was
+// written for it.
+type HookError struct {
+	Command Command
+	Err     error
+}
+
+func (e *HookError) Error() string {
+	return fmt.Sprintf("%v refused before send: %v", e.Command, e.Err)
+}
+
+func (e *HookError) Unwrap() error {
+	return e.Err
+}
+
+func (s *MMDispenser) runBeforeSend(ctx context.Context, command Command, frame, text []byte) error {
+	for _, hook := range s.beforeSend {
+		f := WireFrame{Command: command, Raw: append([]byte(nil), frame...), Text: append([]byte(nil), text...)}
+
+		if err := hook(ctx, f); err != nil {
+			s.log().Errorf("-> %v refused: %v", command, err)
+			return &HookError{Command: command, Err: err}
+		}
+	}
+
+	return nil
+}
+
+func (s *MMDispenser) runAfterReceive(ctx context.Context, frame, text []byte, err error) {
+	if len(s.afterReceive) == 0 {
+		return
+	}
+
+	// a garbled frame may be too short to carry a command
+	var command Command
+
+	if len(frame) > 3 {
+		command = Command(frame[3])
+	}
+
+	for _, hook := range s.afterReceive {
+		hook(ctx, WireFrame{Command: command, Raw: append([]byte(nil), frame...), Text: append([]byte(nil), text...)}, err)
+	}
+}
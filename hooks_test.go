@@ -0,0 +1,64 @@
+package mm010_nrc_api_test
+
+import (
+	"context"
+	"errors"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/mm010sim"
+	"mm010_nrc_api/protocol"
+	"testing"
+	"time"
+)
+
+func TestWireHooks(t *testing.T) {
+	sim := mm010sim.New()
+	defer sim.Close()
+
+	var sent, received []api.WireFrame
+
+	c := api.NewTransportConnection("sim", sim.Conn(), api.WithTimeout(time.Second), api.WithGuardTime(0),
+		api.WithBlockedSensorPolicy(api.IgnoreBlockedSensor),
+		api.WithBeforeSend(func(ctx context.Context, f api.WireFrame) error {
+			sent = append(sent, f)
+			return nil
+		}),
+		api.WithBeforeSend(api.AllowCommands(protocol.CommandStatus, protocol.CommandReadData)),
+		api.WithAfterReceive(func(ctx context.Context, f api.WireFrame, err error) {
+			if err != nil {
+				t.Errorf("unexpected receive error %v", err)
+			}
+			received = append(received, f)
+		}))
+	defer c.Close()
+
+	ctx := context.Background()
+
+	if _, err := c.ReadDataContext(ctx, api.ProgramID, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sent) != 1 || sent[0].Command != protocol.CommandReadData || string(sent[0].Text) != "D/100" ||
+		sent[0].Raw[0] != protocol.RequestStart {
+		t.Fatalf("unexpected sent frames %+v", sent)
+	}
+
+	if len(received) != 1 || received[0].Command != protocol.CommandReadData || received[0].Raw[0] != protocol.ResponseStart {
+		t.Fatalf("unexpected received frames %+v", received)
+	}
+
+	_, err := c.DispenseContext(ctx, 1)
+
+	var hookErr *api.HookError
+
+	if !errors.As(err, &hookErr) || hookErr.Command != protocol.CommandDispense || !errors.Is(err, api.ErrCommandNotAllowed) {
+		t.Fatalf("expected the dispense to be refused, got %v", err)
+	}
+
+	if sim.Notes() != 1000 || len(received) != 1 {
+		t.Fatal("the refused dispense reached the device")
+	}
+
+	if _, err := c.StatusContext(ctx); err != nil {
+		t.Fatalf("link not usable after a refused command: %v", err)
+	}
+}
@@ -0,0 +1,235 @@
+// Package httpapi serves a dispenser over HTTP with JSON bodies, for systems
+// on the same host that are not written in Go.
+//
+//	GET  /status       Status
+//	GET  /counters     Counters
+//	POST /dispense     {"count": 5, "id": "optional transaction ID"}
+//	POST /diagnostics  DiagnosticsReport
+//
+// Every request must carry one of the configured keys in the X-API-Key header
+// or as a bearer token. The commands of a request are attributed to the
+// client of its key, see mm010_nrc_api.ContextWithClient. Errors are answered
+// as {"error": "..."}; a failed dispense also carries the counts of the notes
+// that left the device.
+package httpapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	api "mm010_nrc_api"
+)
+
+type Config struct {
+	// APIKeys are the accepted keys. Without any, every request is refused.
+	APIKeys []string
+	// Clients names the client of a key in metrics and audit records. A key
+	// without a name is named after its SHA-256 hash, "key-" and the first 8
+	// hex digits, so the key itself is never recorded.
+	Clients map[string]string
+	// Timeout bounds the device work of one request, 90s if zero.
+	Timeout time.Duration
+}
+
+type dispenseRequest struct {
+	Count int `json:"count"`
+	// ID makes the dispense idempotent, see MMDispenser.DispenseIdempotent. It
+	// limits Count to MaxNotesPerDispense.
+	ID string `json:"id,omitempty"`
+}
+
+type dispenseResponse struct {
+	Requested int            `json:"requested"`
+	Dispensed int            `json:"dispensed"`
+	Rejected  int            `json:"rejected"`
+	Status    api.StatusCode `json:"status"`
+	Error     string         `json:"error,omitempty"`
+}
+
+type server struct {
+	d   api.Dispenser
+	cfg Config
+}
+
+func NewHandler(d api.Dispenser, cfg Config) http.Handler {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 90 * time.Second
+	}
+
+	s := &server{d: d, cfg: cfg}
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/status", s.method(http.MethodGet, s.status))
+	mux.HandleFunc("/counters", s.method(http.MethodGet, s.counters))
+	mux.HandleFunc("/dispense", s.method(http.MethodPost, s.dispense))
+	mux.HandleFunc("/diagnostics", s.method(http.MethodPost, s.diagnostics))
+
+	return s.auth(mux)
+}
+
+func (s *server) auth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("X-API-Key")
+
+		if key == "" {
+			key = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		}
+
+		for _, k := range s.cfg.APIKeys {
+			if key != "" && subtle.ConstantTimeCompare([]byte(key), []byte(k)) == 1 {
+				next.ServeHTTP(w, r.WithContext(api.ContextWithClient(r.Context(), s.client(k))))
+				return
+			}
+		}
+
+		writeError(w, http.StatusUnauthorized, errors.New("missing or invalid API key"))
+	})
+}
+
+func (s *server) client(key string) string {
+	if name, ok := s.cfg.Clients[key]; ok {
+		return name
+	}
+
+	sum := sha256.Sum256([]byte(key))
+
+	return "key-" + hex.EncodeToString(sum[:4])
+}
+
+func (s *server) method(method string, fn func(ctx context.Context, r *http.Request) (interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), s.cfg.Timeout)
+		defer cancel()
+
+		body, err := fn(ctx, r)
+
+		if err != nil && body == nil {
+			writeError(w, errorCode(err), err)
+			return
+		}
+
+		if err != nil {
+			writeJSON(w, errorCode(err), body)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, body)
+	}
+}
+
+func (s *server) status(ctx context.Context, r *http.Request) (interface{}, error) {
+	return s.d.StatusContext(ctx)
+}
+
+func (s *server) counters(ctx context.Context, r *http.Request) (interface{}, error) {
+	return s.d.Counters(ctx)
+}
+
+func (s *server) diagnostics(ctx context.Context, r *http.Request) (interface{}, error) {
+	return s.d.RunDiagnostics(ctx)
+}
+
+func (s *server) dispense(_ context.Context, r *http.Request) (interface{}, error) {
+	var req dispenseRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, badRequest{err}
+	}
+
+	if req.Count < 1 || (req.ID != "" && req.Count > api.MaxNotesPerDispense) {
+		return nil, badRequest{errors.New("count out of range")}
+	}
+
+	// a client hanging up must not stop the device between two cycles
+	ctx, cancel := context.WithTimeout(api.ContextWithClient(context.Background(), api.ClientFromContext(r.Context())), s.cfg.Timeout)
+	defer cancel()
+
+	res := dispenseResponse{Requested: req.Count}
+
+	if req.ID != "" {
+		r, err := s.d.DispenseIdempotent(ctx, req.ID, byte(req.Count))
+
+		res.Dispensed, res.Rejected, res.Status = int(r.NotesDispensed), int(r.NotesRejected), r.Status
+
+		return res.withError(err)
+	}
+
+	tx, err := s.d.DispenseNotes(ctx, req.Count)
+
+	res.Dispensed, res.Rejected = tx.Dispensed, tx.Rejected
+
+	if len(tx.Attempts) > 0 {
+		res.Status = tx.Attempts[len(tx.Attempts)-1].Status
+	}
+
+	// a short dispense still answers with the counts, the status tells why
+	if errors.Is(err, api.ErrPartialDispense) || errors.Is(err, api.ErrRejectCapExceeded) {
+		return res, nil
+	}
+
+	return res.withError(err)
+}
+
+// withError keeps the counts in the answer to a failed dispense, so that a
+// client does not pay out again the notes that already left the device.
+func (res dispenseResponse) withError(err error) (interface{}, error) {
+	if err != nil {
+		res.Error = err.Error()
+	}
+
+	return res, err
+}
+
+type badRequest struct {
+	err error
+}
+
+func (e badRequest) Error() string {
+	return e.err.Error()
+}
+
+func errorCode(err error) int {
+	var blocked *api.BlockedSensorError
+
+	switch {
+	case errors.As(err, &badRequest{}):
+		return http.StatusBadRequest
+	case errors.As(err, &blocked):
+		return http.StatusConflict
+	case errors.Is(err, api.ErrMaintenanceMode):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, api.ErrRateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, api.ErrCommandNotAllowed):
+		return http.StatusForbidden
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, api.ErrReadTimeout):
+		return http.StatusGatewayTimeout
+	}
+
+	return http.StatusBadGateway
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,167 @@
+package httpapi_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/httpapi"
+	"mm010_nrc_api/mm010mock"
+	"mm010_nrc_api/mm010sim"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestHandler(t *testing.T) {
+	sim := mm010sim.New()
+	c := api.NewTransportConnection("sim", sim.Conn(), api.WithTimeout(time.Second))
+
+	defer sim.Close()
+	defer c.Close()
+
+	srv := httptest.NewServer(httpapi.NewHandler(c, httpapi.Config{APIKeys: []string{"secret"}}))
+	defer srv.Close()
+
+	do := func(method, path, key, body string) *http.Response {
+		req, _ := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		req.Header.Set("X-API-Key", key)
+		res, err := http.DefaultClient.Do(req)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return res
+	}
+
+	if res := do(http.MethodGet, "/status", "wrong", ""); res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a wrong key, got %d", res.StatusCode)
+	}
+
+	if res := do(http.MethodPost, "/status", "secret", ""); res.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", res.StatusCode)
+	}
+
+	res := do(http.MethodPost, "/dispense", "secret", `{"count": 3, "id": "tx-1"}`)
+
+	var body struct {
+		Dispensed int    `json:"dispensed"`
+		Status    string `json:"status"`
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil || res.StatusCode != http.StatusOK {
+		t.Fatalf("dispense answered %d: %v", res.StatusCode, err)
+	}
+
+	if body.Dispensed != 3 || body.Status != "good_operation" {
+		t.Fatalf("unexpected dispense response %+v", body)
+	}
+
+	if res := do(http.MethodPost, "/dispense", "secret", `{"count": 0}`); res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a zero count, got %d", res.StatusCode)
+	}
+}
+
+func TestDispenseKeepsCounts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	d := mm010mock.NewMockDispenser(ctrl)
+	h := httpapi.NewHandler(d, httpapi.Config{APIKeys: []string{"secret"}})
+
+	// the client hung up, which must not cancel the dispense
+	gone, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	dispense := func(tx api.Transaction, err error) (int, map[string]interface{}) {
+		d.EXPECT().DispenseNotes(gomock.Any(), 120).DoAndReturn(func(ctx context.Context, total int) (api.Transaction, error) {
+			if ctx.Err() != nil {
+				return api.Transaction{}, ctx.Err()
+			}
+
+			return tx, err
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/dispense", strings.NewReader(`{"count": 120}`)).WithContext(gone)
+		req.Header.Set("X-API-Key", "secret")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		var body map[string]interface{}
+
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		return rec.Code, body
+	}
+
+	code, body := dispense(api.Transaction{Requested: 120, Dispensed: 100, Rejected: 6},
+		fmt.Errorf("%w: 3 notes rejected in a row", api.ErrRejectCapExceeded))
+
+	if code != http.StatusOK || body["dispensed"] != 100.0 || body["rejected"] != 6.0 {
+		t.Fatalf("expected a short dispense for the reject cap, got %d %v", code, body)
+	}
+
+	code, body = dispense(api.Transaction{Requested: 120, Dispensed: 50}, api.ErrReadTimeout)
+
+	if code != http.StatusGatewayTimeout || body["dispensed"] != 50.0 || body["error"] != api.ErrReadTimeout.Error() {
+		t.Fatalf("expected the counts along with the timeout, got %d %v", code, body)
+	}
+}
+
+type auditRecords []api.AuditRecord
+
+func (r *auditRecords) Audit(rec api.AuditRecord) error {
+	*r = append(*r, rec)
+	return nil
+}
+
+func TestClientAttribution(t *testing.T) {
+	sim := mm010sim.New()
+	defer sim.Close()
+
+	var (
+		records auditRecords
+		events  []api.CommandEvent
+	)
+
+	c := api.NewTransportConnection("sim", sim.Conn(), api.WithTimeout(time.Second), api.WithAuditLogger(&records),
+		api.WithObserver(func(e api.CommandEvent) { events = append(events, e) }))
+	defer c.Close()
+
+	h := httpapi.NewHandler(c, httpapi.Config{APIKeys: []string{"secret", "other"}, Clients: map[string]string{"secret": "kiosk"}})
+
+	do := func(method, path, key, body string) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-API-Key", key)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s %s answered %d", method, path, rec.Code)
+		}
+	}
+
+	do(http.MethodPost, "/dispense", "secret", `{"count": 2}`)
+
+	if len(records) == 0 || records[len(records)-1].Client != "kiosk" {
+		t.Fatalf("expected the dispense audited for kiosk, got %+v", records)
+	}
+
+	events = nil
+	do(http.MethodGet, "/status", "other", "")
+
+	sum := sha256.Sum256([]byte("other"))
+
+	if len(events) != 1 || events[0].Client != "key-"+hex.EncodeToString(sum[:4]) {
+		t.Fatalf("expected the status attributed to the hash of the key, got %+v", events)
+	}
+}
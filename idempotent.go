@@ -0,0 +1,177 @@
+package mm010_nrc_api
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// DispenseRecord is what DispenseIdempotent keeps about a transaction ID.
+type DispenseRecord struct {
+	ID    string `json:"id"`
+	Count byte   `json:"count"`
+	// Transactions is the TransactionCounterLifelong value read before the
+	// dispense was sent.
+	Transactions uint64 `json:"transactions"`
+	// Settled is set once the outcome is known, Result is only valid then.
+	Settled bool           `json:"settled"`
+	Result  DispenseResult `json:"result"`
+}
+
+// DispenseStore keeps DispenseRecords. A store that survives restarts of the
+// host also protects against double dispensing across them.
+type DispenseStore interface {
+	Load(id string) (DispenseRecord, bool, error)
+	Save(r DispenseRecord) error
+	Delete(id string) error
+}
+
+type memoryDispenseStore struct {
+	mu      sync.Mutex
+	records map[string]DispenseRecord
+}
+
+// NewMemoryDispenseStore returns the store DispenseIdempotent uses unless one
+// is set with WithDispenseStore.
+func NewMemoryDispenseStore() DispenseStore {
+	return &memoryDispenseStore{records: map[string]DispenseRecord{}}
+}
+
+func (m *memoryDispenseStore) Load(id string) (DispenseRecord, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, ok := m.records[id]
+
+	return r, ok, nil
+}
+
+func (m *memoryDispenseStore) Save(r DispenseRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.records[r.ID] = r
+
+	return nil
+}
+
+func (m *memoryDispenseStore) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.records, id)
+
+	return nil
+}
+
+func WithDispenseStore(store DispenseStore) Option {
+	return func(s *MMDispenser) {
+		s.dispenseStore = store
+	}
+}
+
+// DispenseIdempotent dispenses count notes at most once per id. Repeating a
+// settled id returns the stored result without dispensing. When a dispense
+// fails, the device's transaction counter tells whether it was executed: if
+// so, its result is taken from LastStatus, stored and returned without error,
+// otherwise the id is released so a retry dispenses. If the device can not be
+// asked, the error wraps ErrDispenseInDoubt and the next call with the same id
+// asks again.
+//
+// The link is not held from the first counter read to the last, so calls of
+// DispenseIdempotent wait for each other, and no other note moving command,
+// e.g. DispenseContext, may run meanwhile: its transaction would be taken for
+// the one of id.
+func (s *MMDispenser) DispenseIdempotent(ctx context.Context, id string, count byte) (DispenseResult, error) {
+	select {
+	case s.idempotent <- struct{}{}:
+	case <-ctx.Done():
+		return DispenseResult{}, ctx.Err()
+	}
+	defer func() { <-s.idempotent }()
+
+	store := s.dispenses()
+	r, ok, err := store.Load(id)
+
+	if err != nil {
+		return DispenseResult{}, err
+	}
+
+	if ok && r.Count != count {
+		return DispenseResult{}, fmt.Errorf("transaction %q was for %d notes, not %d", id, r.Count, count)
+	}
+
+	if ok && !r.Settled {
+		if r, err = s.settleDispense(ctx, r); err != nil {
+			return DispenseResult{}, err
+		}
+
+		ok = r.Settled
+	}
+
+	if ok {
+		return r.Result, nil
+	}
+
+	r = DispenseRecord{ID: id, Count: count}
+
+	if r.Transactions, err = s.ReadCounter(ctx, TransactionCounterLifelong); err != nil {
+		return DispenseResult{}, err
+	}
+
+	if err = store.Save(r); err != nil {
+		return DispenseResult{}, err
+	}
+
+	res, err := s.DispenseContext(ctx, count)
+
+	if err != nil {
+		r, serr := s.settleDispense(ctx, r)
+
+		switch {
+		case serr != nil:
+			return DispenseResult{}, fmt.Errorf("%w (dispense: %v)", serr, err)
+		case !r.Settled:
+			return DispenseResult{}, err
+		}
+
+		return r.Result, nil
+	}
+
+	r.Settled, r.Result = true, res
+
+	return res, store.Save(r)
+}
+
+// settleDispense finds out whether the dispense of r reached the device.
+func (s *MMDispenser) settleDispense(ctx context.Context, r DispenseRecord) (DispenseRecord, error) {
+	store := s.dispenses()
+	transactions, err := s.ReadCounter(ctx, TransactionCounterLifelong)
+
+	if err != nil {
+		return r, fmt.Errorf("%w: transaction %q: %v", ErrDispenseInDoubt, r.ID, err)
+	}
+
+	if transactions == r.Transactions {
+		return r, store.Delete(r.ID)
+	}
+
+	if r.Result, err = s.LastStatusContext(ctx); err != nil {
+		return r, fmt.Errorf("%w: transaction %q: %v", ErrDispenseInDoubt, r.ID, err)
+	}
+
+	r.Settled = true
+
+	return r, store.Save(r)
+}
+
+func (s *MMDispenser) dispenses() DispenseStore {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.dispenseStore == nil {
+		s.dispenseStore = NewMemoryDispenseStore()
+	}
+
+	return s.dispenseStore
+}
@@ -0,0 +1,70 @@
+package mm010_nrc_api_test
+
+import (
+	"context"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/mm010sim"
+	"mm010_nrc_api/protocol"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDispenseIdempotent(t *testing.T) {
+	sim := mm010sim.New()
+	c := api.NewTransportConnection("sim", sim.Conn(), api.WithTimeout(time.Second),
+		api.WithClassTimeout(api.DispenseCommand, time.Second))
+	ctx := context.Background()
+
+	defer sim.Close()
+
+	sim.SetNotes(10)
+	sim.DropNext(protocol.CommandDispense)
+
+	res, err := c.DispenseIdempotent(ctx, "tx-1", 2)
+
+	if err != nil || res.NotesDispensed != 2 {
+		t.Fatalf("expected the unanswered dispense to be settled from LastStatus, got %+v %v", res, err)
+	}
+
+	if res, err = c.DispenseIdempotent(ctx, "tx-1", 2); err != nil || res.NotesDispensed != 2 || sim.Notes() != 8 {
+		t.Fatalf("repeated id dispensed again: %+v %v, %d notes left", res, err, sim.Notes())
+	}
+
+	sim.SetSensors(true, false)
+
+	if _, err = c.DispenseIdempotent(ctx, "tx-2", 1); err == nil {
+		t.Fatal("expected the blocked sensor to fail the dispense")
+	}
+
+	sim.SetSensors(false, false)
+
+	if res, err = c.DispenseIdempotent(ctx, "tx-2", 1); err != nil || res.NotesDispensed != 1 || sim.Notes() != 7 {
+		t.Fatalf("retry of a dispense that never ran: %+v %v, %d notes left", res, err, sim.Notes())
+	}
+}
+
+func TestDispenseIdempotentConcurrent(t *testing.T) {
+	sim, c := connect(t)
+	sim.SetNotes(10)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if res, err := c.DispenseIdempotent(context.Background(), "tx-1", 2); err != nil || res.NotesDispensed != 2 {
+				t.Errorf("unexpected result %+v %v", res, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if sim.Notes() != 8 {
+		t.Fatalf("expected one dispense of 2 notes, %d notes left", sim.Notes())
+	}
+}
@@ -0,0 +1,89 @@
+package mm010_nrc_api
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DefaultInitializeTimeout bounds Initialize when ctx has no deadline.
+const DefaultInitializeTimeout = 30 * time.Second
+
+// ReadyReport is what Initialize found out about the device.
+type ReadyReport struct {
+	Status         Status        `json:"status"`
+	Configuration1 byte          `json:"configuration_1"`
+	Configuration2 byte          `json:"configuration_2"`
+	Counters       Counters      `json:"counters"`
+	StatusPolls    int           `json:"status_polls"`
+	Duration       time.Duration `json:"duration"`
+	// Ready is false while a sensor is blocked, which makes dispenses fail.
+	Ready bool `json:"ready"`
+}
+
+// Initialize runs the power-on sequence: Reset, Status every poll interval
+// until the device stops reporting the reset, ConfigurationStatus and a
+// snapshot of the counters. It gives up after DefaultInitializeTimeout
+// unless ctx has a deadline. The report holds what was gathered before an
+// error, with the step that failed named in the error.
+func (s *MMDispenser) Initialize(ctx context.Context) (ReadyReport, error) {
+	started := time.Now()
+	res := ReadyReport{}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultInitializeTimeout)
+		defer cancel()
+	}
+
+	err := s.initialize(ctx, &res)
+	res.Duration = time.Since(started)
+
+	return res, err
+}
+
+func (s *MMDispenser) initialize(ctx context.Context, res *ReadyReport) error {
+	if err := s.ResetContext(ctx); err != nil {
+		return fmt.Errorf("reset: %w", err)
+	}
+
+	var err error
+
+	if res.Status, res.StatusPolls, err = s.awaitReset(ctx); err != nil {
+		return fmt.Errorf("status: %w", err)
+	}
+
+	res.Ready = !sensorsBlocked(res.Status)
+
+	if res.Configuration1, res.Configuration2, err = s.ConfigurationStatusContext(ctx); err != nil {
+		return fmt.Errorf("configuration status: %w", err)
+	}
+
+	if res.Counters, err = s.Counters(ctx); err != nil {
+		return fmt.Errorf("counters: %w", err)
+	}
+
+	return nil
+}
+
+// awaitReset polls Status every poll interval until the device stops
+// reporting a reset, and returns the last status and the number of polls.
+func (s *MMDispenser) awaitReset(ctx context.Context) (Status, int, error) {
+	interval := s.pollInterval
+
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	for polls := 1; ; polls++ {
+		status, err := s.StatusContext(ctx)
+
+		if err != nil || !status.ResetSinceLastStatusMessage {
+			return status, polls, err
+		}
+
+		if err = sleep(ctx, interval); err != nil {
+			return status, polls, err
+		}
+	}
+}
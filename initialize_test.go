@@ -0,0 +1,32 @@
+package mm010_nrc_api_test
+
+import (
+	"context"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/mm010sim"
+	"testing"
+	"time"
+)
+
+func TestInitialize(t *testing.T) {
+	sim := mm010sim.New()
+	c := api.NewTransportConnection("sim", sim.Conn(), api.WithTimeout(time.Second), api.WithGuardTime(0),
+		api.WithPollInterval(10*time.Millisecond))
+
+	defer sim.Close()
+	defer c.Close()
+
+	if _, err := c.DispenseContext(context.Background(), 3); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := c.Initialize(context.Background())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !report.Ready || report.StatusPolls != 2 || report.Status.ResetSinceLastStatusMessage || report.Counters.DispenseLifelong != 3 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+}
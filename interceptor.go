@@ -0,0 +1,67 @@
+package mm010_nrc_api
+
+import "context"
+
+// Invoker runs a command exchange and returns the response text; Reset,
+// which is answered by a bare ACK, returns none.
+type Invoker func(ctx context.Context, command Command, data []byte) ([]byte, error)
+
+// Interceptor wraps every command exchange, e.g. for logging, tracing, rate
+// limits or policy checks like a cap on notes per minute. It calls next to
+// run the exchange, possibly with a changed ctx or data, or returns an error
+// to stop the command. It runs while the link is held, so it must not issue
+// commands on the same connection.
+type Interceptor func(ctx context.Context, command Command, data []byte, next Invoker) ([]byte, error)
+
+// WithInterceptor adds i to the chain around command exchanges. The first
+// one added is the outermost.
+func WithInterceptor(i Interceptor) Option {
+	return func(s *MMDispenser) {
+		s.interceptors = append(s.interceptors, i)
+	}
+}
+
+// ExchangeInfo tells how an exchange went on the line: how often the request
+// was repeated after a NAK and how many bytes went out and came in.
+type ExchangeInfo struct {
+	Retries  int
+	BytesOut int
+	BytesIn  int
+}
+
+type exchangeInfoKey struct{}
+
+// WithExchangeInfo returns a context and an ExchangeInfo that a command run
+// with that context fills in. Interceptors pass the context to next.
+func WithExchangeInfo(ctx context.Context) (context.Context, *ExchangeInfo) {
+	info := &ExchangeInfo{}
+
+	return context.WithValue(ctx, exchangeInfoKey{}, info), info
+}
+
+// recordExchange fills in the ExchangeInfo of ctx, if any, with what changed
+// in stats since before.
+func (s *MMDispenser) recordExchange(ctx context.Context, before Stats, retries int) {
+	info, ok := ctx.Value(exchangeInfoKey{}).(*ExchangeInfo)
+
+	if !ok {
+		return
+	}
+
+	after := s.Stats()
+	info.Retries = retries
+	info.BytesOut = int(after.BytesOut - before.BytesOut)
+	info.BytesIn = int(after.BytesIn - before.BytesIn)
+}
+
+func (s *MMDispenser) intercept(ctx context.Context, command Command, data []byte, invoker Invoker) ([]byte, error) {
+	for i := len(s.interceptors) - 1; i >= 0; i-- {
+		next, interceptor := invoker, s.interceptors[i]
+
+		invoker = func(ctx context.Context, command Command, data []byte) ([]byte, error) {
+			return interceptor(ctx, command, data, next)
+		}
+	}
+
+	return invoker(ctx, command, data)
+}
@@ -0,0 +1,93 @@
+package mm010_nrc_api_test
+
+import (
+	"context"
+	"errors"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/mm010sim"
+	"mm010_nrc_api/protocol"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInterceptors(t *testing.T) {
+	sim := mm010sim.New()
+	errLimit := errors.New("note limit reached")
+
+	var calls []string
+	notes := 0
+
+	trace := func(ctx context.Context, command api.Command, data []byte, next api.Invoker) ([]byte, error) {
+		calls = append(calls, command.String())
+		return next(ctx, command, data)
+	}
+
+	// allow 5 notes in total
+	limit := func(ctx context.Context, command api.Command, data []byte, next api.Invoker) ([]byte, error) {
+		if command == protocol.CommandDispense {
+			if notes+int(data[0]-0x20) > 5 {
+				return nil, errLimit
+			}
+
+			notes += int(data[0] - 0x20)
+		}
+
+		return next(ctx, command, data)
+	}
+
+	c := api.NewTransportConnection("sim", sim.Conn(), api.WithTimeout(time.Second), api.WithGuardTime(0),
+		api.WithInterceptor(trace), api.WithInterceptor(limit))
+
+	defer sim.Close()
+	defer c.Close()
+
+	if _, err := c.DispenseContext(context.Background(), 3); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.DispenseContext(context.Background(), 3); !errors.Is(err, errLimit) {
+		t.Fatalf("expected the limit to stop the dispense, got %v", err)
+	}
+
+	if err := c.ResetContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "Status Dispense Status Dispense Reset"; strings.Join(calls, " ") != want || sim.Notes() != 997 {
+		t.Fatalf("got calls %q, %d notes left", calls, sim.Notes())
+	}
+}
+
+func TestExchangeInfo(t *testing.T) {
+	sim := mm010sim.New()
+
+	var infos []api.ExchangeInfo
+
+	record := func(ctx context.Context, command api.Command, data []byte, next api.Invoker) ([]byte, error) {
+		ctx, info := api.WithExchangeInfo(ctx)
+		response, err := next(ctx, command, data)
+		infos = append(infos, *info)
+
+		return response, err
+	}
+
+	c := api.NewTransportConnection("sim", sim.Conn(), api.WithTimeout(time.Second), api.WithGuardTime(0),
+		api.WithInterceptor(record))
+
+	defer sim.Close()
+	defer c.Close()
+
+	sim.NakNext(1)
+
+	if _, err := c.StatusContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := c.Stats()
+
+	if len(infos) != 1 || infos[0].Retries != 1 ||
+		uint64(infos[0].BytesOut) != stats.BytesOut || uint64(infos[0].BytesIn) != stats.BytesIn || infos[0].BytesIn == 0 {
+		t.Fatalf("got %+v, stats %+v", infos, stats)
+	}
+}
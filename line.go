@@ -0,0 +1,206 @@
+package mm010_nrc_api
+
+import (
+	"context"
+	"time"
+
+	"mm010_nrc_api/protocol"
+)
+
+// unit is one thing the device sent: a control byte or a whole text frame.
+type unit struct {
+	control byte
+	frame   []byte
+}
+
+// readResponse drives the link through the device's side of the exchange
+// until EOT, all of which must arrive before deadline.
+func readResponse(ctx context.Context, v *MMDispenser, deadline time.Time) ([]byte, error) {
+	var data []byte
+
+	for {
+		u, err := v.readUnit(ctx, deadline)
+
+		if err != nil {
+			v.link.Reset()
+			return nil, err
+		}
+
+		var action LinkAction
+
+		if u.frame != nil {
+			action, err = v.link.Text()
+		} else {
+			state := v.link.State()
+			action, err = v.link.Control(u.control)
+
+			if err == nil && action == LinkWait && u.control == protocol.Eot && state == LinkAwaitingAck {
+				v.log().Debugf("<- stale EOT ignored")
+			}
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		switch action {
+		case LinkAccept:
+			data, err = v.decodeResponse(u.frame)
+			v.runAfterReceive(ctx, u.frame, data, err)
+
+			if err == nil {
+				data, err = v.acknowledge(protocol.Command(u.frame[3]), data)
+			}
+
+			if err != nil {
+				v.link.Reset()
+				return nil, err
+			}
+		case LinkAckOnly:
+			v.log().Debugf("<- repeated response")
+
+			if err := v.sendControl(protocol.Ack); err != nil {
+				v.link.Reset()
+				return nil, err
+			}
+		case LinkDone:
+			// nothing may follow EOT, so what did is left over from an
+			// aborted transaction and must not be taken for the next response
+			if err := v.flush(); err != nil {
+				v.log().Errorf("flush after EOT: %v", err)
+			}
+
+			return data, nil
+		}
+	}
+}
+
+// readAck waits for the ACK that is the whole answer to a Reset.
+func readAck(ctx context.Context, v *MMDispenser, deadline time.Time) error {
+	defer v.link.Reset()
+
+	for {
+		u, err := v.readUnit(ctx, deadline)
+
+		switch {
+		case err != nil:
+			return err
+		case u.control == protocol.Ack:
+			return nil
+		case u.control == protocol.Nack:
+			return ErrNack
+		case u.control == protocol.Eot:
+			v.log().Debugf("<- stale EOT ignored")
+		default:
+			return &ProtocolError{Op: LinkAwaitingAck.String(), Frame: append([]byte{u.control}, u.frame...), Err: ErrUnexpectedResponse}
+		}
+	}
+}
+
+func (s *MMDispenser) decodeResponse(frame []byte) ([]byte, error) {
+	data, err := protocol.DecodeResponseWith(s.checksum, s.identify, frame)
+
+	if err != nil {
+		if len(s.redactors) > 0 {
+			s.log().Errorf("<- %d bytes: %v", len(frame), err)
+		} else {
+			s.log().Errorf("<- %X: %v", frame, err)
+		}
+
+		return nil, &ProtocolError{Op: "read response", Frame: frame, Err: err}
+	}
+
+	s.log().Debugf("<- %v %X", protocol.Command(frame[3]), s.redact(protocol.Command(frame[3]), data))
+
+	return data, nil
+}
+
+// readUnit returns the next control byte or text frame. Bytes read past it
+// stay buffered for the next call. Once part of a frame arrived, the gap
+// between reads may not exceed the inter-byte timeout either, if one is set.
+func (s *MMDispenser) readUnit(ctx context.Context, deadline time.Time) (unit, error) {
+	for {
+		if u, ok := s.nextUnit(); ok {
+			return u, nil
+		}
+
+		gap := time.Duration(0)
+
+		if len(s.rx) > 0 {
+			gap = s.interByteTimeout
+		}
+
+		chunk, err := s.readChunk(ctx, deadline, gap)
+
+		if err != nil {
+			return unit{}, err
+		}
+
+		s.rx = append(s.rx, chunk...)
+	}
+}
+
+func (s *MMDispenser) nextUnit() (unit, bool) {
+	if len(s.rx) == 0 {
+		return unit{}, false
+	}
+
+	if s.rx[0] != protocol.ResponseStart {
+		u := unit{control: s.rx[0]}
+		s.rx = s.rx[1:]
+		s.traffic.add(TraceRx, []byte{u.control})
+
+		switch u.control {
+		case protocol.Ack:
+			s.log().Debugf("<- ACK")
+		case protocol.Nack:
+			s.log().Debugf("<- NAK")
+			s.countNack()
+		case protocol.Eot:
+			s.log().Debugf("<- EOT")
+		}
+
+		return u, true
+	}
+
+	n, ok := protocol.FrameLength(s.checksum, s.rx)
+
+	if !ok {
+		return unit{}, false
+	}
+
+	u := unit{frame: append([]byte(nil), s.rx[:n]...)}
+	s.rx = s.rx[n:]
+	s.traffic.add(TraceRx, u.frame)
+
+	return u, true
+}
+
+type chunk struct {
+	data []byte
+	err  error
+}
+
+// readChunk waits for the next chunk from the port until deadline, or for gap
+// if that is set.
+func (s *MMDispenser) readChunk(ctx context.Context, deadline time.Time, gap time.Duration) ([]byte, error) {
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	var gapTimer <-chan time.Time
+
+	if gap > 0 {
+		gapTimer = time.After(gap)
+	}
+
+	select {
+	case c := <-s.reader().chunks:
+		return s.received(c.data), c.err
+	case <-gapTimer:
+		return nil, ErrInterByteTimeout
+	case <-timer.C:
+		return nil, ErrReadTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,47 @@
+package mm010_nrc_api_test
+
+import (
+	"errors"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/protocol"
+	"testing"
+	"time"
+)
+
+func TestLineDiscipline(t *testing.T) {
+	status := protocol.EncodeResponse(protocol.CommunicationIdentify, protocol.CommandStatus, []byte{0x21, 0x20, 0x20, 0x20})
+
+	cases := []struct {
+		name     string
+		response [][]byte
+		afterAck [][]byte
+		err      error
+	}{
+		{"ack and text in one read", [][]byte{append([]byte{protocol.Ack}, status...)}, [][]byte{{protocol.Eot}}, nil},
+		{"stale eot before ack", [][]byte{{protocol.Eot, protocol.Ack}, status}, [][]byte{{protocol.Eot}}, nil},
+		{"lost ack", [][]byte{status}, [][]byte{{protocol.Eot}}, nil},
+		{"repeated response", [][]byte{{protocol.Ack}, status}, [][]byte{status, {protocol.Eot}}, nil},
+		{"eot before data", [][]byte{{protocol.Ack}, {protocol.Eot}}, nil, api.ErrNoResponse},
+	}
+
+	for _, tc := range cases {
+		acks := 0
+		port := newFakePort(func(p []byte) [][]byte {
+			if p[0] == protocol.RequestStart {
+				return tc.response
+			}
+
+			if acks++; acks == 1 {
+				return tc.afterAck
+			}
+
+			return [][]byte{{protocol.Eot}}
+		})
+
+		s, err := api.NewTransportConnection(tc.name, port, api.WithTimeout(time.Second), api.WithRetryPolicy(api.NoRetry)).Status()
+
+		if !errors.Is(err, tc.err) || (err == nil && !s.FeedSensorBlocked) {
+			t.Errorf("%s: expected %v, got %+v %v", tc.name, tc.err, s, err)
+		}
+	}
+}
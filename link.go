@@ -0,0 +1,153 @@
+package mm010_nrc_api
+
+import (
+	"time"
+
+	"mm010_nrc_api/protocol"
+)
+
+// DefaultGuardTime is how long the host keeps quiet after the EOT of an
+// exchange before it sends the next request.
+const DefaultGuardTime = 200 * time.Millisecond
+
+// LinkState is where the host is in the exchange that follows a request:
+// the device answers ACK, sends the response text, which the host ACKs, and
+// ends with EOT.
+type LinkState int
+
+const (
+	LinkIdle LinkState = iota
+	LinkAwaitingAck
+	LinkReceivingText
+	LinkAwaitingEot
+)
+
+var linkStateOps = map[LinkState]string{
+	LinkIdle:          "idle",
+	LinkAwaitingAck:   "wait for ACK",
+	LinkReceivingText: "wait for response",
+	LinkAwaitingEot:   "wait for EOT",
+}
+
+func (l LinkState) String() string {
+	if op, ok := linkStateOps[l]; ok {
+		return op
+	}
+
+	return "unknown"
+}
+
+// LinkAction tells the driver of a Link what to do with what it received.
+type LinkAction int
+
+const (
+	// LinkWait means to keep reading.
+	LinkWait LinkAction = iota
+	// LinkAccept means to keep the response text and ACK it.
+	LinkAccept
+	// LinkAckOnly means to ACK a repeated response text and drop it.
+	LinkAckOnly
+	// LinkDone means the exchange is complete.
+	LinkDone
+)
+
+// Link is the host side of the handshake, without any I/O: the driver reports
+// the request it sent and every control byte and text frame it receives, and
+// follows the returned actions. It tolerates what a noisy or out of step line
+// produces: a stale EOT before the ACK, a response without ACK when the ACK
+// was lost, duplicate ACKs, a repeated response when our ACK was lost and
+// stray bytes while idle. After an error the link is idle again.
+type Link struct {
+	state LinkState
+	guard time.Duration
+	ended time.Time
+}
+
+// SetGuardTime sets the quiet time after EOT, zero by default.
+func (l *Link) SetGuardTime(d time.Duration) {
+	l.guard = d
+}
+
+// ReadyAt is the earliest time the next request may be sent: the guard time
+// after the last exchange ended with EOT.
+func (l *Link) ReadyAt() time.Time {
+	if l.ended.IsZero() {
+		return time.Time{}
+	}
+
+	return l.ended.Add(l.guard)
+}
+
+func (l *Link) State() LinkState {
+	return l.state
+}
+
+// Request starts a new exchange, abandoning one still in progress.
+func (l *Link) Request() {
+	l.state = LinkAwaitingAck
+}
+
+// Reset makes the link idle, e.g. after the line was flushed.
+func (l *Link) Reset() {
+	l.state = LinkIdle
+}
+
+// Text reports a response text frame. It must have been checked already;
+// one that could not be decoded is an error of the driver.
+func (l *Link) Text() (LinkAction, error) {
+	switch l.state {
+	case LinkIdle:
+		return LinkWait, nil
+	case LinkAwaitingEot:
+		return LinkAckOnly, nil
+	}
+
+	l.state = LinkAwaitingEot
+
+	return LinkAccept, nil
+}
+
+// Reject takes back the acceptance of a response text the host answered
+// NAK, so the repetition the device sends next is accepted in its place.
+func (l *Link) Reject() {
+	if l.state == LinkAwaitingEot {
+		l.state = LinkReceivingText
+	}
+}
+
+// Control reports a control byte.
+func (l *Link) Control(b byte) (LinkAction, error) {
+	state := l.state
+
+	switch {
+	case state == LinkIdle:
+		return LinkWait, nil
+	case b == protocol.Ack:
+		if state == LinkAwaitingAck {
+			l.state = LinkReceivingText
+		}
+
+		return LinkWait, nil
+	case b == protocol.Nack && state == LinkAwaitingEot:
+		// the request was already executed, so this must not look like a
+		// rejected request to the retry logic in command
+		return l.fail(&ProtocolError{Op: state.String(), Frame: []byte{b}, Err: ErrNack})
+	case b == protocol.Nack:
+		return l.fail(ErrNack)
+	case b == protocol.Eot && state == LinkAwaitingAck:
+		return LinkWait, nil
+	case b == protocol.Eot && state == LinkReceivingText:
+		return l.fail(&ProtocolError{Op: state.String(), Frame: []byte{b}, Err: ErrNoResponse})
+	case b == protocol.Eot:
+		l.state = LinkIdle
+		l.ended = time.Now()
+		return LinkDone, nil
+	}
+
+	return l.fail(&ProtocolError{Op: state.String(), Frame: []byte{b}, Err: ErrUnexpectedResponse})
+}
+
+func (l *Link) fail(err error) (LinkAction, error) {
+	l.state = LinkIdle
+	return LinkWait, err
+}
@@ -0,0 +1,53 @@
+package mm010_nrc_api_test
+
+import (
+	"errors"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/protocol"
+	"testing"
+)
+
+func TestLinkStateMachine(t *testing.T) {
+	var l api.Link
+
+	if a, err := l.Control(0x7F); a != api.LinkWait || err != nil || l.State() != api.LinkIdle {
+		t.Fatalf("garbage while idle: %v %v %v", a, err, l.State())
+	}
+
+	l.Request()
+	steps := []struct {
+		text    bool
+		control byte
+		action  api.LinkAction
+		state   api.LinkState
+	}{
+		{control: protocol.Eot, action: api.LinkWait, state: api.LinkAwaitingAck},
+		{control: protocol.Ack, action: api.LinkWait, state: api.LinkReceivingText},
+		{control: protocol.Ack, action: api.LinkWait, state: api.LinkReceivingText},
+		{text: true, action: api.LinkAccept, state: api.LinkAwaitingEot},
+		{text: true, action: api.LinkAckOnly, state: api.LinkAwaitingEot},
+		{control: protocol.Eot, action: api.LinkDone, state: api.LinkIdle},
+	}
+
+	for i, step := range steps {
+		var a api.LinkAction
+		var err error
+
+		if step.text {
+			a, err = l.Text()
+		} else {
+			a, err = l.Control(step.control)
+		}
+
+		if err != nil || a != step.action || l.State() != step.state {
+			t.Fatalf("step %d: %v %v in %v", i, a, err, l.State())
+		}
+	}
+
+	l.Request()
+	l.Control(protocol.Ack)
+
+	if _, err := l.Control(protocol.Eot); !errors.Is(err, api.ErrNoResponse) || l.State() != api.LinkIdle {
+		t.Fatalf("EOT without response: %v in %v", err, l.State())
+	}
+}
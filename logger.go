@@ -0,0 +1,61 @@
+package mm010_nrc_api
+
+import (
+	"fmt"
+	"time"
+)
+
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// WithLogger routes frame and error logging to l. It takes precedence over the
+// logging flag of the constructors.
+func WithLogger(l Logger) Option {
+	return func(s *MMDispenser) {
+		s.logger = l
+	}
+}
+
+// StdoutLogger is the logger used when logging is enabled without WithLogger.
+// It prints every line with a timestamp and the connection name.
+type StdoutLogger struct {
+	Name string
+}
+
+func (l StdoutLogger) Debugf(format string, args ...interface{}) {
+	l.printf("DEBUG", format, args...)
+}
+
+func (l StdoutLogger) Infof(format string, args ...interface{}) {
+	l.printf("INFO", format, args...)
+}
+
+func (l StdoutLogger) Errorf(format string, args ...interface{}) {
+	l.printf("ERROR", format, args...)
+}
+
+func (l StdoutLogger) printf(level string, format string, args ...interface{}) {
+	fmt.Printf("%s %s mm010_nrc[%v]: %s\n", time.Now().Format("2006-01-02 15:04:05.000"), level, l.Name,
+		fmt.Sprintf(format, args...))
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debugf(format string, args ...interface{}) {}
+func (nopLogger) Infof(format string, args ...interface{})  {}
+func (nopLogger) Errorf(format string, args ...interface{}) {}
+
+func (s *MMDispenser) log() Logger {
+	if s.logger != nil {
+		return s.logger
+	}
+
+	if s.logging {
+		return StdoutLogger{Name: s.name}
+	}
+
+	return nopLogger{}
+}
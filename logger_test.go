@@ -0,0 +1,39 @@
+package mm010_nrc_api_test
+
+import (
+	"context"
+	"fmt"
+	api "mm010_nrc_api"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *recordingLogger) add(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) Debugf(format string, args ...interface{}) { l.add(format, args...) }
+func (l *recordingLogger) Infof(format string, args ...interface{})  { l.add(format, args...) }
+func (l *recordingLogger) Errorf(format string, args ...interface{}) { l.add(format, args...) }
+
+func TestLoggerReceivesCommandNames(t *testing.T) {
+	logger := &recordingLogger{}
+	c := api.NewTransportConnection("log", newFakePort(answer(statusPayload)), api.WithTimeout(time.Second), api.WithLogger(logger))
+
+	if _, err := c.StatusContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(logger.lines) == 0 || !strings.HasPrefix(logger.lines[0], "-> Status ") {
+		t.Fatalf("unexpected log lines %q", logger.lines)
+	}
+}
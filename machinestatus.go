@@ -0,0 +1,45 @@
+package mm010_nrc_api
+
+import (
+	"context"
+	"fmt"
+)
+
+// MachineStatusInfo is the decoded MachineStatus data item. Its value starts with
+// a status code byte, encoded as in command responses, followed by optional
+// detail text.
+type MachineStatusInfo struct {
+	Code        StatusCode `json:"code"`
+	Description string     `json:"description"`
+	IsError     bool       `json:"is_error"`
+	IsFatal     bool       `json:"is_fatal"`
+	Detail      string     `json:"detail"`
+	Raw         string     `json:"raw"`
+}
+
+func (s *MMDispenser) MachineStatus(ctx context.Context) (MachineStatusInfo, error) {
+	v, err := s.ReadDataContext(ctx, MachineStatus, "")
+
+	if err != nil {
+		return MachineStatusInfo{}, err
+	}
+
+	return parseMachineStatus(v)
+}
+
+func parseMachineStatus(v string) (MachineStatusInfo, error) {
+	if len(v) == 0 {
+		return MachineStatusInfo{}, fmt.Errorf("data item %v: empty value: %w", MachineStatus, ErrInvalidValue)
+	}
+
+	code := StatusCode(v[0])
+
+	return MachineStatusInfo{
+		Code:        code,
+		Description: code.String(),
+		IsError:     code.IsError(),
+		IsFatal:     LookupStatus(code).Fatal,
+		Detail:      v[1:],
+		Raw:         v,
+	}, nil
+}
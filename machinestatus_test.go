@@ -0,0 +1,20 @@
+package mm010_nrc_api_test
+
+import (
+	"context"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/protocol"
+	"testing"
+)
+
+func TestMachineStatus(t *testing.T) {
+	sim, c := connect(t)
+
+	sim.SetData(api.MachineStatus, string(protocol.BlockedExit)+"E2")
+
+	ms, err := c.MachineStatus(context.Background())
+
+	if err != nil || ms.Code != api.BlockedExit || !ms.IsFatal || ms.Detail != "E2" {
+		t.Fatalf("unexpected machine status %+v %v", ms, err)
+	}
+}
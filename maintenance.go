@@ -0,0 +1,61 @@
+package mm010_nrc_api
+
+import (
+	"context"
+
+	"mm010_nrc_api/protocol"
+)
+
+// EnterMaintenanceMode makes commands that present notes to the customer,
+// Dispense and SingleNoteDispense and everything built on them, fail with
+// ErrMaintenanceMode, so no cash comes out while a technician works on the
+// device. Purge, TestDispense and the diagnostics keep working.
+// It waits for the command in flight, so once it returns no dispense is
+// running.
+func (s *MMDispenser) EnterMaintenanceMode(ctx context.Context) error {
+	if err := s.acquire(ctx); err != nil {
+		return err
+	}
+	defer s.release()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.maintenance {
+		s.log().Infof("maintenance mode entered")
+	}
+
+	s.maintenance = true
+
+	return nil
+}
+
+func (s *MMDispenser) ExitMaintenanceMode() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.maintenance {
+		s.log().Infof("maintenance mode left")
+	}
+
+	s.maintenance = false
+}
+
+func (s *MMDispenser) InMaintenanceMode() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.maintenance
+}
+
+// checkMaintenance is the interlock in front of every exchange.
+func (s *MMDispenser) checkMaintenance(command Command) error {
+	switch command {
+	case protocol.CommandDispense, protocol.CommandSingleNoteDispense:
+		if s.InMaintenanceMode() {
+			return ErrMaintenanceMode
+		}
+	}
+
+	return nil
+}
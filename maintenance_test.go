@@ -0,0 +1,30 @@
+package mm010_nrc_api_test
+
+import (
+	"context"
+	"errors"
+	api "mm010_nrc_api"
+	"testing"
+)
+
+func TestMaintenanceMode(t *testing.T) {
+	sim, c := connect(t)
+
+	if err := c.EnterMaintenanceMode(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.DispenseContext(context.Background(), 1); !errors.Is(err, api.ErrMaintenanceMode) {
+		t.Fatalf("expected ErrMaintenanceMode, got %v", err)
+	}
+
+	if _, _, err := c.PurgeContext(context.Background()); err != nil {
+		t.Fatalf("purge in maintenance mode: %v", err)
+	}
+
+	c.ExitMaintenanceMode()
+
+	if res, err := c.DispenseContext(context.Background(), 1); err != nil || res.NotesDispensed != 1 || sim.Notes() != 999 {
+		t.Fatalf("dispense after maintenance: %+v, %v", res, err)
+	}
+}
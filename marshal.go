@@ -0,0 +1,14 @@
+package mm010_nrc_api
+
+import "fmt"
+
+// parseEnum finds the value in 0..last whose name is text.
+func parseEnum(text []byte, what string, last int, name func(int) string) (int, error) {
+	for i := 0; i <= last; i++ {
+		if name(i) == string(text) {
+			return i, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown %s %q", what, text)
+}
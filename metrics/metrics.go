@@ -0,0 +1,181 @@
+// Package metrics exports dispenser command statistics as Prometheus metrics.
+//
+//	collector := metrics.NewCollector("atm1")
+//	prometheus.MustRegister(collector)
+//	c, err := mm010_nrc_api.NewConnection("/dev/ttyUSB0", mm010_nrc_api.WithObserver(collector.Observe))
+package metrics
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/protocol"
+)
+
+const namespace = "mm010"
+
+var (
+	dispenseAttemptsDesc = prometheus.NewDesc(namespace+"_dispense_attempts_total",
+		"Note moving commands sent to the dispenser.", []string{"device", "status"}, nil)
+	notesDispensedDesc = prometheus.NewDesc(namespace+"_notes_dispensed_total",
+		"Notes reported dispensed by the device.", []string{"device"}, nil)
+	notesRejectedDesc = prometheus.NewDesc(namespace+"_notes_rejected_total",
+		"Notes reported rejected by the device.", []string{"device"}, nil)
+	naksDesc = prometheus.NewDesc(namespace+"_naks_total",
+		"NAK responses received from the device.", []string{"device"}, nil)
+	checksumFailuresDesc = prometheus.NewDesc(namespace+"_checksum_failures_total",
+		"Responses dropped because of a block check mismatch.", []string{"device"}, nil)
+	commandErrorsDesc = prometheus.NewDesc(namespace+"_command_errors_total",
+		"Command exchanges that ended in an error.", []string{"device", "command"}, nil)
+	latencyDesc = prometheus.NewDesc(namespace+"_command_duration_seconds",
+		"Duration of command exchanges including retries.", []string{"device", "command"}, nil)
+	clientCommandsDesc = prometheus.NewDesc(namespace+"_client_commands_total",
+		"Command exchanges per client, see mm010_nrc_api.ContextWithClient.", []string{"device", "client", "command"}, nil)
+	clientNotesDispensedDesc = prometheus.NewDesc(namespace+"_client_notes_dispensed_total",
+		"Notes reported dispensed per client.", []string{"device", "client"}, nil)
+)
+
+type clientCommand struct {
+	client  string
+	command protocol.Command
+}
+
+type histogram struct {
+	count   uint64
+	sum     float64
+	buckets map[float64]uint64
+}
+
+// Collector implements prometheus.Collector for one dispenser. Feed it by
+// passing Observe to mm010_nrc_api.WithObserver.
+type Collector struct {
+	device  string
+	buckets []float64
+
+	mu               sync.Mutex
+	dispenseAttempts map[protocol.StatusCode]uint64
+	notesDispensed   uint64
+	notesRejected    uint64
+	naks             uint64
+	checksumFailures uint64
+	commandErrors    map[protocol.Command]uint64
+	latency          map[protocol.Command]*histogram
+	// clientCommands and clientNotes only count commands with a client
+	clientCommands map[clientCommand]uint64
+	clientNotes    map[string]uint64
+}
+
+func NewCollector(device string) *Collector {
+	return &Collector{
+		device:           device,
+		buckets:          prometheus.DefBuckets,
+		dispenseAttempts: map[protocol.StatusCode]uint64{},
+		commandErrors:    map[protocol.Command]uint64{},
+		latency:          map[protocol.Command]*histogram{},
+		clientCommands:   map[clientCommand]uint64{},
+		clientNotes:      map[string]uint64{},
+	}
+}
+
+func (c *Collector) Observe(e api.CommandEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.naks += uint64(e.Retries)
+
+	if errors.Is(e.Err, api.ErrNack) {
+		c.naks++
+	}
+
+	if errors.Is(e.Err, api.ErrChecksumMismatch) {
+		c.checksumFailures++
+	}
+
+	if e.Err != nil {
+		c.commandErrors[e.Command]++
+	}
+
+	if e.Result != nil {
+		c.dispenseAttempts[e.Result.Status]++
+		c.notesDispensed += uint64(e.Result.NotesDispensed)
+		c.notesRejected += uint64(e.Result.NotesRejected)
+	}
+
+	if e.Client != "" {
+		c.clientCommands[clientCommand{e.Client, e.Command}]++
+
+		if e.Result != nil {
+			c.clientNotes[e.Client] += uint64(e.Result.NotesDispensed)
+		}
+	}
+
+	h, ok := c.latency[e.Command]
+
+	if !ok {
+		h = &histogram{buckets: map[float64]uint64{}}
+		c.latency[e.Command] = h
+	}
+
+	seconds := e.Duration.Seconds()
+	h.count++
+	h.sum += seconds
+
+	for _, b := range c.buckets {
+		if seconds <= b {
+			h.buckets[b]++
+		}
+	}
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- dispenseAttemptsDesc
+	ch <- notesDispensedDesc
+	ch <- notesRejectedDesc
+	ch <- naksDesc
+	ch <- checksumFailuresDesc
+	ch <- commandErrorsDesc
+	ch <- latencyDesc
+	ch <- clientCommandsDesc
+	ch <- clientNotesDispensedDesc
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for status, n := range c.dispenseAttempts {
+		ch <- prometheus.MustNewConstMetric(dispenseAttemptsDesc, prometheus.CounterValue, float64(n), c.device, status.String())
+	}
+
+	ch <- prometheus.MustNewConstMetric(notesDispensedDesc, prometheus.CounterValue, float64(c.notesDispensed), c.device)
+	ch <- prometheus.MustNewConstMetric(notesRejectedDesc, prometheus.CounterValue, float64(c.notesRejected), c.device)
+	ch <- prometheus.MustNewConstMetric(naksDesc, prometheus.CounterValue, float64(c.naks), c.device)
+	ch <- prometheus.MustNewConstMetric(checksumFailuresDesc, prometheus.CounterValue, float64(c.checksumFailures), c.device)
+
+	for command, n := range c.commandErrors {
+		ch <- prometheus.MustNewConstMetric(commandErrorsDesc, prometheus.CounterValue, float64(n), c.device, command.String())
+	}
+
+	for command, h := range c.latency {
+		buckets := make(map[float64]uint64, len(h.buckets))
+
+		for b, n := range h.buckets {
+			buckets[b] = n
+		}
+
+		ch <- prometheus.MustNewConstHistogram(latencyDesc, h.count, h.sum, buckets, c.device, command.String())
+	}
+
+	for cc, n := range c.clientCommands {
+		ch <- prometheus.MustNewConstMetric(clientCommandsDesc, prometheus.CounterValue, float64(n), c.device, cc.client, cc.command.String())
+	}
+
+	for client, n := range c.clientNotes {
+		ch <- prometheus.MustNewConstMetric(clientNotesDispensedDesc, prometheus.CounterValue, float64(n), c.device, client)
+	}
+}
+
+// compile time check that the collector satisfies the Prometheus interface
+var _ prometheus.Collector = (*Collector)(nil)
@@ -2,20 +2,22 @@ package mm010_nrc_api
 
 import (
 	"bytes"
-	"encoding/binary"
-	"errors"
+	"context"
 	"fmt"
+	"io"
+	"sync"
 	"time"
 
 	"github.com/tarm/serial"
+	"mm010_nrc_api/protocol"
 )
 
 const (
-	RequestStart          byte = 0x04
-	ResponseStart         byte = 0x01
-	CommunicationIdentify byte = 0x30
-	TextStart             byte = 0x02
-	TextEnd               byte = 0x03
+	RequestStart          = protocol.RequestStart
+	ResponseStart         = protocol.ResponseStart
+	CommunicationIdentify = protocol.CommunicationIdentify
+	TextStart             = protocol.TextStart
+	TextEnd               = protocol.TextEnd
 )
 
 type Baud int
@@ -27,605 +29,728 @@ const (
 	Baud9600 Baud = 9600
 )
 
-type ResponseType byte
+type ResponseType = protocol.ResponseType
 
 const (
-	ErrorResponse ResponseType = 0x00
-	AckResponse   ResponseType = 0x06
-	NackResponse  ResponseType = 0x15
-	EotResponse   ResponseType = 0x04
+	ErrorResponse = protocol.ErrorResponse
+	AckResponse   = protocol.AckResponse
+	NackResponse  = protocol.NackResponse
+	EotResponse   = protocol.EotResponse
 )
 
-type StatusCode byte
+type StatusCode = protocol.StatusCode
 
 const (
-	GoodOperation        StatusCode = 0x20
-	FeedFailure          StatusCode = 0x21
-	MistrackedNoteAtExit StatusCode = 0x24
-	TooLongAtExit        StatusCode = 0x25
-	BlockedExit          StatusCode = 0x26
-	TransportError       StatusCode = 0x2A
-	DoubleDetectError    StatusCode = 0x2C
-	DivertedError        StatusCode = 0x2D
-	WrongCount           StatusCode = 0x2E
-	NoteMissingAtDD      StatusCode = 0x2F
-	RejectRateExceeded   StatusCode = 0x30
-	NonVolatileRAMError  StatusCode = 0x34
-	OperationTimeout     StatusCode = 0x36
-	InternalQueError     StatusCode = 0x37
-	InvalidCommand       StatusCode = 0x4F
+	GoodOperation        = protocol.GoodOperation
+	FeedFailure          = protocol.FeedFailure
+	MistrackedNoteAtExit = protocol.MistrackedNoteAtExit
+	TooLongAtExit        = protocol.TooLongAtExit
+	BlockedExit          = protocol.BlockedExit
+	TransportError       = protocol.TransportError
+	DoubleDetectError    = protocol.DoubleDetectError
+	DivertedError        = protocol.DivertedError
+	WrongCount           = protocol.WrongCount
+	NoteMissingAtDD      = protocol.NoteMissingAtDD
+	RejectRateExceeded   = protocol.RejectRateExceeded
+	NonVolatileRAMError  = protocol.NonVolatileRAMError
+	OperationTimeout     = protocol.OperationTimeout
+	InternalQueError     = protocol.InternalQueError
+	InvalidCommand       = protocol.InvalidCommand
 )
 
-type DataItem uint16
+type RejectReason = protocol.RejectReason
 
 const (
-	ProgramID                        DataItem = 100
-	MachineID                        DataItem = 101
-	MaxNumberOfNotesInOneTransaction DataItem = 104
-	Baudrate                         DataItem = 115
-	Parity                           DataItem = 116
-	DispenseCounterLifelong          DataItem = 303
-	RejectCounterLifelong            DataItem = 304
-	TotalProcessedCounterLifelong    DataItem = 305
-	DispenseCounterTrip              DataItem = 306
-	RejectCounterTrip                DataItem = 307
-	TotalProcessedCcounterTrip       DataItem = 308
-	TransactionCounterLifelong       DataItem = 313
-	TransactionCounterTrip           DataItem = 314
-	ThroatSensorCalibrationValue     DataItem = 350
-	LearningNotes                    DataItem = 392
-	RejectReasonCounter              DataItem = 501
-	ErrorStatusCounter               DataItem = 502
-	MachineStatus                    DataItem = 503
+	RejectMistracked      = protocol.RejectMistracked
+	RejectTooLong         = protocol.RejectTooLong
+	RejectDoubleDetect    = protocol.RejectDoubleDetect
+	RejectDiverted        = protocol.RejectDiverted
+	RejectWrongCount      = protocol.RejectWrongCount
+	RejectNoteMissingAtDD = protocol.RejectNoteMissingAtDD
 )
 
-type MMDispenser struct {
-	config  *serial.Config
-	port    *serial.Port
-	logging bool
-	open    bool
-	timeout time.Duration
-}
+type DataItem = protocol.DataItem
 
-type Status struct {
-	FeedSensorBlocked           bool
-	ExitSensorBlocked           bool
-	ResetSinceLastStatusMessage bool
-	TimingWheelSensorBlocked    bool
-	CalibratingDoubleDetect     bool
-	AverageThickness            byte
-	AverageLength               byte
-}
+const (
+	ProgramID                        = protocol.ProgramID
+	MachineID                        = protocol.MachineID
+	MaxNumberOfNotesInOneTransaction = protocol.MaxNumberOfNotesInOneTransaction
+	Baudrate                         = protocol.Baudrate
+	Parity                           = protocol.Parity
+	DispenseCounterLifelong          = protocol.DispenseCounterLifelong
+	RejectCounterLifelong            = protocol.RejectCounterLifelong
+	TotalProcessedCounterLifelong    = protocol.TotalProcessedCounterLifelong
+	DispenseCounterTrip              = protocol.DispenseCounterTrip
+	RejectCounterTrip                = protocol.RejectCounterTrip
+	TotalProcessedCcounterTrip       = protocol.TotalProcessedCcounterTrip
+	TransactionCounterLifelong       = protocol.TransactionCounterLifelong
+	TransactionCounterTrip           = protocol.TransactionCounterTrip
+	ThroatSensorCalibrationValue     = protocol.ThroatSensorCalibrationValue
+	LearningNotes                    = protocol.LearningNotes
+	RejectReasonCounter              = protocol.RejectReasonCounter
+	ErrorStatusCounter               = protocol.ErrorStatusCounter
+	MachineStatus                    = protocol.MachineStatus
+)
 
-type response struct {
-	data ResponseType
-	err  error
+type Transport interface {
+	io.ReadWriteCloser
 }
 
-type responseData struct {
-	data []byte
-	err  error
+// MMDispenser is safe for concurrent use, including Close and the Set methods.
+// Each command exchange holds the link exclusively, so frames of concurrent
+// callers never interleave on the wire; waiting callers are served one at a
+// time in no particular order. Helpers made of several commands (e.g. the
+// sensor check before Dispense) are not atomic as a whole. Close does not wait
+// and aborts an exchange in flight; Shutdown waits for it. Options must not
+// be applied to a connection in use.
+type MMDispenser struct {
+	name     string
+	config   *serial.Config
+	backend  SerialBackend
+	portLock bool
+	port     Transport
+	logging  bool
+	logger   Logger
+	// redactors mask what is logged of request, the text of the request in
+	// flight
+	redactors []Redactor
+	request   []byte
+	open      bool
+	timeout   time.Duration
+
+	identify      byte
+	checksum      ChecksumFunc
+	countEncoding CountEncoding
+
+	frameFormat FrameFormat
+	// extended is the format requests are sent in, settled for AutoFrames
+	// once negotiated
+	extended   bool
+	negotiated bool
+
+	lock chan struct{}
+	// idempotent serializes DispenseIdempotent
+	idempotent chan struct{}
+
+	suppressEcho bool
+	ackHandler   AckFunc
+	echo         []byte
+	// rx holds bytes read past the last unit of a response
+	rx   []byte
+	link Link
+
+	// blockedSensorPolicy is guarded by mu, it has a setter
+	blockedSensorPolicy BlockedSensorPolicy
+	retry               RetryPolicy
+	pollInterval        time.Duration
+	observers           []func(CommandEvent)
+	interceptors        []Interceptor
+	beforeSend          []BeforeSendHook
+	afterReceive        []AfterReceiveHook
+	commandTimeouts     map[Command]time.Duration
+	classTimeouts       map[CommandClass]time.Duration
+	interByteTimeout    time.Duration
+	reconnect           *ReconnectPolicy
+	stateHandlers       []func(ConnectionState)
+	state               ConnectionState
+	lost                bool
+	bus                 *Bus
+	diagnosticsLimits   *DiagnosticsLimits
+	trace               *TraceRecorder
+	traffic             *trafficRing
+	auditLogger         AuditLogger
+	auditSeq            uint64
+	auditTime           TimeSource
+	rateLimit           *RateLimit
+	recovery            *RecoveryPolicy
+	rejectRateWarning   float64
+	rejectCap           int
+	noteMetrics         *NoteMetrics
+	shutdownPolicy      ShutdownPolicy
+	// cycles are the note moving commands of the last minute
+	cycles    []noteCycle
+	lastCycle time.Time
+
+	// mu guards the state below, which is shared with Watch, and open, lost
+	// and port, which Close changes without the link
+	mu                 sync.Mutex
+	rejectRateExceeded uint64
+	rejectCapExceeded  uint64
+	// rejectSession is the baseline of RejectAnalysis, begun when
+	// rejectRateExceeded was sessionRejectRateExceeded
+	rejectSession             *rejectSession
+	sessionRejectRateExceeded uint64
+	dispenseStore             DispenseStore
+	portReader                *portReader
+	abort                     context.CancelFunc
+	stats                     Stats
+	maintenance               bool
+	latency                   time.Duration
+	aborted                   bool
 }
 
-func NewConnection(path string, baud Baud, logging bool, timeout time.Duration) (MMDispenser, error) {
-	if timeout == 0 {
-		timeout = 3 * time.Second
+type Status struct {
+	FeedSensorBlocked           bool `json:"feed_sensor_blocked"`
+	ExitSensorBlocked           bool `json:"exit_sensor_blocked"`
+	ResetSinceLastStatusMessage bool `json:"reset_since_last_status"`
+	TimingWheelSensorBlocked    bool `json:"timing_wheel_sensor_blocked"`
+	CalibratingDoubleDetect     bool `json:"calibrating_double_detect"`
+	AverageThickness            byte `json:"average_thickness"`
+	AverageLength               byte `json:"average_length"`
+	// Sensors and Flags are the two status bytes as received, with the bits
+	// that have no field above.
+	Sensors byte `json:"sensors"`
+	Flags   byte `json:"flags"`
+}
+
+// NewConnection opens the serial port at path, normalized by
+// NormalizePortPath. Without options the port runs at 9600 baud, 7 data bits,
+// even parity and one stop bit with a 3 second read timeout and talks to
+// communication identify 0x30. A port that fails to open is reported as a
+// PortOpenError.
+func NewConnection(path string, opts ...Option) (*MMDispenser, error) {
+	path, err := NormalizePortPath(path)
+
+	if err != nil {
+		return nil, err
 	}
 
-	c := &serial.Config{Name: path, Baud: int(baud), ReadTimeout: timeout, Parity: serial.ParityEven, StopBits: serial.Stop1,
+	res := newDispenser(path, nil)
+	res.config = &serial.Config{Name: path, Baud: int(Baud9600), Parity: serial.ParityEven, StopBits: serial.Stop1,
 		Size: 7}
 
-	o, err := serial.OpenPort(c)
+	for _, opt := range opts {
+		opt(res)
+	}
+
+	res.config.ReadTimeout = res.timeout
 
-	res := MMDispenser{}
+	o, err := res.openPort()
 
 	if err != nil {
-		return res, err
+		return nil, res.portOpenError(err)
 	}
 
-	res.config = c
 	res.port = o
-	res.logging = logging
 	res.open = true
-	res.timeout = timeout
 
 	return res, nil
 }
 
+// NewTransportConnection runs the protocol over an already opened transport,
+// e.g. a TCP serial device server or an in-memory pipe. name is only used for logging.
+func NewTransportConnection(name string, t Transport, opts ...Option) *MMDispenser {
+	res := newDispenser(name, t)
+	res.open = true
+
+	for _, opt := range opts {
+		opt(res)
+	}
+
+	return res
+}
+
+func newDispenser(name string, t Transport) *MMDispenser {
+	return &MMDispenser{
+		name:       name,
+		port:       t,
+		timeout:    3 * time.Second,
+		identify:   CommunicationIdentify,
+		checksum:   protocol.LRC,
+		lock:       make(chan struct{}, 1),
+		idempotent: make(chan struct{}, 1),
+		retry:      DefaultRetryPolicy,
+		link:       Link{guard: DefaultGuardTime},
+	}
+}
+
+// Open reopens a closed connection. It waits for an exchange aborted by
+// Close to give up the link first.
 func (s *MMDispenser) Open() error {
-	if s.open {
-		return errors.New("port already opened")
+	if s.isOpen() {
+		return ErrPortAlreadyOpen
+	}
+
+	if err := s.acquire(context.Background()); err != nil {
+		return err
+	}
+	defer s.release()
+
+	if s.bus != nil {
+		s.setPort(s.port, true, false)
+		return nil
 	}
 
-	p, err := serial.OpenPort(s.config)
+	if s.config == nil {
+		return ErrReopenNotSupported
+	}
+
+	p, err := s.openPort()
 
 	if err != nil {
 		return err
 	}
 
-	s.port = p
-	s.open = true
+	s.setPort(p, true, false)
+	s.echo = nil
+	s.setState(Connected)
 
 	return nil
 }
 
 func (s *MMDispenser) Close() error {
-	if s.port == nil || !s.open {
-		return errors.New("port not opened")
-	}
-
-	err := s.port.Close()
+	s.mu.Lock()
+	port, open := s.port, s.open
 	s.open = false
+	// a closed connection stays closed, even if auto reconnect is pending
+	s.lost = false
+	s.mu.Unlock()
 
-	return err
-}
-
-func (s *MMDispenser) Status() (Status, error) {
-	status := Status{}
-	err := sendRequest(s, 0x40, []byte{})
-
-	if err != nil {
-		return status, err
+	if port == nil || !open {
+		return ErrPortClosed
 	}
 
-	response, err := readResponse(s)
-
-	if err != nil {
-		return status, err
+	if s.bus != nil {
+		return nil
 	}
 
-	status.FeedSensorBlocked = (response[0] & (1 << 0)) != 0
-	status.ExitSensorBlocked = (response[0] & (1 << 1)) != 0
-	status.ResetSinceLastStatusMessage = (response[0] & (1 << 3)) != 0
-	status.TimingWheelSensorBlocked = (response[0] & (1 << 4)) != 0
-	status.CalibratingDoubleDetect = (response[1] & (1 << 4)) != 0
-	status.AverageThickness = response[2] - 0x20
-	status.AverageLength = response[3] - 0x20
+	err := port.Close()
+	s.stopReader()
 
-	return status, err
+	return err
 }
 
-func (s *MMDispenser) Purge() (StatusCode, byte, error) {
-	err := sendRequest(s, 0x41, []byte{})
+// isOpen reports whether the connection is open. Close changes it without
+// the link, so it is read under mu.
+func (s *MMDispenser) isOpen() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	if err != nil {
-		return 0, 0, err
-	}
+	return s.open
+}
 
-	response, err := readResponse(s)
+func (s *MMDispenser) isLost() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	if err != nil {
-		return 0, 0, err
-	}
+	return s.lost
+}
 
-	return StatusCode(response[0]), response[1] - 0x20, nil
+// setPort replaces the port and its state. The caller holds the link, so an
+// exchange may read the port holding either the link or mu.
+func (s *MMDispenser) setPort(p Transport, open, lost bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.port, s.open, s.lost = p, open, lost
 }
 
-func (s *MMDispenser) Dispense(count byte) (StatusCode, byte, byte, error) {
-	err := sendRequest(s, 0x42, []byte{count + 0x20})
+func (s *MMDispenser) StatusContext(ctx context.Context) (Status, error) {
+	status := Status{}
+	v, err := s.decodedCommand(ctx, protocol.CommandStatus)
 
 	if err != nil {
-		return 0, 0, 0, err
+		return status, err
 	}
 
-	response, err := readResponse(s)
+	response := v.(protocol.StatusResponse)
 
-	if err != nil {
-		return 0, 0, 0, err
-	}
+	status.Sensors = response.Sensors
+	status.Flags = response.Flags
+	status.FeedSensorBlocked = (response.Sensors & (1 << 0)) != 0
+	status.ExitSensorBlocked = (response.Sensors & (1 << 1)) != 0
+	status.ResetSinceLastStatusMessage = (response.Sensors & (1 << 3)) != 0
+	status.TimingWheelSensorBlocked = (response.Sensors & (1 << 4)) != 0
+	status.CalibratingDoubleDetect = (response.Flags & (1 << 4)) != 0
+	status.AverageThickness = response.AverageThickness
+	status.AverageLength = response.AverageLength
 
-	return StatusCode(response[0]), response[1] - 0x20, response[2] - 0x20, nil
+	return status, err
 }
 
-func (s *MMDispenser) TestDispense(count byte) (StatusCode, byte, byte, error) {
-	err := sendRequest(s, 0x43, []byte{count + 0x20})
+func (s *MMDispenser) PurgeContext(ctx context.Context) (StatusCode, byte, error) {
+	v, err := s.decodedCommand(ctx, protocol.CommandPurge)
 
 	if err != nil {
-		return 0, 0, 0, err
+		return 0, 0, err
 	}
 
-	response, err := readResponse(s)
+	response := v.(protocol.PurgeResponse)
 
-	if err != nil {
-		return 0, 0, 0, err
-	}
+	return response.Status, response.Purged, nil
+}
 
-	return StatusCode(response[0]), response[1] - 0x20, response[2] - 0x20, nil
+func (s *MMDispenser) DispenseContext(ctx context.Context, count byte) (DispenseResult, error) {
+	return s.dispense(ctx, protocol.CommandDispense, count)
 }
 
-func (s *MMDispenser) Reset() error {
-	err := sendRequest(s, 0x44, []byte{})
+func (s *MMDispenser) TestDispenseContext(ctx context.Context, count byte) (DispenseResult, error) {
+	return s.dispense(ctx, protocol.CommandTestDispense, count)
+}
 
-	if err != nil {
+func (s *MMDispenser) ResetContext(ctx context.Context) error {
+	if err := s.acquire(ctx); err != nil {
 		return err
 	}
+	defer s.release()
+
+	_, err := s.intercept(ctx, protocol.CommandReset, nil, s.reset)
 
-	_, err = readRespCodeWithTimeout(s)
 	return err
 }
 
-func (s *MMDispenser) LastStatus() (StatusCode, byte, byte, error) {
-	err := sendRequest(s, 0x45, []byte{})
+// reset runs the Reset exchange, which is answered by a bare ACK.
+func (s *MMDispenser) reset(ctx context.Context, command Command, data []byte) ([]byte, error) {
+	started := time.Now()
+	stats := s.Stats()
+	err := s.ensureLink(ctx)
 
-	if err != nil {
-		return 0, 0, 0, err
+	if err == nil {
+		err = sendRequest(ctx, s, protocol.CommandReset)
 	}
 
-	response, err := readResponse(s)
-
-	if err != nil {
-		return 0, 0, 0, err
+	if err == nil {
+		err = readAck(ctx, s, s.deadline(protocol.CommandReset))
 	}
 
-	return StatusCode(response[0]), response[1] - 0x20, response[2] - 0x20, nil
-}
-
-func (s *MMDispenser) ConfigurationStatus() (byte, byte, error) {
-	err := sendRequest(s, 0x46, []byte{})
-
-	if err != nil {
-		return 0, 0, err
-	}
+	s.linkFailed(err)
+	s.recordExchange(ctx, stats, 0)
 
-	response, err := readResponse(s)
+	s.observe(ctx, protocol.CommandReset, started, 0, nil, err)
+	s.audit(ctx, protocol.CommandReset, started, nil, nil, err)
 
-	if err != nil {
-		return 0, 0, err
-	}
+	return nil, err
+}
 
-	return response[0] - 0x20, response[1] - 0x20, nil
+func (s *MMDispenser) LastStatusContext(ctx context.Context) (DispenseResult, error) {
+	return s.dispenseCommand(ctx, protocol.CommandLastStatus)
 }
 
-func (s *MMDispenser) DoubleDetectDiagnostics() (StatusCode, byte, byte, error) {
-	err := sendRequest(s, 0x47, []byte{})
+func (s *MMDispenser) ConfigurationStatusContext(ctx context.Context) (byte, byte, error) {
+	v, err := s.decodedCommand(ctx, protocol.CommandConfigurationStatus)
 
 	if err != nil {
-		return 0, 0, 0, err
+		return 0, 0, err
 	}
 
-	response, err := readResponse(s)
-
-	if err != nil {
-		return 0, 0, 0, err
-	}
+	response := v.(protocol.ConfigurationResponse)
 
-	return StatusCode(response[0]), response[1] - 0x20, response[2] - 0x20, nil
+	return response.Configuration1, response.Configuration2, nil
 }
 
-func (s *MMDispenser) SensorDiagnostics() (StatusCode, byte, byte, error) {
-	err := sendRequest(s, 0x48, []byte{})
-
-	if err != nil {
-		return 0, 0, 0, err
-	}
-
-	response, err := readResponse(s)
-
-	if err != nil {
-		return 0, 0, 0, err
-	}
+func (s *MMDispenser) DoubleDetectDiagnosticsContext(ctx context.Context) (StatusCode, byte, byte, error) {
+	return s.statusCommand(ctx, protocol.CommandDoubleDetectDiagnostics)
+}
 
-	return StatusCode(response[0]), response[1] - 0x20, response[2] - 0x20, nil
+func (s *MMDispenser) SensorDiagnosticsContext(ctx context.Context) (StatusCode, byte, byte, error) {
+	return s.statusCommand(ctx, protocol.CommandSensorDiagnostics)
 }
 
-func (s *MMDispenser) SingleNoteDispense() (StatusCode, byte, byte, error) {
-	err := sendRequest(s, 0x4A, []byte{})
+func (s *MMDispenser) SingleNoteDispenseContext(ctx context.Context) (StatusCode, byte, byte, error) {
+	return s.statusCommand(ctx, protocol.CommandSingleNoteDispense)
+}
 
-	if err != nil {
-		return 0, 0, 0, err
-	}
+func (s *MMDispenser) SingleNoteEjectContext(ctx context.Context) (StatusCode, byte, byte, error) {
+	return s.statusCommand(ctx, protocol.CommandSingleNoteEject)
+}
 
-	response, err := readResponse(s)
+func (s *MMDispenser) TestModeContext(ctx context.Context) (StatusCode, error) {
+	v, err := s.decodedCommand(ctx, protocol.CommandTestMode)
 
 	if err != nil {
-		return 0, 0, 0, err
+		return 0, err
 	}
 
-	return StatusCode(response[0]), response[1] - 0x20, response[2] - 0x20, nil
+	return v.(protocol.TestModeResponse).Status, nil
 }
 
-func (s *MMDispenser) SingleNoteEject() (StatusCode, byte, byte, error) {
-	err := sendRequest(s, 0x4B, []byte{})
-
-	if err != nil {
-		return 0, 0, 0, err
+func (s *MMDispenser) ReadDataContext(ctx context.Context, item DataItem, param string) (string, error) {
+	if err := s.acquire(ctx); err != nil {
+		return "", err
 	}
+	defer s.release()
 
-	response, err := readResponse(s)
+	return s.readData(ctx, item, param)
+}
 
-	if err != nil {
-		return 0, 0, 0, err
+// ReadDataBatch reads several data items while holding the link, so no other
+// command gets in between. The protocol has neither a multi-item read nor
+// pipelining, so every item still costs a full exchange. On error the items
+// read so far are returned along with it.
+func (s *MMDispenser) ReadDataBatch(ctx context.Context, items []DataItem) (map[DataItem]string, error) {
+	if err := s.acquire(ctx); err != nil {
+		return nil, err
 	}
+	defer s.release()
 
-	return StatusCode(response[0]), response[1] - 0x20, response[2] - 0x20, nil
-}
-
-func (s *MMDispenser) TestMode() (StatusCode, error) {
-	err := sendRequest(s, 0x54, []byte{})
+	res := make(map[DataItem]string, len(items))
 
-	if err != nil {
-		return 0, err
-	}
+	for _, item := range items {
+		v, err := s.readData(ctx, item, "")
 
-	response, err := readResponse(s)
+		if err != nil {
+			return res, fmt.Errorf("data item %v: %w", item, err)
+		}
 
-	if err != nil {
-		return 0, err
+		res[item] = v
 	}
 
-	return StatusCode(response[0]), nil
+	return res, nil
 }
 
-func (s *MMDispenser) ReadData(item DataItem, param string) (string, error) {
+func (s *MMDispenser) readData(ctx context.Context, item DataItem, param string) (string, error) {
 	str := fmt.Sprintf("D/%3d", item)
 
 	if len(param) > 0 {
 		str += fmt.Sprintf("/%s", param)
 	}
 
-	sendRequest(s, 0x52, []byte(str))
+	response, err := s.commandLocked(ctx, protocol.CommandReadData, []byte(str))
+
+	if err != nil {
+		return "", err
+	}
 
-	response, err := readResponse(s)
+	v, err := s.decodeText(protocol.CommandReadData, response)
 
 	if err != nil {
 		return "", err
 	}
 
-	if response[0] != 0x30 {
-		return "", errors.New("illegal command")
+	r := v.(protocol.DataResponse)
+
+	if !r.OK() {
+		return "", ErrIllegalCommand
 	}
 
-	return string(response[1:]), nil
+	return r.Value, nil
 }
 
-func (s *MMDispenser) WriteData(item DataItem, data string) error {
-	err := sendRequest(s, 0x57, []byte(fmt.Sprintf("D/%3d/%s", item, data)))
-
-	if err != nil {
+func (s *MMDispenser) WriteDataContext(ctx context.Context, item DataItem, data string) error {
+	if err := validateWrite(item, data); err != nil {
 		return err
 	}
 
-	response, err := readResponse(s)
+	v, err := s.decodedCommand(ctx, protocol.CommandWriteData, []byte(fmt.Sprintf("D/%3d/%s", item, data)))
 
 	if err != nil {
 		return err
 	}
 
-	if response[0] != 0x30 {
-		return errors.New("illegal command")
+	if !v.(protocol.DataResponse).OK() {
+		return ErrIllegalCommand
 	}
 
 	return nil
 }
 
-func (s *MMDispenser) Ack() {
-	_, _ = s.port.Write([]byte{0x06})
-}
+// command runs one request/response exchange and returns the response text.
+func (s *MMDispenser) command(ctx context.Context, command protocol.Command, data ...[]byte) ([]byte, error) {
+	if err := s.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer s.release()
 
-func (s *MMDispenser) Nack() {
-	_, _ = s.port.Write([]byte{0x15})
+	return s.commandLocked(ctx, command, data...)
 }
 
-func timeout(timeout time.Duration, r chan response) {
-	time.Sleep(timeout)
-
-	r <- response{err: errors.New("timeout")}
+// commandLocked is command for callers that already hold the link.
+func (s *MMDispenser) commandLocked(ctx context.Context, command protocol.Command, data ...[]byte) ([]byte, error) {
+	return s.intercept(ctx, command, bytes.Join(data, nil), func(ctx context.Context, command Command, data []byte) ([]byte, error) {
+		return s.run(ctx, command, data)
+	})
 }
 
-func timeoutData(timeout time.Duration, r chan responseData) {
-	time.Sleep(timeout)
-
-	r <- responseData{err: errors.New("timeout")}
-}
+// run is the exchange of commandLocked behind the interceptors.
+func (s *MMDispenser) run(ctx context.Context, command protocol.Command, data ...[]byte) ([]byte, error) {
+	started := time.Now()
 
-func readResponse(v *MMDispenser) ([]byte, error) {
-	resp, err := readRespCodeWithTimeout(v)
+	err := s.checkMaintenance(command)
 
-	if err != nil {
-		return nil, err
+	if err == nil {
+		err = s.checkRateLimit(command, bytes.Join(data, nil))
 	}
 
-	if resp != AckResponse {
-		return nil, errors.New("Response not ACK")
+	if err == nil {
+		err = s.ensureLink(ctx)
 	}
 
-	data, err := readRespDataWithTimeout(v)
+	if err == nil {
+		err = s.negotiateFrameFormat(ctx)
+	}
 
 	if err != nil {
+		s.observe(ctx, command, started, 0, nil, err)
+		s.audit(ctx, command, started, data, nil, err)
 		return nil, err
 	}
 
-	v.Ack()
+	stats := s.Stats()
+	exchangeCtx, finish := s.abortable(ctx)
+	response, retries, err := s.exchange(exchangeCtx, command, data...)
 
-	resp, err = readRespCodeWithTimeout(v)
+	// an exchange that completed before Abort got to it stands
+	if finish() && err != nil {
+		s.resync()
+		response, err = nil, ErrAborted
+	}
 
-	if err != nil {
-		return nil, err
+	if s.linkFailed(err) && !movesNotes(command) && s.ensureLink(ctx) == nil {
+		response, retries, err = s.exchange(ctx, command, data...)
+		s.linkFailed(err)
 	}
 
-	if resp != EotResponse {
-		return nil, errors.New("Response not EOT")
+	if err == nil {
+		err = s.checkResponseLength(command, response)
 	}
 
-	time.Sleep(time.Millisecond * 200)
+	s.recordCycle(command, response)
+	s.recordExchange(ctx, stats, retries)
+	s.observe(ctx, command, started, retries, response, err)
+	s.audit(ctx, command, started, data, response, err)
 
-	return data, nil
+	return response, err
 }
 
-func readRespCodeWithTimeout(s *MMDispenser) (ResponseType, error) {
-	inner := make(chan response)
-
-	go func() {
-		i, v := readRespCode(s)
-		inner <- response{data: i, err: v}
-	}()
-	go timeout(s.timeout, inner)
-
-	v := <-inner
+// checkResponseLength makes sure response has every fixed field of command,
+// so the callers can index it without further checks.
+func (s *MMDispenser) checkResponseLength(command protocol.Command, response []byte) error {
+	if info, ok := protocol.LookupCommand(command); ok && len(response) < info.MinResponseLengthWith(s.countEncoding) {
+		return &ProtocolError{Op: "decode " + info.Name, Frame: response, Err: ErrResponseFormat}
+	}
 
-	return v.data, v.err
+	return nil
 }
 
-func readRespCode(v *MMDispenser) (ResponseType, error) {
-	var buf []byte
-	innerBuf := make([]byte, 256)
-
-	totalRead := 0
-
-	for ; ; {
-		n, err := v.port.Read(innerBuf)
+// exchange sends the request and reads the response, retransmitting the
+// request while the device answers NAK and the retry policy allows it.
+func (s *MMDispenser) exchange(ctx context.Context, command protocol.Command, data ...[]byte) ([]byte, int, error) {
+	for attempt := 0; ; attempt++ {
+		err := sendRequest(ctx, s, command, data...)
 
 		if err != nil {
-			return ErrorResponse, err
+			return nil, attempt, err
 		}
 
-		totalRead += n
-		buf = append(buf, innerBuf[:n]...)
+		response, err := readResponse(ctx, s, s.deadline(command))
 
-		if totalRead < 1 {
-			continue
+		if err != ErrNack || attempt >= s.retry.MaxRetries {
+			return response, attempt, err
 		}
-		break
-	}
 
-	if buf[0] == 0x06 {
-		if v.logging {
-			fmt.Printf("mm010_nrc[%v]: <- ACK\n", v.config.Name)
+		if err = sleep(ctx, s.retry.delay(attempt)); err != nil {
+			return nil, attempt, err
 		}
-		return AckResponse, nil // TODO Ack
 	}
+}
 
-	if buf[0] == 0x15 {
-		if v.logging {
-			fmt.Printf("mm010_nrc[%v]: <- NAK\n", v.config.Name)
-		}
-		return NackResponse, nil
+func (s *MMDispenser) acquire(ctx context.Context) error {
+	if s.lock == nil {
+		return nil
 	}
 
-	if buf[0] == 0x04 {
-		if v.logging {
-			fmt.Printf("mm010_nrc[%v]: <- EOT\n", v.config.Name)
-		}
-		return EotResponse, nil
+	select {
+	case s.lock <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
-
-	return ErrorResponse, nil
 }
 
-func readRespDataWithTimeout(s *MMDispenser) ([]byte, error) {
-	inner := make(chan responseData)
-
-	go func() {
-		i, v := readRespData(s)
-		inner <- responseData{data: i, err: v}
-	}()
-	go timeoutData(s.timeout, inner)
-
-	v := <-inner
-
-	return v.data, v.err
+func (s *MMDispenser) release() {
+	if s.lock != nil {
+		<-s.lock
+	}
 }
 
-func readRespData(v *MMDispenser) ([]byte, error) {
-	var buf []byte
-	innerBuf := make([]byte, 256)
-
-	totalRead := 0
-
-	lastRead := false
+// decodedCommand is command with the response decoded by the schema of
+// command, see protocol.DecodeTextWith.
+func (s *MMDispenser) decodedCommand(ctx context.Context, command protocol.Command, data ...[]byte) (interface{}, error) {
+	response, err := s.command(ctx, command, data...)
 
-	for ; ; {
-		n, err := v.port.Read(innerBuf)
-
-		if err != nil {
-			return nil, err
-		}
-
-		totalRead += n
-		buf = append(buf, innerBuf[:n]...)
+	if err != nil {
+		return nil, err
+	}
 
-		if len(buf) > 2 && buf[len(buf)-2] == TextEnd {
-			lastRead = true
-		}
+	return s.decodeText(command, response)
+}
 
-		if lastRead == false {
-			continue
-		}
+func (s *MMDispenser) statusCommand(ctx context.Context, command protocol.Command, data ...[]byte) (StatusCode, byte, byte, error) {
+	v, err := s.decodedCommand(ctx, command, data...)
 
-		break
+	if err != nil {
+		return 0, 0, 0, err
 	}
 
-	if buf[0] != ResponseStart || buf[1] != CommunicationIdentify {
-		fmt.Printf("mm010_nrc[%v]: <- %X\n", v.config.Name, buf)
-		return nil, fmt.Errorf("Response format invalid")
+	if r, ok := v.(protocol.DiagnosticsResponse); ok {
+		return r.Status, r.Value1, r.Value2, nil
 	}
 
-	crc := buf[len(buf)-1]
+	r := v.(protocol.DispenseResponse)
 
-	buf = buf[:len(buf)-1]
-
-	crc2 := getChecksum(buf)
+	return r.Status, r.Dispensed, r.Rejected, nil
+}
 
-	if crc != crc2 {
-		return nil, fmt.Errorf("Response verification failed")
-	}
+func (s *MMDispenser) dispenseCommand(ctx context.Context, command protocol.Command, data ...[]byte) (DispenseResult, error) {
+	status, dispensed, rejected, err := s.statusCommand(ctx, command, data...)
 
-	if buf[2] != TextStart || buf[len(buf)-1] != TextEnd {
-		return nil, fmt.Errorf("Response format invalid")
+	if err != nil {
+		return DispenseResult{}, err
 	}
 
-	buf = buf[4 : len(buf)-1]
-
-	if v.logging {
-		fmt.Printf("mm010_nrc[%v]: <- %X\n", v.config.Name, buf)
-	}
+	res := newDispenseResult(status, dispensed, rejected)
+	s.observeResult(res)
 
-	return buf, nil
+	return res, nil
 }
 
-func sendRequest(v *MMDispenser, commandCode byte, bytesData ...[]byte) error {
-	if !v.open {
-		return errors.New("serial port is closed")
+func sendRequest(ctx context.Context, v *MMDispenser, command protocol.Command, bytesData ...[]byte) error {
+	if !v.isOpen() {
+		return ErrPortClosed
 	}
 
-	buf := new(bytes.Buffer)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
-	length := 6
+	if err := sleep(ctx, time.Until(v.link.ReadyAt())); err != nil {
+		return err
+	}
 
-	for _, b := range bytesData {
-		length += len(b)
+	// whatever is still buffered belongs to an earlier exchange
+	if err := v.flush(); err != nil {
+		return err
 	}
 
-	_ = binary.Write(buf, binary.LittleEndian, RequestStart)
-	_ = binary.Write(buf, binary.LittleEndian, CommunicationIdentify)
-	_ = binary.Write(buf, binary.LittleEndian, TextStart)
-	_ = binary.Write(buf, binary.LittleEndian, commandCode)
+	frame := protocol.EncodeRequestWith(v.checksum, v.identify, command, bytesData...)
 
-	for _, data := range bytesData {
-		_ = binary.Write(buf, binary.LittleEndian, data)
+	if v.extended {
+		frame = protocol.EncodeExtendedRequest(v.checksum, v.identify, command, bytesData...)
 	}
 
-	_ = binary.Write(buf, binary.LittleEndian, TextEnd)
+	if err := v.runBeforeSend(ctx, command, frame, bytes.Join(bytesData, nil)); err != nil {
+		return err
+	}
 
-	crc := getChecksum(buf.Bytes())
+	v.link.Request()
 
-	_ = binary.Write(buf, binary.LittleEndian, crc)
+	v.request = bytes.Join(bytesData, nil)
 
-	if v.logging {
-		fmt.Printf("mm010_nrc[%v]: -> %X\n", v.config.Name, buf.Bytes())
+	if len(v.redactors) > 0 {
+		v.log().Debugf("-> %v %X", command, v.redact(command, nil))
+	} else {
+		v.log().Debugf("-> %v %X", command, frame)
 	}
 
-	_, err := v.port.Write(buf.Bytes())
+	_, err := v.write(frame)
 
 	return err
 }
 
-func getChecksum(data []byte) byte {
-	chksum := byte(0)
-
-	for _, b := range data {
-		chksum = chksum ^ b
-	}
-
-	return chksum
+// ExecuteRaw sends a command the library does not wrap and returns the text of
+// its response, with framing, checksum, retries and the ACK/EOT handshake
+// handled as for any other command. Commands answered by a bare ACK, like
+// Reset, can not be sent this way.
+func (s *MMDispenser) ExecuteRaw(ctx context.Context, code byte, payload []byte) ([]byte, error) {
+	return s.command(ctx, protocol.Command(code), payload)
 }
@@ -1,14 +1,17 @@
 package mm010_nrc_api_test
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	api "mm010_nrc_api"
+	"mm010_nrc_api/protocol"
 	"testing"
 	"time"
 )
 
 func TestConnection(t *testing.T) {
-	c, er := api.NewConnection("COM4", api.Baud4800, true, 3 * time.Second)
+	c, er := api.NewConnection("COM4", api.WithBaud(api.Baud4800), api.WithLogging(true), api.WithTimeout(3*time.Second))
 
 	//fmt.Println(r)
 	if er != nil {
@@ -37,3 +40,19 @@ func TestConnection(t *testing.T) {
 
 	fmt.Println(s)
 }
+
+func TestExecuteRaw(t *testing.T) {
+	_, c := connect(t)
+
+	response, err := c.ExecuteRaw(context.Background(), byte(protocol.CommandPurge), nil)
+
+	if err != nil || !bytes.Equal(response, []byte{byte(protocol.GoodOperation), 0x20}) {
+		t.Fatalf("unexpected purge response %X %v", response, err)
+	}
+
+	response, err = c.ExecuteRaw(context.Background(), 0x5A, []byte("vendor"))
+
+	if err != nil || protocol.StatusCode(response[0]) != protocol.InvalidCommand {
+		t.Fatalf("expected the simulator to reject an unknown opcode, got %X %v", response, err)
+	}
+}
@@ -0,0 +1,27 @@
+package mm010mock_test
+
+import (
+	"context"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/mm010mock"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+)
+
+var _ api.Dispenser = (*mm010mock.MockDispenser)(nil)
+
+func TestMockDispense(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mm010mock.NewMockDispenser(ctrl)
+	m.EXPECT().DispenseContext(gomock.Any(), byte(2)).Return(api.DispenseResult{NotesDispensed: 2}, nil)
+
+	var d api.Dispenser = m
+	res, err := d.DispenseContext(context.Background(), 2)
+
+	if err != nil || res.NotesDispensed != 2 {
+		t.Fatalf("unexpected result %+v %v", res, err)
+	}
+}
@@ -0,0 +1,60 @@
+package mm010sim
+
+import (
+	"time"
+
+	api "mm010_nrc_api"
+)
+
+// FakeConfig sets up a FakeDispenser. The zero value is a dispenser with
+// 1000 notes in the cassette that answers at once.
+type FakeConfig struct {
+	Notes int
+	// Latency holds back every response, NoteTime adds to it for each note a
+	// command moves, so dispensing feels like the real device.
+	Latency  time.Duration
+	NoteTime time.Duration
+}
+
+// FakeDispenser is an api.Dispenser that runs entirely in memory, for
+// developing cash workflows before hardware arrives. It is a regular
+// connection talking to a Simulator, so everything from framing to counters
+// behaves as with a device. Failure scenarios are scripted through Sim, e.g.
+// Sim.FailNext, Sim.SetSensors or Sim.SetNotes.
+type FakeDispenser struct {
+	*api.MMDispenser
+	Sim *Simulator
+}
+
+// NewFakeDispenser starts a simulator configured by cfg and connects to it.
+// The options apply to the connection; the read timeout defaults to 3s on
+// top of cfg.Latency.
+func NewFakeDispenser(cfg FakeConfig, opts ...api.Option) *FakeDispenser {
+	sim := New()
+
+	if cfg.Notes > 0 {
+		sim.SetNotes(cfg.Notes)
+	}
+
+	sim.SetLatency(cfg.Latency, cfg.NoteTime)
+
+	opts = append([]api.Option{api.WithTimeout(3*time.Second + cfg.Latency), api.WithGuardTime(0)}, opts...)
+
+	return &FakeDispenser{
+		MMDispenser: api.NewTransportConnection("fake", sim.Conn(), opts...),
+		Sim:         sim,
+	}
+}
+
+// Close closes the connection and stops the simulator.
+func (f *FakeDispenser) Close() error {
+	err := f.MMDispenser.Close()
+
+	if simErr := f.Sim.Close(); err == nil {
+		err = simErr
+	}
+
+	return err
+}
+
+var _ api.Dispenser = (*FakeDispenser)(nil)
@@ -0,0 +1,542 @@
+// Package mm010sim implements the device side of the MM010 NRC protocol over
+// an in-memory pipe so applications can be tested without hardware.
+//
+//	sim := mm010sim.New()
+//	defer sim.Close()
+//	c := mm010_nrc_api.NewTransportConnection("sim", sim.Conn(), mm010_nrc_api.WithTimeout(time.Second))
+package mm010sim
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"mm010_nrc_api/protocol"
+)
+
+type Simulator struct {
+	mu sync.Mutex
+
+	host   net.Conn
+	device net.Conn
+	done   chan struct{}
+	quit   chan struct{}
+	closer sync.Once
+
+	identify byte
+	checksum protocol.ChecksumFunc
+	extended bool
+	counts   protocol.CountEncoding
+	notes    int
+
+	feedBlocked   bool
+	exitBlocked   bool
+	resetFlag     bool
+	thickness     byte
+	length        byte
+	configuration [2]byte
+	lastStatus    protocol.StatusCode
+	lastDispensed byte
+	lastRejected  byte
+	lastFrame     []byte
+
+	data   map[protocol.DataItem]string
+	params map[protocol.DataItem]map[string]string
+
+	failNext map[protocol.Command]protocol.StatusCode
+	dropNext map[protocol.Command]bool
+	nakNext  int
+	garble   int
+	rejects  int
+
+	latency  time.Duration
+	noteTime time.Duration
+}
+
+// New starts a simulated dispenser with 1000 notes in the cassette.
+func New() *Simulator {
+	host, device := net.Pipe()
+
+	s := &Simulator{
+		host:       host,
+		device:     device,
+		done:       make(chan struct{}),
+		quit:       make(chan struct{}),
+		identify:   protocol.CommunicationIdentify,
+		checksum:   protocol.LRC,
+		notes:      1000,
+		resetFlag:  true,
+		thickness:  10,
+		length:     60,
+		lastStatus: protocol.GoodOperation,
+		data: map[protocol.DataItem]string{
+			protocol.ProgramID:                        "MM010SIM",
+			protocol.MachineID:                        "000001",
+			protocol.MaxNumberOfNotesInOneTransaction: "50",
+			protocol.DispenseCounterLifelong:          "0",
+			protocol.RejectCounterLifelong:            "0",
+			protocol.TotalProcessedCounterLifelong:    "0",
+			protocol.DispenseCounterTrip:              "0",
+			protocol.RejectCounterTrip:                "0",
+			protocol.TotalProcessedCcounterTrip:       "0",
+			protocol.TransactionCounterLifelong:       "0",
+			protocol.TransactionCounterTrip:           "0",
+		},
+		params:   map[protocol.DataItem]map[string]string{},
+		failNext: map[protocol.Command]protocol.StatusCode{},
+		dropNext: map[protocol.Command]bool{},
+	}
+
+	go s.serve()
+
+	return s
+}
+
+// Conn returns the host end of the link.
+func (s *Simulator) Conn() io.ReadWriteCloser {
+	return s.host
+}
+
+func (s *Simulator) Close() error {
+	s.closer.Do(func() { close(s.quit) })
+
+	err := s.device.Close()
+	<-s.done
+
+	return err
+}
+
+func (s *Simulator) SetNotes(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.notes = n
+}
+
+func (s *Simulator) Notes() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.notes
+}
+
+func (s *Simulator) SetSensors(feedBlocked, exitBlocked bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.feedBlocked = feedBlocked
+	s.exitBlocked = exitBlocked
+}
+
+func (s *Simulator) SetData(item protocol.DataItem, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data[item] = value
+}
+
+// SetParamData sets the value of an item read with a parameter, like the
+// per reason RejectReasonCounter.
+func (s *Simulator) SetParamData(item protocol.DataItem, param, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.params[item] == nil {
+		s.params[item] = map[string]string{}
+	}
+
+	s.params[item][param] = value
+}
+
+func (s *Simulator) Data(item protocol.DataItem) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.data[item]
+	return v, ok
+}
+
+// FailNext makes the next command with the given code report status instead
+// of performing the operation.
+func (s *Simulator) FailNext(command protocol.Command, status protocol.StatusCode) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failNext[command] = status
+}
+
+// DropNext makes the next command with the given code execute without
+// answering, as if the response was lost on the line.
+func (s *Simulator) DropNext(command protocol.Command) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.dropNext[command] = true
+}
+
+// NakNext answers the next n requests with NAK.
+func (s *Simulator) NakNext(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nakNext = n
+}
+
+// GarbleNext sends the next n response frames with a wrong checksum.
+func (s *Simulator) GarbleNext(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.garble = n
+}
+
+// SetRejects makes every dispense reject n notes on top of the ones it
+// dispenses, as a worn pick roller would, while the cassette lasts.
+func (s *Simulator) SetRejects(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rejects = n
+}
+
+// SetChecksum switches the block check of frames in both directions, e.g. to
+// protocol.CRC16.
+func (s *Simulator) SetChecksum(sum protocol.ChecksumFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.checksum = sum
+}
+
+// SetExtendedFrames makes the simulator act as newer firmware that
+// understands extended frames and answers them in kind. Without it extended
+// requests are answered NAK.
+func (s *Simulator) SetExtendedFrames(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.extended = enabled
+}
+
+// SetCountEncoding switches the encoding of note counts in requests and
+// responses, e.g. to protocol.WideCount.
+func (s *Simulator) SetCountEncoding(enc protocol.CountEncoding) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.counts = enc
+}
+
+// SetConfiguration sets the two bytes answered to ConfigurationStatus.
+func (s *Simulator) SetConfiguration(c1, c2 byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.configuration = [2]byte{c1, c2}
+}
+
+// SetLatency delays the response to every request by latency, plus noteTime
+// for each note the request asks to move.
+func (s *Simulator) SetLatency(latency, noteTime time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.latency = latency
+	s.noteTime = noteTime
+}
+
+// delay returns how long the response to a request frame is held back.
+func (s *Simulator) delay(frame protocol.Frame) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if frame.Kind != protocol.RequestFrame {
+		return 0
+	}
+
+	notes := 0
+
+	switch frame.Command {
+	case protocol.CommandDispense, protocol.CommandTestDispense:
+		if len(frame.Data) >= s.counts.Size() {
+			notes = s.counts.Decode(frame.Data)
+		}
+	case protocol.CommandSingleNoteDispense, protocol.CommandSingleNoteEject:
+		notes = 1
+	}
+
+	return s.latency + time.Duration(notes)*s.noteTime
+}
+
+func (s *Simulator) serve() {
+	defer close(s.done)
+
+	dec := protocol.NewDecoder(s.device, protocol.FromHost)
+
+	for {
+		s.mu.Lock()
+		dec.SetChecksum(s.checksum)
+		s.mu.Unlock()
+
+		frame, err := dec.Decode()
+
+		switch err {
+		case nil, protocol.ErrFrameChecksum, protocol.ErrFrameFormat:
+		default:
+			return
+		}
+
+		out := s.handle(frame, err)
+
+		if d := s.delay(frame); d > 0 {
+			select {
+			case <-time.After(d):
+			case <-s.quit:
+				return
+			}
+		}
+
+		for _, o := range out {
+			if _, err := s.device.Write(o); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// handle returns the writes to send back for one decoded frame, one element
+// per write.
+func (s *Simulator) handle(frame protocol.Frame, decodeErr error) [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if frame.Kind == protocol.ControlFrame {
+		switch {
+		case decodeErr != nil:
+			return nil
+		case frame.Control == protocol.Ack:
+			s.lastFrame = nil
+			return [][]byte{{protocol.Eot}}
+		case frame.Control == protocol.Nack && s.lastFrame != nil:
+			return [][]byte{s.lastFrame}
+		}
+
+		return nil
+	}
+
+	if decodeErr != nil || frame.Identify != s.identify || frame.Extended && !s.extended {
+		return [][]byte{{protocol.Nack}}
+	}
+
+	if s.nakNext > 0 {
+		s.nakNext--
+		return [][]byte{{protocol.Nack}}
+	}
+
+	if frame.Command == protocol.CommandReset {
+		s.reset()
+		return [][]byte{{protocol.Ack}}
+	}
+
+	response := protocol.MarshalWith(s.checksum, protocol.Frame{
+		Kind:     protocol.ResponseFrame,
+		Identify: s.identify,
+		Command:  frame.Command,
+		Data:     s.execute(frame.Command, frame.Data),
+		Extended: frame.Extended,
+	})
+
+	if s.dropNext[frame.Command] {
+		delete(s.dropNext, frame.Command)
+		return nil
+	}
+
+	if s.garble > 0 {
+		s.garble--
+		response[len(response)-1] ^= 0xFF
+	}
+
+	s.lastFrame = response
+
+	return [][]byte{{protocol.Ack}, response}
+}
+
+func (s *Simulator) reset() {
+	s.resetFlag = true
+	s.feedBlocked = false
+	s.exitBlocked = false
+}
+
+func (s *Simulator) execute(command protocol.Command, param []byte) []byte {
+	if status, ok := s.failNext[command]; ok {
+		delete(s.failNext, command)
+		s.record(status, 0, 0)
+
+		if command == protocol.CommandPurge {
+			return s.noteCounts(status, 0)
+		}
+
+		return s.noteCounts(status, 0, 0)
+	}
+
+	switch command {
+	case protocol.CommandStatus:
+		return s.status()
+	case protocol.CommandPurge:
+		return s.noteCounts(protocol.GoodOperation, 0)
+	case protocol.CommandDispense, protocol.CommandTestDispense:
+		if len(param) != s.counts.Size() || param[0] < 0x20 {
+			return s.noteCounts(protocol.InvalidCommand, 0, 0)
+		}
+		return s.dispense(s.counts.Decode(param))
+	case protocol.CommandSingleNoteDispense, protocol.CommandSingleNoteEject:
+		return s.dispense(1)
+	case protocol.CommandLastStatus:
+		return s.noteCounts(s.lastStatus, int(s.lastDispensed), int(s.lastRejected))
+	case protocol.CommandConfigurationStatus:
+		return []byte{protocol.EncodeCount(s.configuration[0]), protocol.EncodeCount(s.configuration[1])}
+	case protocol.CommandDoubleDetectDiagnostics, protocol.CommandSensorDiagnostics:
+		return []byte{byte(protocol.GoodOperation), protocol.EncodeCount(s.thickness), protocol.EncodeCount(s.length)}
+	case protocol.CommandTestMode:
+		return []byte{byte(protocol.GoodOperation)}
+	case protocol.CommandReadData:
+		return s.readData(string(param))
+	case protocol.CommandWriteData:
+		return s.writeData(string(param))
+	}
+
+	return []byte{byte(protocol.InvalidCommand)}
+}
+
+func (s *Simulator) status() []byte {
+	flags := byte(0x20)
+
+	if s.feedBlocked {
+		flags |= 1 << 0
+	}
+
+	if s.exitBlocked {
+		flags |= 1 << 1
+	}
+
+	if s.resetFlag {
+		flags |= 1 << 3
+		s.resetFlag = false
+	}
+
+	return []byte{flags, 0x20, protocol.EncodeCount(s.thickness), protocol.EncodeCount(s.length)}
+}
+
+func (s *Simulator) dispense(count int) []byte {
+	status := protocol.GoodOperation
+	dispensed := count
+
+	if s.exitBlocked {
+		status, dispensed = protocol.BlockedExit, 0
+	} else if s.notes < count {
+		status, dispensed = protocol.FeedFailure, s.notes
+	}
+
+	s.notes -= dispensed
+	rejected := 0
+
+	if dispensed > 0 {
+		if rejected = s.rejects; rejected > s.notes {
+			rejected = s.notes
+		}
+	}
+
+	s.notes -= rejected
+	s.record(status, byte(dispensed), byte(rejected))
+	s.addCounter(protocol.DispenseCounterLifelong, dispensed)
+	s.addCounter(protocol.DispenseCounterTrip, dispensed)
+	s.addCounter(protocol.RejectCounterLifelong, rejected)
+	s.addCounter(protocol.RejectCounterTrip, rejected)
+	s.addCounter(protocol.TotalProcessedCounterLifelong, dispensed+rejected)
+	s.addCounter(protocol.TotalProcessedCcounterTrip, dispensed+rejected)
+	s.addCounter(protocol.TransactionCounterLifelong, 1)
+	s.addCounter(protocol.TransactionCounterTrip, 1)
+
+	return s.noteCounts(status, dispensed, rejected)
+}
+
+// noteCounts is a response of status followed by note counts.
+func (s *Simulator) noteCounts(status protocol.StatusCode, counts ...int) []byte {
+	res := []byte{byte(status)}
+
+	for _, n := range counts {
+		b, _ := s.counts.Encode(n)
+		res = append(res, b...)
+	}
+
+	return res
+}
+
+func (s *Simulator) record(status protocol.StatusCode, dispensed, rejected byte) {
+	s.lastStatus = status
+	s.lastDispensed = dispensed
+	s.lastRejected = rejected
+}
+
+func (s *Simulator) addCounter(item protocol.DataItem, n int) {
+	v, _ := strconv.Atoi(s.data[item])
+	s.data[item] = strconv.Itoa(v + n)
+}
+
+func parseItem(param string) (protocol.DataItem, string, error) {
+	parts := strings.SplitN(param, "/", 3)
+
+	if len(parts) < 2 || parts[0] != "D" {
+		return 0, "", fmt.Errorf("malformed data item %q", param)
+	}
+
+	item, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+
+	if err != nil {
+		return 0, "", err
+	}
+
+	value := ""
+
+	if len(parts) == 3 {
+		value = parts[2]
+	}
+
+	return protocol.DataItem(item), value, nil
+}
+
+func (s *Simulator) readData(param string) []byte {
+	item, sub, err := parseItem(param)
+
+	if err != nil {
+		return []byte{0x31}
+	}
+
+	value, ok := s.data[item]
+
+	if sub != "" {
+		value, ok = s.params[item][sub]
+	}
+
+	if !ok {
+		return []byte{0x31}
+	}
+
+	return append([]byte{0x30}, value...)
+}
+
+func (s *Simulator) writeData(param string) []byte {
+	item, value, err := parseItem(param)
+
+	if err != nil {
+		return []byte{0x31}
+	}
+
+	s.data[item] = value
+
+	return []byte{0x30}
+}
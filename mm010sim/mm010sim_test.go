@@ -0,0 +1,125 @@
+package mm010sim_test
+
+import (
+	"context"
+	"errors"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/mm010sim"
+	"mm010_nrc_api/protocol"
+	"testing"
+	"time"
+)
+
+func connect(t *testing.T) (*mm010sim.Simulator, *api.MMDispenser) {
+	sim := mm010sim.New()
+	c := api.NewTransportConnection("sim", sim.Conn(), api.WithTimeout(time.Second), api.WithGuardTime(0))
+
+	t.Cleanup(func() {
+		_ = c.Close()
+		_ = sim.Close()
+	})
+
+	return sim, c
+}
+
+func TestStatusReportsResetOnce(t *testing.T) {
+	_, c := connect(t)
+
+	first, err := c.Status()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := c.Status()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !first.ResetSinceLastStatusMessage || second.ResetSinceLastStatusMessage {
+		t.Fatalf("reset flag not cleared: %+v %+v", first, second)
+	}
+}
+
+func TestFakeDispenser(t *testing.T) {
+	f := mm010sim.NewFakeDispenser(mm010sim.FakeConfig{Notes: 10, Latency: 20 * time.Millisecond, NoteTime: 10 * time.Millisecond})
+	defer f.Close()
+
+	started := time.Now()
+
+	if res, err := f.DispenseContext(context.Background(), 4); err != nil || res.NotesDispensed != 4 {
+		t.Fatalf("dispense: %+v, %v", res, err)
+	}
+
+	if d := time.Since(started); d < 60*time.Millisecond {
+		t.Fatalf("dispense took %v, want at least 60ms", d)
+	}
+
+	if f.Sim.Notes() != 6 {
+		t.Fatalf("%d notes left, want 6", f.Sim.Notes())
+	}
+
+	f.Sim.SetSensors(false, true)
+
+	if _, err := f.DispenseContext(context.Background(), 1); err == nil {
+		t.Fatal("dispense with the exit sensor blocked succeeded")
+	}
+}
+
+func TestRegistryCoverage(t *testing.T) {
+	_, c := connect(t)
+
+	params := map[protocol.Command][]byte{
+		protocol.CommandDispense:     {protocol.EncodeCount(1)},
+		protocol.CommandTestDispense: {protocol.EncodeCount(1)},
+		protocol.CommandReadData:     []byte("D/100"),
+		protocol.CommandWriteData:    []byte("D/104/50"),
+	}
+
+	for _, info := range protocol.Commands() {
+		if info.Schema == "" {
+			continue
+		}
+
+		text, err := c.ExecuteRaw(context.Background(), byte(info.Code), params[info.Code])
+
+		if err != nil {
+			t.Fatalf("%s: %v", info.Name, err)
+		}
+
+		if _, err = protocol.DecodeText(info.Code, text); err != nil {
+			t.Fatalf("%s: response % X: %v", info.Name, text, err)
+		}
+	}
+}
+
+func TestFaultInjection(t *testing.T) {
+	sim, c := connect(t)
+
+	sim.FailNext(protocol.CommandDispense, api.FeedFailure)
+
+	status, _, _, err := c.Dispense(1)
+
+	if err != nil || status != api.FeedFailure {
+		t.Fatalf("expected FeedFailure, got %v %v", status, err)
+	}
+
+	sim.NakNext(1)
+
+	if _, err := c.Status(); err != nil {
+		t.Fatalf("expected a single NAK to be retried, got %v", err)
+	}
+
+	sim.NakNext(api.DefaultRetryPolicy.MaxRetries + 1)
+
+	if _, err := c.Status(); !errors.Is(err, api.ErrNack) {
+		t.Fatalf("expected ErrNack, got %v", err)
+	}
+
+	sim.GarbleNext(1)
+
+	if _, err := c.Status(); !errors.Is(err, api.ErrChecksumMismatch) {
+		t.Fatalf("expected ErrChecksumMismatch, got %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package mm010_nrc_api
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// NoteMetrics builds histograms of the average note thickness and length the
+// device reports after dispenses. A shift of the distribution points to notes
+// of another denomination in the cassette, a widening one to worn notes.
+// Attach it with WithNoteMetrics; it is safe for concurrent use and may be
+// shared by several connections.
+type NoteMetrics struct {
+	mu        sync.Mutex
+	since     time.Time
+	last      time.Time
+	thickness [256]uint64
+	length    [256]uint64
+	samples   uint64
+}
+
+// NoteHistogram counts the samples per measured value.
+type NoteHistogram struct {
+	Counts map[byte]uint64 `json:"counts"`
+	Min    byte            `json:"min"`
+	Max    byte            `json:"max"`
+	Mean   float64         `json:"mean"`
+	StdDev float64         `json:"std_dev"`
+}
+
+// NoteMetricsSnapshot holds the histograms of the samples taken between
+// Since and Last.
+type NoteMetricsSnapshot struct {
+	Samples   uint64        `json:"samples"`
+	Since     time.Time     `json:"since"`
+	Last      time.Time     `json:"last"`
+	Thickness NoteHistogram `json:"thickness"`
+	Length    NoteHistogram `json:"length"`
+}
+
+func NewNoteMetrics() *NoteMetrics {
+	return &NoteMetrics{since: time.Now()}
+}
+
+// WithNoteMetrics has the connection poll Status after every dispense that
+// moved notes and add the reported averages to m.
+func WithNoteMetrics(m *NoteMetrics) Option {
+	return func(s *MMDispenser) {
+		s.noteMetrics = m
+	}
+}
+
+func (m *NoteMetrics) Add(thickness, length byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.thickness[thickness]++
+	m.length[length]++
+	m.samples++
+	m.last = time.Now()
+}
+
+func (m *NoteMetrics) Snapshot() NoteMetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return NoteMetricsSnapshot{
+		Samples:   m.samples,
+		Since:     m.since,
+		Last:      m.last,
+		Thickness: histogram(&m.thickness, m.samples),
+		Length:    histogram(&m.length, m.samples),
+	}
+}
+
+// Reset drops the samples, e.g. after the cassette was refilled, and starts
+// a new period.
+func (m *NoteMetrics) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.since, m.last = time.Now(), time.Time{}
+	m.thickness = [256]uint64{}
+	m.length = [256]uint64{}
+	m.samples = 0
+}
+
+// sampleNotes feeds the note metrics, if any, after a dispense that moved
+// notes. A failed status poll only costs the sample.
+func (s *MMDispenser) sampleNotes(ctx context.Context, res DispenseResult) {
+	if s.noteMetrics == nil || res.NotesDispensed == 0 {
+		return
+	}
+
+	status, err := s.StatusContext(ctx)
+
+	if err != nil {
+		s.log().Errorf("note metrics: %v", err)
+		return
+	}
+
+	s.noteMetrics.Add(status.AverageThickness, status.AverageLength)
+}
+
+func histogram(counts *[256]uint64, samples uint64) NoteHistogram {
+	h := NoteHistogram{Counts: map[byte]uint64{}}
+
+	if samples == 0 {
+		return h
+	}
+
+	h.Min = 0xFF
+	sum := 0.0
+
+	for v, n := range counts {
+		if n == 0 {
+			continue
+		}
+
+		h.Counts[byte(v)] = n
+		sum += float64(v) * float64(n)
+
+		if byte(v) < h.Min {
+			h.Min = byte(v)
+		}
+
+		h.Max = byte(v)
+	}
+
+	h.Mean = sum / float64(samples)
+	variance := 0.0
+
+	for v, n := range h.Counts {
+		d := float64(v) - h.Mean
+		variance += d * d * float64(n)
+	}
+
+	h.StdDev = math.Sqrt(variance / float64(samples))
+
+	return h
+}
@@ -0,0 +1,45 @@
+package mm010_nrc_api_test
+
+import (
+	"context"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/mm010sim"
+	"testing"
+	"time"
+)
+
+func TestNoteMetrics(t *testing.T) {
+	sim := mm010sim.New()
+	metrics := api.NewNoteMetrics()
+	c := api.NewTransportConnection("sim", sim.Conn(), api.WithTimeout(time.Second), api.WithGuardTime(0),
+		api.WithNoteMetrics(metrics))
+
+	defer sim.Close()
+	defer c.Close()
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.DispenseContext(context.Background(), 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := c.DispenseOneByOne(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	snap := metrics.Snapshot()
+
+	if snap.Samples != 3 || snap.Thickness.Mean != 10 || snap.Thickness.StdDev != 0 || snap.Length.Counts[60] != 3 ||
+		snap.Length.Min != 60 || snap.Length.Max != 60 {
+		t.Fatalf("unexpected snapshot %+v", snap)
+	}
+
+	metrics.Reset()
+
+	if snap = metrics.Snapshot(); snap.Samples != 0 || len(snap.Length.Counts) != 0 {
+		t.Fatalf("snapshot after reset %+v", snap)
+	}
+}
+
+// TestRegistryCoverage keeps the simulator in sync with the command registry:
+// every command with a response schema must be answered in that schema.
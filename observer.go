@@ -0,0 +1,69 @@
+package mm010_nrc_api
+
+import (
+	"context"
+	"time"
+
+	"mm010_nrc_api/protocol"
+)
+
+type Command = protocol.Command
+
+// CommandEvent describes one finished command exchange.
+type CommandEvent struct {
+	Command  Command
+	Started  time.Time
+	Duration time.Duration
+	// Retries counts retransmissions of the request after a NAK.
+	Retries int
+	// Client is the client of the command, see ContextWithClient.
+	Client string
+	// Result is set when a note moving command (Dispense, TestDispense,
+	// SingleNoteDispense, SingleNoteEject) got a response.
+	Result *DispenseResult
+	Err    error
+}
+
+// WithObserver registers fn to be called after every command exchange. The
+// observers run in the order they were registered, followed by the audit
+// logger, on the goroutine of the command and before it returns, so the
+// event of a command is always delivered before its result. They are called
+// while the link is held, so they must not block or issue commands.
+func WithObserver(fn func(CommandEvent)) Option {
+	return func(s *MMDispenser) {
+		s.observers = append(s.observers, fn)
+	}
+}
+
+func (s *MMDispenser) observe(ctx context.Context, command Command, started time.Time, retries int, response []byte, err error) {
+	s.countCommand(time.Since(started), retries, err)
+
+	if len(s.observers) == 0 {
+		return
+	}
+
+	e := CommandEvent{Command: command, Started: started, Duration: time.Since(started), Retries: retries,
+		Client: ClientFromContext(ctx), Err: err}
+
+	if err == nil && movesNotes(command) {
+		if v, err := s.decodeText(command, response); err == nil {
+			r := v.(protocol.DispenseResponse)
+			res := newDispenseResult(r.Status, r.Dispensed, r.Rejected)
+			e.Result = &res
+		}
+	}
+
+	for _, fn := range s.observers {
+		fn(e)
+	}
+}
+
+func movesNotes(command Command) bool {
+	switch command {
+	case protocol.CommandDispense, protocol.CommandTestDispense,
+		protocol.CommandSingleNoteDispense, protocol.CommandSingleNoteEject:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,76 @@
+package mm010_nrc_api_test
+
+import (
+	"context"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/mm010sim"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestObserverSeesRetriesAndResults(t *testing.T) {
+	sim := mm010sim.New()
+	defer sim.Close()
+
+	var events []api.CommandEvent
+
+	c := api.NewTransportConnection("sim", sim.Conn(), api.WithTimeout(time.Second),
+		api.WithBlockedSensorPolicy(api.IgnoreBlockedSensor),
+		api.WithObserver(func(e api.CommandEvent) { events = append(events, e) }))
+	defer c.Close()
+
+	sim.NakNext(1)
+
+	if _, err := c.DispenseContext(context.Background(), 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 1 || events[0].Retries != 1 || events[0].Result == nil || events[0].Result.NotesDispensed != 2 {
+		t.Fatalf("unexpected events %+v", events)
+	}
+}
+
+type auditFunc func(r api.AuditRecord) error
+
+func (f auditFunc) Audit(r api.AuditRecord) error {
+	return f(r)
+}
+
+func TestObserversAndAuditPrecedeResult(t *testing.T) {
+	sim := mm010sim.New()
+	defer sim.Close()
+
+	var order []string
+
+	observer := func(name string) func(api.CommandEvent) {
+		return func(e api.CommandEvent) { order = append(order, name+" "+e.Command.String()) }
+	}
+
+	c := api.NewTransportConnection("sim", sim.Conn(), api.WithTimeout(time.Second),
+		api.WithObserver(observer("first")), api.WithObserver(observer("second")),
+		api.WithAuditLogger(auditFunc(func(r api.AuditRecord) error {
+			order = append(order, "audit "+r.Command)
+			return nil
+		})))
+	defer c.Close()
+
+	if _, err := c.DispenseContext(context.Background(), 2); err != nil {
+		t.Fatal(err)
+	}
+
+	// no waiting: everything ran before DispenseContext returned
+	if got := strings.Join(order, ", "); got != "first Status, second Status, first Dispense, second Dispense, audit Dispense" {
+		t.Fatalf("unexpected order %s", got)
+	}
+
+	order = nil
+
+	if _, _, err := c.PurgeContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := strings.Join(order, ", "); got != "first Purge, second Purge, audit Purge" {
+		t.Fatalf("unexpected order %s", got)
+	}
+}
@@ -0,0 +1,84 @@
+package mm010_nrc_api
+
+import (
+	"context"
+	"fmt"
+
+	"mm010_nrc_api/protocol"
+)
+
+// NoteTelemetry is one note of DispenseOneByOne or EjectOneByOne, with the
+// averages the device reported in the status poll right after it.
+type NoteTelemetry struct {
+	Note      int            `json:"note"`
+	Result    DispenseResult `json:"result"`
+	Thickness byte           `json:"thickness"`
+	Length    byte           `json:"length"`
+}
+
+type OneByOneReport struct {
+	Requested int             `json:"requested"`
+	Dispensed int             `json:"dispensed"`
+	Rejected  int             `json:"rejected"`
+	Notes     []NoteTelemetry `json:"notes"`
+}
+
+// DispenseOneByOne dispenses n notes with one SingleNoteDispense each and
+// polls Status after every note for its measurements, e.g. to audit a
+// suspect cassette. It stops at the first double detect with
+// ErrDoubleDetectAnomaly and at any other error status with
+// ErrPartialDispense. The report covers the notes moved until then.
+func (s *MMDispenser) DispenseOneByOne(ctx context.Context, n int) (OneByOneReport, error) {
+	return s.oneByOne(ctx, protocol.CommandSingleNoteDispense, n)
+}
+
+// EjectOneByOne is DispenseOneByOne with SingleNoteEject, which moves the
+// notes to the reject bin.
+func (s *MMDispenser) EjectOneByOne(ctx context.Context, n int) (OneByOneReport, error) {
+	return s.oneByOne(ctx, protocol.CommandSingleNoteEject, n)
+}
+
+func (s *MMDispenser) oneByOne(ctx context.Context, command Command, n int) (OneByOneReport, error) {
+	report := OneByOneReport{Requested: n}
+
+	if n < 1 {
+		return report, fmt.Errorf("note count %d out of range", n)
+	}
+
+	if err := s.checkSensors(ctx); err != nil {
+		return report, err
+	}
+
+	for i := 1; i <= n; i++ {
+		res, err := s.dispenseCommand(ctx, command)
+
+		if err != nil {
+			return report, err
+		}
+
+		report.Dispensed += int(res.NotesDispensed)
+		report.Rejected += int(res.NotesRejected)
+
+		status, err := s.StatusContext(ctx)
+
+		if err != nil {
+			return report, err
+		}
+
+		if s.noteMetrics != nil && res.NotesDispensed > 0 {
+			s.noteMetrics.Add(status.AverageThickness, status.AverageLength)
+		}
+
+		report.Notes = append(report.Notes, NoteTelemetry{Note: i, Result: res,
+			Thickness: status.AverageThickness, Length: status.AverageLength})
+
+		switch {
+		case res.Status == DoubleDetectError:
+			return report, fmt.Errorf("%w at note %d", ErrDoubleDetectAnomaly, i)
+		case res.Status.IsError():
+			return report, fmt.Errorf("%w: moved %d of %d notes, last status %v", ErrPartialDispense, report.Dispensed, n, res.Status)
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,30 @@
+package mm010_nrc_api_test
+
+import (
+	"context"
+	"errors"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/protocol"
+	"testing"
+)
+
+func TestDispenseOneByOne(t *testing.T) {
+	sim, c := connect(t)
+	ctx := context.Background()
+
+	report, err := c.DispenseOneByOne(ctx, 3)
+
+	if err != nil || report.Dispensed != 3 || len(report.Notes) != 3 || sim.Notes() != 997 {
+		t.Fatalf("got %+v, %v", report, err)
+	}
+
+	if note := report.Notes[2]; note.Note != 3 || note.Thickness != 10 || note.Length != 60 {
+		t.Fatalf("unexpected telemetry %+v", note)
+	}
+
+	sim.FailNext(protocol.CommandSingleNoteEject, api.DoubleDetectError)
+
+	if report, err = c.EjectOneByOne(ctx, 5); !errors.Is(err, api.ErrDoubleDetectAnomaly) || len(report.Notes) != 1 {
+		t.Fatalf("expected the double detect to stop the batch, got %+v, %v", report, err)
+	}
+}
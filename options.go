@@ -0,0 +1,105 @@
+package mm010_nrc_api
+
+import (
+	"time"
+
+	"github.com/tarm/serial"
+
+	"mm010_nrc_api/protocol"
+)
+
+// Option tweaks a connection before it is opened.
+type Option func(s *MMDispenser)
+
+// WithBaud sets the serial port speed. It has no effect on transport connections.
+func WithBaud(baud Baud) Option {
+	return func(s *MMDispenser) {
+		if s.config != nil {
+			s.config.Baud = int(baud)
+		}
+	}
+}
+
+func WithLogging(enabled bool) Option {
+	return func(s *MMDispenser) {
+		s.logging = enabled
+	}
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(s *MMDispenser) {
+		if timeout > 0 {
+			s.timeout = timeout
+		}
+	}
+}
+
+func WithEchoSuppression(enabled bool) Option {
+	return func(s *MMDispenser) {
+		s.SetEchoSuppression(enabled)
+	}
+}
+
+type ParityMode byte
+
+const (
+	ParityNone ParityMode = ParityMode(serial.ParityNone)
+	ParityOdd  ParityMode = ParityMode(serial.ParityOdd)
+	ParityEven ParityMode = ParityMode(serial.ParityEven)
+)
+
+type StopBits byte
+
+const (
+	Stop1 StopBits = StopBits(serial.Stop1)
+	Stop2 StopBits = StopBits(serial.Stop2)
+)
+
+// WithParity sets the serial port parity. It has no effect on transport connections.
+func WithParity(parity ParityMode) Option {
+	return func(s *MMDispenser) {
+		if s.config != nil {
+			s.config.Parity = serial.Parity(parity)
+		}
+	}
+}
+
+// WithStopBits sets the serial port stop bits. It has no effect on transport connections.
+func WithStopBits(stopBits StopBits) Option {
+	return func(s *MMDispenser) {
+		if s.config != nil {
+			s.config.StopBits = serial.StopBits(stopBits)
+		}
+	}
+}
+
+// WithDataBits sets the serial port data bits, 7 by default. It has no effect
+// on transport connections.
+func WithDataBits(bits byte) Option {
+	return func(s *MMDispenser) {
+		if s.config != nil {
+			s.config.Size = bits
+		}
+	}
+}
+
+// WithCommunicationIdentify sets the identify byte sent in requests and
+// expected in responses.
+func WithCommunicationIdentify(identify byte) Option {
+	return func(s *MMDispenser) {
+		s.identify = identify
+	}
+}
+
+type ChecksumFunc = protocol.ChecksumFunc
+
+// WithChecksum sets the block check of request and response frames,
+// protocol.LRC unless set. protocol.CRC16 serves related models that use a
+// two byte CRC instead.
+func WithChecksum(sum ChecksumFunc) Option {
+	return func(s *MMDispenser) {
+		if sum != nil {
+			s.checksum = sum
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package mm010_nrc_api_test
+
+import (
+	api "mm010_nrc_api"
+	"mm010_nrc_api/mm010sim"
+	"mm010_nrc_api/protocol"
+	"testing"
+	"time"
+)
+
+func TestCRC16Checksum(t *testing.T) {
+	sim := mm010sim.New()
+	sim.SetChecksum(protocol.CRC16)
+
+	c := api.NewTransportConnection("sim", sim.Conn(), api.WithTimeout(time.Second), api.WithGuardTime(0), api.WithChecksum(protocol.CRC16))
+
+	defer sim.Close()
+	defer c.Close()
+
+	if _, dispensed, _, err := c.Dispense(3); err != nil || dispensed != 3 {
+		t.Fatalf("dispense: %d, %v", dispensed, err)
+	}
+}
@@ -0,0 +1,178 @@
+package mm010_nrc_api
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type PollerConfig struct {
+	// Interval between polls, 1s if zero.
+	Interval time.Duration
+	// HistorySize is the number of samples kept, 64 if zero.
+	HistorySize int
+	// Debounce is the number of consecutive polls a sensor flag must report a
+	// new value before it is accepted. Zero or one accepts every change.
+	Debounce int
+}
+
+type StatusSample struct {
+	Time   time.Time
+	Status Status
+	Err    error
+}
+
+type StatusChange struct {
+	Time     time.Time
+	Previous Status
+	Current  Status
+}
+
+// StatusPoller polls Status in the background, keeps the recent samples and
+// reports debounced changes of the sensor flags.
+type StatusPoller struct {
+	d   *MMDispenser
+	cfg PollerConfig
+
+	changes chan StatusChange
+
+	mu      sync.Mutex
+	current Status
+	valid   bool
+	pending []int
+	history []StatusSample
+	head    int
+}
+
+// debouncedFlags are the Status flags subject to PollerConfig.Debounce and
+// change detection.
+var debouncedFlags = []func(*Status) *bool{
+	func(s *Status) *bool { return &s.FeedSensorBlocked },
+	func(s *Status) *bool { return &s.ExitSensorBlocked },
+	func(s *Status) *bool { return &s.TimingWheelSensorBlocked },
+	func(s *Status) *bool { return &s.CalibratingDoubleDetect },
+}
+
+func NewStatusPoller(d *MMDispenser, cfg PollerConfig) *StatusPoller {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+
+	if cfg.HistorySize <= 0 {
+		cfg.HistorySize = 64
+	}
+
+	return &StatusPoller{
+		d:       d,
+		cfg:     cfg,
+		changes: make(chan StatusChange, 16),
+		pending: make([]int, len(debouncedFlags)),
+	}
+}
+
+// Run polls until ctx is done and then closes the Changes channel. Changes
+// must be drained, or polling stalls once its buffer is full.
+func (p *StatusPoller) Run(ctx context.Context) error {
+	defer close(p.changes)
+
+	if !p.d.isOpen() {
+		return ErrPortClosed
+	}
+
+	ticker := time.NewTicker(p.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+		status, err := p.d.StatusContext(ctx)
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if change, ok := p.update(StatusSample{Time: now, Status: status, Err: err}); ok {
+			select {
+			case p.changes <- change:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Current returns the debounced status and whether any poll succeeded yet.
+func (p *StatusPoller) Current() (Status, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.current, p.valid
+}
+
+// History returns the kept samples, oldest first, as they were polled.
+func (p *StatusPoller) History() []StatusSample {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	res := make([]StatusSample, 0, len(p.history))
+
+	if len(p.history) == p.cfg.HistorySize {
+		res = append(res, p.history[p.head:]...)
+	}
+
+	return append(res, p.history[:p.head]...)
+}
+
+func (p *StatusPoller) Changes() <-chan StatusChange {
+	return p.changes
+}
+
+func (p *StatusPoller) update(sample StatusSample) (StatusChange, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.history) < p.cfg.HistorySize {
+		p.history = append(p.history, sample)
+	} else {
+		p.history[p.head] = sample
+	}
+
+	p.head = (p.head + 1) % p.cfg.HistorySize
+
+	if sample.Err != nil {
+		return StatusChange{}, false
+	}
+
+	if !p.valid {
+		p.current, p.valid = sample.Status, true
+		return StatusChange{}, false
+	}
+
+	next := sample.Status
+	changed := false
+
+	for i, flag := range debouncedFlags {
+		if *flag(&next) == *flag(&p.current) {
+			p.pending[i] = 0
+			continue
+		}
+
+		if p.pending[i]++; p.pending[i] < p.cfg.Debounce {
+			*flag(&next) = *flag(&p.current)
+			continue
+		}
+
+		p.pending[i] = 0
+		changed = true
+	}
+
+	change := StatusChange{Time: sample.Time, Previous: p.current, Current: next}
+	p.current = next
+
+	return change, changed
+}
@@ -0,0 +1,55 @@
+package mm010_nrc_api_test
+
+import (
+	"context"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/protocol"
+	"testing"
+	"time"
+)
+
+func TestStatusPollerDebounce(t *testing.T) {
+	feed := []bool{false, true, false, true, true}
+	polls := 0
+
+	port := newFakePort(answer(func(cmd protocol.Command) []byte {
+		sensors := byte(0x20)
+
+		if feed[polls%len(feed)] {
+			sensors |= 0x01
+		}
+
+		polls++
+
+		return []byte{sensors, 0x20, 0x20, 0x20}
+	}))
+
+	c := api.NewTransportConnection("poller", port, api.WithTimeout(time.Second))
+	p := api.NewStatusPoller(c, api.PollerConfig{Interval: time.Millisecond, HistorySize: 4, Debounce: 2})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	go func() {
+		done <- p.Run(ctx)
+	}()
+
+	change := <-p.Changes()
+	cancel()
+
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("expected Run to end with the context, got %v", err)
+	}
+
+	if change.Previous.FeedSensorBlocked || !change.Current.FeedSensorBlocked {
+		t.Fatalf("unexpected change %+v", change)
+	}
+
+	if history := p.History(); len(history) != 4 || !history[3].Status.FeedSensorBlocked || history[1].Status.FeedSensorBlocked {
+		t.Fatalf("expected the last 4 of 5 polls in the history, got %+v", history)
+	}
+
+	if current, ok := p.Current(); !ok || !current.FeedSensorBlocked {
+		t.Fatalf("unexpected current status %+v %v", current, ok)
+	}
+}
@@ -0,0 +1,232 @@
+package mm010_nrc_api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+type PoolConfig struct {
+	// RetryAfter is how long a unit that failed stays out of service, 1 minute
+	// if zero.
+	RetryAfter time.Duration
+}
+
+// Pool manages several dispensers, e.g. one per cassette of a multi-unit
+// ATM, and routes dispense requests to a unit holding the denomination. Units
+// may share a denomination to back each other up.
+type Pool struct {
+	cfg PoolConfig
+
+	mu    sync.Mutex
+	units []*poolUnit
+}
+
+type poolUnit struct {
+	name         string
+	denomination int
+	d            Dispenser
+	failed       time.Time
+	err          error
+}
+
+type UnitStatus struct {
+	Name         string
+	Denomination int
+	Status       Status
+	Err          error
+	// OutOfService is set while the unit is skipped after a failure.
+	OutOfService bool
+	LastError    error
+}
+
+// UnitDispense is the part of a pool dispense one unit handled.
+type UnitDispense struct {
+	Unit        string
+	Transaction Transaction
+	Err         error
+}
+
+type PoolResult struct {
+	Denomination int
+	Requested    int
+	Dispensed    int
+	Units        []UnitDispense
+}
+
+func NewPool(cfg PoolConfig) *Pool {
+	if cfg.RetryAfter <= 0 {
+		cfg.RetryAfter = time.Minute
+	}
+
+	return &Pool{cfg: cfg}
+}
+
+// Add adds d under a unique name. Units are tried in the order they were
+// added.
+func (p *Pool) Add(name string, denomination int, d Dispenser) error {
+	if denomination <= 0 {
+		return fmt.Errorf("unit %q: denomination %d out of range", name, denomination)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, u := range p.units {
+		if u.name == name {
+			return fmt.Errorf("unit %q already in pool", name)
+		}
+	}
+
+	p.units = append(p.units, &poolUnit{name: name, denomination: denomination, d: d})
+
+	return nil
+}
+
+// Restore puts a failed unit back in service right away.
+func (p *Pool) Restore(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, u := range p.units {
+		if u.name == name {
+			u.failed, u.err = time.Time{}, nil
+		}
+	}
+}
+
+// Dispense dispenses count notes of denomination with DispenseNotes. If a
+// unit falls short or finds a sensor blocked, it is taken out of service and
+// the next unit of the denomination dispenses the rest. On any other error
+// the unit is taken out of service too, but the pool stops: whether notes
+// left the unit is not known then and must be checked with LastStatus before
+// trying again. A cancelled or expired ctx stops the pool as well but leaves
+// the unit in service. A unit that refuses the dispense before sending it,
+// e.g. in maintenance mode or rate limited, stays in service and the next
+// unit is tried.
+func (p *Pool) Dispense(ctx context.Context, denomination int, count int) (PoolResult, error) {
+	res := PoolResult{Denomination: denomination, Requested: count}
+
+	if count < 1 {
+		return res, fmt.Errorf("note count %d out of range", count)
+	}
+
+	var refused error
+
+	for _, u := range p.candidates(denomination) {
+		tx, err := u.d.DispenseNotes(ctx, res.Requested-res.Dispensed)
+
+		res.Dispensed += tx.Dispensed
+		res.Units = append(res.Units, UnitDispense{Unit: u.name, Transaction: tx, Err: err})
+
+		if err == nil {
+			return res, nil
+		}
+
+		if ctx.Err() != nil {
+			return res, fmt.Errorf("unit %q: %w", u.name, err)
+		}
+
+		if refusedBeforeSend(err) {
+			refused = fmt.Errorf("unit %q: %w", u.name, err)
+			continue
+		}
+
+		p.fail(u, err)
+
+		var blocked *BlockedSensorError
+
+		if !errors.Is(err, ErrPartialDispense) && !errors.As(err, &blocked) {
+			return res, fmt.Errorf("unit %q: %w", u.name, err)
+		}
+	}
+
+	if len(res.Units) == 0 {
+		return res, fmt.Errorf("%w %d", ErrNoUnit, denomination)
+	}
+
+	if res.Dispensed == 0 && refused != nil {
+		return res, refused
+	}
+
+	return res, fmt.Errorf("%w: dispensed %d of %d notes of %d", ErrPartialDispense, res.Dispensed, res.Requested, denomination)
+}
+
+// Denominations lists the denominations of the units in service, highest
+// first.
+func (p *Pool) Denominations() []int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	seen := map[int]bool{}
+	var res []int
+
+	for _, u := range p.units {
+		if !seen[u.denomination] && !p.outOfService(u) {
+			seen[u.denomination] = true
+			res = append(res, u.denomination)
+		}
+	}
+
+	sort.Sort(sort.Reverse(sort.IntSlice(res)))
+
+	return res
+}
+
+// Status polls every unit, also those out of service.
+func (p *Pool) Status(ctx context.Context) []UnitStatus {
+	p.mu.Lock()
+	units := append([]*poolUnit(nil), p.units...)
+	p.mu.Unlock()
+
+	res := make([]UnitStatus, len(units))
+
+	for i, u := range units {
+		status, err := u.d.StatusContext(ctx)
+
+		p.mu.Lock()
+		res[i] = UnitStatus{Name: u.name, Denomination: u.denomination, Status: status, Err: err,
+			OutOfService: p.outOfService(u), LastError: u.err}
+		p.mu.Unlock()
+	}
+
+	return res
+}
+
+// refusedBeforeSend tells the errors that stop a dispense before a command
+// is sent, so that no note moved because of them.
+func refusedBeforeSend(err error) bool {
+	var hook *HookError
+
+	return errors.As(err, &hook) || errors.Is(err, ErrMaintenanceMode) || errors.Is(err, ErrRateLimited) ||
+		errors.Is(err, ErrCountRange)
+}
+
+func (p *Pool) candidates(denomination int) []*poolUnit {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var res []*poolUnit
+
+	for _, u := range p.units {
+		if u.denomination == denomination && !p.outOfService(u) {
+			res = append(res, u)
+		}
+	}
+
+	return res
+}
+
+func (p *Pool) outOfService(u *poolUnit) bool {
+	return !u.failed.IsZero() && time.Since(u.failed) < p.cfg.RetryAfter
+}
+
+func (p *Pool) fail(u *poolUnit, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	u.failed, u.err = time.Now(), err
+}
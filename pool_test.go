@@ -0,0 +1,94 @@
+package mm010_nrc_api_test
+
+import (
+	"context"
+	"errors"
+	api "mm010_nrc_api"
+	"testing"
+)
+
+func TestPoolFailover(t *testing.T) {
+	first, a := connect(t)
+	second, b := connect(t)
+	_, c := connect(t)
+	ctx := context.Background()
+
+	first.SetNotes(2)
+	second.SetNotes(10)
+
+	pool := api.NewPool(api.PoolConfig{})
+
+	_ = pool.Add("a", 10, a)
+	_ = pool.Add("b", 10, b)
+	_ = pool.Add("c", 20, c)
+
+	if err := pool.Add("a", 20, c); err == nil {
+		t.Fatal("added a unit name twice")
+	}
+
+	res, err := pool.Dispense(ctx, 10, 5)
+
+	if err != nil || res.Dispensed != 5 || len(res.Units) != 2 || second.Notes() != 7 {
+		t.Fatalf("expected the second unit to dispense the rest, got %+v %v", res, err)
+	}
+
+	if _, err := pool.Dispense(ctx, 50, 1); !errors.Is(err, api.ErrNoUnit) {
+		t.Fatalf("expected ErrNoUnit, got %v", err)
+	}
+
+	if _, err := pool.Dispense(ctx, 10, 0); err == nil {
+		t.Fatal("expected an error for a zero note count")
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	if _, err := pool.Dispense(cancelled, 20, 1); err == nil {
+		t.Fatal("expected an error for a cancelled context")
+	}
+
+	for _, u := range pool.Status(ctx) {
+		if u.Err != nil || u.OutOfService != (u.Name == "a") {
+			t.Fatalf("unexpected unit status %+v", u)
+		}
+	}
+}
+
+func TestPoolSkipsRefusingUnit(t *testing.T) {
+	_, a := connect(t)
+	second, b := connect(t)
+	ctx := context.Background()
+
+	pool := api.NewPool(api.PoolConfig{})
+
+	if err := pool.Add("zero", 0, a); err == nil {
+		t.Fatal("added a unit without a denomination")
+	}
+
+	_ = pool.Add("a", 10, a)
+	_ = pool.Add("b", 10, b)
+
+	if err := a.EnterMaintenanceMode(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := pool.Dispense(ctx, 10, 3)
+
+	if err != nil || res.Dispensed != 3 || len(res.Units) != 2 || second.Notes() != 997 {
+		t.Fatalf("expected the second unit to dispense, got %+v %v", res, err)
+	}
+
+	if err := b.EnterMaintenanceMode(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := pool.Dispense(ctx, 10, 1); !errors.Is(err, api.ErrMaintenanceMode) {
+		t.Fatalf("expected ErrMaintenanceMode, got %v", err)
+	}
+
+	for _, u := range pool.Status(ctx) {
+		if u.OutOfService {
+			t.Fatalf("unit %s taken out of service for a refused dispense", u.Name)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package mm010_nrc_api
+
+import (
+	"io"
+	"strings"
+)
+
+// WithPortLock makes NewConnection, Open and reconnects take an advisory
+// lock keyed by the port path, so a second process using the library on
+// the same port fails with ErrPortBusy instead of corrupting both sessions.
+// The lock is a flock on a file in the temp directory on Unix and a named
+// mutex, local to the login session, on Windows; other systems go without.
+// Programs not taking the lock are not kept out.
+func WithPortLock(enabled bool) Option {
+	return func(s *MMDispenser) {
+		s.portLock = enabled
+	}
+}
+
+// lockedPort releases the port lock once the port is closed.
+type lockedPort struct {
+	Transport
+	lock io.Closer
+}
+
+func (p *lockedPort) Close() error {
+	err := p.Transport.Close()
+	p.lock.Close()
+
+	return err
+}
+
+func (p *lockedPort) Flush() error {
+	if f, ok := p.Transport.(Flusher); ok {
+		return f.Flush()
+	}
+
+	return nil
+}
+
+// portKey turns a port path into a name usable for a file or mutex.
+func portKey(name string) string {
+	return "mm010_nrc_api-" + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+
+		return '_'
+	}, name)
+}
@@ -0,0 +1,16 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd && !windows
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd,!windows
+
+package mm010_nrc_api
+
+import "io"
+
+type noLock struct{}
+
+func (noLock) Close() error {
+	return nil
+}
+
+func lockPort(name string) (io.Closer, error) {
+	return noLock{}, nil
+}
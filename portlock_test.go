@@ -0,0 +1,37 @@
+package mm010_nrc_api_test
+
+import (
+	"errors"
+	api "mm010_nrc_api"
+	"testing"
+)
+
+func TestPortLock(t *testing.T) {
+	backend := backendFunc(func(c api.PortConfig) (api.Transport, error) {
+		return newFakePort(answer(statusPayload)), nil
+	})
+
+	name := "TestPortLock"
+
+	first, err := api.NewConnection(name, api.WithSerialBackend(backend), api.WithPortLock(true))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := api.NewConnection(name, api.WithSerialBackend(backend), api.WithPortLock(true)); !errors.Is(err, api.ErrPortBusy) {
+		t.Fatalf("expected ErrPortBusy, got %v", err)
+	}
+
+	if err := first.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := api.NewConnection(name, api.WithSerialBackend(backend), api.WithPortLock(true))
+
+	if err != nil {
+		t.Fatalf("port still locked after close: %v", err)
+	}
+
+	second.Close()
+}
@@ -0,0 +1,37 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+// +build linux darwin dragonfly freebsd netbsd openbsd
+
+package mm010_nrc_api
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+func lockPort(name string) (io.Closer, error) {
+	// symlinks like /dev/serial/by-id/... lock the device they point to
+	if path, err := filepath.EvalSymlinks(name); err == nil {
+		name = path
+	}
+
+	f, err := os.OpenFile(filepath.Join(os.TempDir(), portKey(name)+".lock"), os.O_RDWR|os.O_CREATE, 0666)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+
+		if err == syscall.EWOULDBLOCK {
+			return nil, ErrPortBusy
+		}
+
+		return nil, err
+	}
+
+	// closing the file releases the lock
+	return f, nil
+}
@@ -0,0 +1,38 @@
+package mm010_nrc_api
+
+import (
+	"io"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+var procCreateMutex = syscall.NewLazyDLL("kernel32.dll").NewProc("CreateMutexW")
+
+type mutexLock syscall.Handle
+
+func (m mutexLock) Close() error {
+	return syscall.CloseHandle(syscall.Handle(m))
+}
+
+func lockPort(name string) (io.Closer, error) {
+	// COM4 and \\.\COM4 are the same port
+	id, err := syscall.UTF16PtrFromString(`Local\` + portKey(strings.ToUpper(strings.TrimPrefix(name, `\\.\`))))
+
+	if err != nil {
+		return nil, err
+	}
+
+	h, _, err := procCreateMutex.Call(0, 0, uintptr(unsafe.Pointer(id)))
+
+	if h == 0 {
+		return nil, err
+	}
+
+	if err == syscall.ERROR_ALREADY_EXISTS {
+		syscall.CloseHandle(syscall.Handle(h))
+		return nil, ErrPortBusy
+	}
+
+	return mutexLock(h), nil
+}
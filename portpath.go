@@ -0,0 +1,127 @@
+package mm010_nrc_api
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"runtime"
+	"strings"
+	"unicode"
+)
+
+var comPort = regexp.MustCompile(`^(?i)com([1-9][0-9]*)$`)
+
+// NormalizePortPath turns the ways users write a serial port into the form
+// NewConnection opens. On Windows "COM12", "com12" and "\\.\COM12" all
+// become "COM12"; elsewhere a bare "ttyUSB0" becomes "/dev/ttyUSB0". Names of
+// the other platform are left alone for custom backends. Empty names and
+// names with control characters fail with ErrInvalidPortPath.
+func NormalizePortPath(port string) (string, error) {
+	return normalizePortPath(port, runtime.GOOS)
+}
+
+func normalizePortPath(port, goos string) (string, error) {
+	p := strings.TrimSpace(port)
+
+	if p == "" {
+		return "", fmt.Errorf("%w: empty", ErrInvalidPortPath)
+	}
+
+	if strings.IndexFunc(p, unicode.IsControl) >= 0 {
+		return "", fmt.Errorf("%w: %q has control characters", ErrInvalidPortPath, port)
+	}
+
+	if goos == "windows" {
+		device := strings.TrimPrefix(strings.TrimPrefix(p, `\\.\`), `//./`)
+
+		if m := comPort.FindStringSubmatch(device); m != nil {
+			return "COM" + m[1], nil
+		}
+
+		return device, nil
+	}
+
+	if windowsPort(p) {
+		return p, nil
+	}
+
+	if strings.HasPrefix(p, "tty") || strings.HasPrefix(p, "cu.") {
+		p = "/dev/" + p
+	}
+
+	if strings.HasPrefix(p, "/") {
+		p = path.Clean(p)
+	}
+
+	return p, nil
+}
+
+func windowsPort(p string) bool {
+	return comPort.MatchString(p) || strings.HasPrefix(p, `\\.\`) || strings.HasPrefix(p, `//./`)
+}
+
+// PortLister is implemented by a SerialBackend that can enumerate the serial
+// ports of the system.
+type PortLister interface {
+	Ports() ([]string, error)
+}
+
+// PortOpenError is returned by NewConnection when the port can not be opened.
+// Available lists the serial ports found on the system, nil if they can not
+// be told.
+type PortOpenError struct {
+	Path      string
+	Available []string
+	Err       error
+}
+
+func (e *PortOpenError) Error() string {
+	msg := fmt.Sprintf("open %s: %v", e.Path, e.Err)
+
+	switch {
+	case runtime.GOOS != "windows" && windowsPort(e.Path):
+		msg += "; COM ports are Windows names, use a device like /dev/ttyUSB0"
+	case runtime.GOOS == "windows" && strings.HasPrefix(e.Path, "/"):
+		msg += "; use a Windows port name like COM3"
+	}
+
+	switch {
+	case e.Available == nil:
+	case len(e.Available) == 0:
+		msg += "; no serial ports found"
+	default:
+		msg += "; available ports: " + strings.Join(e.Available, ", ")
+	}
+
+	return msg
+}
+
+func (e *PortOpenError) Unwrap() error {
+	return e.Err
+}
+
+func (s *MMDispenser) portOpenError(err error) error {
+	if errors.Is(err, ErrPortBusy) {
+		return err
+	}
+
+	return &PortOpenError{Path: s.config.Name, Available: availablePorts(s.backend), Err: err}
+}
+
+// availablePorts asks b for the ports if it can tell, otherwise lists the
+// candidate ports that exist. The candidates on Windows are not checked, so
+// there the ports are unknown.
+func availablePorts(b SerialBackend) []string {
+	if l, ok := b.(PortLister); ok {
+		if ports, err := l.Ports(); err == nil {
+			return append([]string{}, ports...)
+		}
+	}
+
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	return append([]string{}, CandidatePorts()...)
+}
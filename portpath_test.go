@@ -0,0 +1,43 @@
+package mm010_nrc_api_test
+
+import (
+	"errors"
+	api "mm010_nrc_api"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+type listingBackend struct{}
+
+func (listingBackend) Open(c api.PortConfig) (api.Transport, error) {
+	return nil, errors.New("no such file or directory")
+}
+
+func (listingBackend) Ports() ([]string, error) {
+	return []string{"/dev/ttyS0", "/dev/ttyUSB1"}, nil
+}
+
+func TestPortPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix port names")
+	}
+
+	for in, want := range map[string]string{"ttyUSB0": "/dev/ttyUSB0", " /dev//ttyACM1 ": "/dev/ttyACM1", "COM12": "COM12"} {
+		if got, err := api.NormalizePortPath(in); err != nil || got != want {
+			t.Fatalf("%q normalized to %q, %v, want %q", in, got, err, want)
+		}
+	}
+
+	if _, err := api.NormalizePortPath(" "); !errors.Is(err, api.ErrInvalidPortPath) {
+		t.Fatalf("expected ErrInvalidPortPath, got %v", err)
+	}
+
+	_, err := api.NewConnection("ttyUSB0", api.WithSerialBackend(listingBackend{}))
+
+	var openErr *api.PortOpenError
+
+	if !errors.As(err, &openErr) || openErr.Path != "/dev/ttyUSB0" || !strings.Contains(err.Error(), "available ports: /dev/ttyS0, /dev/ttyUSB1") {
+		t.Fatalf("unexpected open error %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package protocol
+
+// ChecksumFunc computes the block check sent after TextEnd, over the frame
+// up to and including TextEnd. It must always return the same number of
+// bytes.
+type ChecksumFunc func(data []byte) []byte
+
+// LRC is the XOR block check of the MM010 and the default everywhere.
+func LRC(data []byte) []byte {
+	return []byte{Checksum(data)}
+}
+
+// CRC16 is the CRC-16/ARC block check (polynomial 0x8005 reflected, initial
+// value 0) sent high byte first, as used by related dispenser models.
+func CRC16(data []byte) []byte {
+	crc := uint16(0)
+
+	for _, b := range data {
+		crc ^= uint16(b)
+
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0xA001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+
+	return []byte{byte(crc >> 8), byte(crc)}
+}
+
+// ChecksumSize is the number of bytes sum appends to a frame.
+func ChecksumSize(sum ChecksumFunc) int {
+	return len(sum(nil))
+}
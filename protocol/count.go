@@ -0,0 +1,86 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrCountRange = errors.New("count out of range")
+
+const (
+	countOffset = 0x20
+	// countBase is the number of values one count byte can take, 0x20 to 0x7E.
+	countBase = 0x7F - countOffset
+)
+
+// EncodeCount encodes a single byte FieldCount value.
+func EncodeCount(n byte) byte {
+	return n + countOffset
+}
+
+// DecodeCount decodes a single byte FieldCount value.
+func DecodeCount(b byte) byte {
+	return b - countOffset
+}
+
+// CountEncoding is how the FieldNotes fields, the note counts, are put in
+// frames.
+type CountEncoding int
+
+const (
+	// SingleCount is one byte, the count offset by 0x20, for up to 94 notes.
+	SingleCount CountEncoding = iota
+	// WideCount is two bytes offset by 0x20, the high and the low digit of the
+	// count in base 95, used by firmware that moves more than 94 notes at once.
+	WideCount
+)
+
+func (e CountEncoding) String() string {
+	if e == WideCount {
+		return "wide"
+	}
+
+	return "single"
+}
+
+// Size is the number of bytes of an encoded count.
+func (e CountEncoding) Size() int {
+	if e == WideCount {
+		return 2
+	}
+
+	return 1
+}
+
+// Max is the largest count the encoding holds. The response types and the
+// API carry counts in a byte, so WideCount stops at 255 although its two
+// digits could go further.
+func (e CountEncoding) Max() int {
+	if e == WideCount {
+		return 0xFF
+	}
+
+	return countBase - 1
+}
+
+func (e CountEncoding) Encode(n int) ([]byte, error) {
+	if n < 0 || n > e.Max() {
+		return nil, fmt.Errorf("%w: %d not in 0..%d", ErrCountRange, n, e.Max())
+	}
+
+	if e == WideCount {
+		return []byte{byte(n/countBase) + countOffset, byte(n%countBase) + countOffset}, nil
+	}
+
+	return []byte{byte(n) + countOffset}, nil
+}
+
+// Decode decodes the count at the start of b, which holds at least Size
+// bytes.
+func (e CountEncoding) Decode(b []byte) int {
+	if e == WideCount {
+		return int(b[0]-countOffset)*countBase + int(b[1]-countOffset)
+	}
+
+	return int(b[0] - countOffset)
+}
@@ -0,0 +1,80 @@
+package protocol
+
+import "bytes"
+
+// ExtendedTextStart takes the place of TextStart in the extended frames of
+// newer firmware, whose text starts with the data length as two bytes, high
+// byte first. Unlike standard frames they may carry data of any byte value,
+// TextEnd included, up to MaxExtendedData bytes:
+// start, identify, ExtendedTextStart, command, length, data..., TextEnd, checksum.
+const ExtendedTextStart byte = 0x12
+
+const MaxExtendedData = 0xFFFF
+
+// extendedHeader is the length of an extended frame up to its data.
+const extendedHeader = 6
+
+// EncodeExtendedRequest is EncodeRequestWith for an extended frame. Data
+// longer than MaxExtendedData does not fit the length field.
+func EncodeExtendedRequest(sum ChecksumFunc, identify byte, command Command, data ...[]byte) []byte {
+	return encodeExtended(sum, RequestStart, identify, command, data...)
+}
+
+// EncodeExtendedResponse is EncodeResponseWith for an extended frame.
+func EncodeExtendedResponse(sum ChecksumFunc, identify byte, command Command, data []byte) []byte {
+	return encodeExtended(sum, ResponseStart, identify, command, data)
+}
+
+func encodeExtended(sum ChecksumFunc, start, identify byte, command Command, data ...[]byte) []byte {
+	text := bytes.Join(data, nil)
+	buf := new(bytes.Buffer)
+
+	buf.WriteByte(start)
+	buf.WriteByte(identify)
+	buf.WriteByte(ExtendedTextStart)
+	buf.WriteByte(byte(command))
+	buf.WriteByte(byte(len(text) >> 8))
+	buf.WriteByte(byte(len(text)))
+	buf.Write(text)
+	buf.WriteByte(TextEnd)
+	buf.Write(sum(buf.Bytes()))
+
+	return buf.Bytes()
+}
+
+// FrameLength returns the length of the standard or extended text frame at
+// the start of buf, or false while buf does not hold all of it yet.
+func FrameLength(sum ChecksumFunc, buf []byte) (int, bool) {
+	size := ChecksumSize(sum)
+
+	if len(buf) > 2 && buf[2] == ExtendedTextStart {
+		if len(buf) < extendedHeader {
+			return 0, false
+		}
+
+		n := extendedHeader + extendedLength(buf) + 1 + size
+
+		return n, len(buf) >= n
+	}
+
+	for i := 1; i+size < len(buf); i++ {
+		if buf[i] == TextEnd {
+			return i + 1 + size, true
+		}
+	}
+
+	return 0, false
+}
+
+func extendedLength(buf []byte) int {
+	return int(buf[4])<<8 | int(buf[5])
+}
+
+// decodeExtended returns the data of an extended frame without its checksum.
+func decodeExtended(buf []byte) ([]byte, error) {
+	if len(buf) < extendedHeader+1 || buf[len(buf)-1] != TextEnd || extendedLength(buf) != len(buf)-extendedHeader-1 {
+		return nil, ErrFrameFormat
+	}
+
+	return buf[extendedHeader : len(buf)-1], nil
+}
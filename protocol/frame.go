@@ -0,0 +1,175 @@
+package protocol
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+type FrameKind int
+
+const (
+	// ControlFrame is a single ACK, NAK or EOT byte.
+	ControlFrame FrameKind = iota
+	// RequestFrame is a host to device command frame.
+	RequestFrame
+	// ResponseFrame is a device to host response frame.
+	ResponseFrame
+)
+
+type Frame struct {
+	Kind FrameKind
+	// Control is the control byte of a ControlFrame.
+	Control  byte
+	Identify byte
+	Command  Command
+	Data     []byte
+	// Extended marks a text frame in the extended format.
+	Extended bool
+}
+
+// Direction tells a Decoder which side of the link produced the stream. It is
+// needed because EOT and RequestStart share the byte 0x04.
+type Direction int
+
+const (
+	FromDevice Direction = iota
+	FromHost
+)
+
+func Marshal(f Frame) []byte {
+	return MarshalWith(LRC, f)
+}
+
+// MarshalWith is Marshal for frames with the block check sum.
+func MarshalWith(sum ChecksumFunc, f Frame) []byte {
+	switch {
+	case f.Kind == RequestFrame && f.Extended:
+		return EncodeExtendedRequest(sum, f.Identify, f.Command, f.Data)
+	case f.Kind == ResponseFrame && f.Extended:
+		return EncodeExtendedResponse(sum, f.Identify, f.Command, f.Data)
+	case f.Kind == RequestFrame:
+		return EncodeRequestWith(sum, f.Identify, f.Command, f.Data)
+	case f.Kind == ResponseFrame:
+		return EncodeResponseWith(sum, f.Identify, f.Command, f.Data)
+	}
+
+	return []byte{f.Control}
+}
+
+// Decoder reads frames from a byte stream, accumulating partial reads until a
+// frame is complete.
+type Decoder struct {
+	r   *bufio.Reader
+	dir Direction
+	sum ChecksumFunc
+}
+
+func NewDecoder(r io.Reader, dir Direction) *Decoder {
+	return &Decoder{r: bufio.NewReader(r), dir: dir, sum: LRC}
+}
+
+// SetChecksum makes the decoder expect the block check sum instead of LRC.
+func (d *Decoder) SetChecksum(sum ChecksumFunc) {
+	d.sum = sum
+}
+
+// Decode returns the next frame. A frame with a bad block check is returned
+// together with ErrFrameChecksum, and a byte that can not start a frame with
+// ErrFrameFormat; in both cases the offending bytes are consumed so decoding
+// can continue with the next frame.
+func (d *Decoder) Decode() (Frame, error) {
+	b, err := d.r.ReadByte()
+
+	if err != nil {
+		return Frame{}, err
+	}
+
+	switch {
+	case b == Ack || b == Nack:
+		return Frame{Kind: ControlFrame, Control: b}, nil
+	case b == Eot && d.dir == FromDevice:
+		return Frame{Kind: ControlFrame, Control: b}, nil
+	case b == RequestStart && d.dir == FromHost:
+		return d.text(RequestFrame, b)
+	case b == ResponseStart && d.dir == FromDevice:
+		return d.text(ResponseFrame, b)
+	}
+
+	return Frame{Kind: ControlFrame, Control: b}, ErrFrameFormat
+}
+
+func (d *Decoder) text(kind FrameKind, start byte) (Frame, error) {
+	head := make([]byte, 3)
+
+	if _, err := io.ReadFull(d.r, head); err != nil {
+		return Frame{}, noEOF(err)
+	}
+
+	f := Frame{Kind: kind, Identify: head[0], Command: Command(head[2])}
+
+	var body []byte
+	var err error
+
+	switch head[1] {
+	case TextStart:
+		body, err = d.r.ReadBytes(TextEnd)
+	case ExtendedTextStart:
+		f.Extended = true
+		body, err = d.extendedBody()
+	default:
+		return f, ErrFrameFormat
+	}
+
+	if err != nil {
+		return f, noEOF(err)
+	}
+
+	crc := make([]byte, ChecksumSize(d.sum))
+
+	if _, err := io.ReadFull(d.r, crc); err != nil {
+		return f, noEOF(err)
+	}
+
+	f.Data = body[:len(body)-1]
+
+	if f.Extended {
+		f.Data = f.Data[2:]
+	}
+
+	raw := append(append([]byte{start}, head...), body...)
+
+	switch {
+	case !bytes.Equal(d.sum(raw), crc):
+		return f, ErrFrameChecksum
+	case body[len(body)-1] != TextEnd:
+		return f, ErrFrameFormat
+	}
+
+	return f, nil
+}
+
+// extendedBody reads the length field, data and TextEnd of an extended frame.
+func (d *Decoder) extendedBody() ([]byte, error) {
+	body := make([]byte, 2)
+
+	if _, err := io.ReadFull(d.r, body); err != nil {
+		return nil, err
+	}
+
+	body = append(body, make([]byte, int(body[0])<<8|int(body[1])+1)...)
+
+	if _, err := io.ReadFull(d.r, body[2:]); err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
@@ -0,0 +1,143 @@
+// Command schemagen writes schema_gen.go of package protocol from the command
+// registry: a response type for every schema and the decoders DecodeText
+// dispatches to. Run go generate in protocol after changing the registry.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"reflect"
+	"strings"
+
+	"mm010_nrc_api/protocol"
+)
+
+// kinds maps the field kinds of a response to their Go type and the read of
+// the fields reader in schema.go.
+var kinds = map[protocol.FieldKind]struct{ typ, read string }{
+	protocol.FieldCount:  {"byte", "f.count()"},
+	protocol.FieldStatus: {"StatusCode", "f.status()"},
+	protocol.FieldFlags:  {"byte", "f.byte()"},
+	protocol.FieldResult: {"byte", "f.byte()"},
+	protocol.FieldNotes:  {"byte", "f.notes()"},
+	protocol.FieldASCII:  {"string", "f.rest()"},
+}
+
+type schema struct {
+	name     string
+	fields   []protocol.Field
+	commands []string
+}
+
+func main() {
+	out := flag.String("o", "schema_gen.go", "output file")
+	flag.Parse()
+
+	src, err := generate()
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err = ioutil.WriteFile(*out, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func schemas() ([]*schema, error) {
+	var res []*schema
+	byName := map[string]*schema{}
+
+	for _, c := range protocol.Commands() {
+		if c.Schema == "" {
+			if len(c.Response) > 0 {
+				return nil, fmt.Errorf("command %s has a response but no schema", c.Name)
+			}
+
+			continue
+		}
+
+		s, ok := byName[c.Schema]
+
+		if !ok {
+			s = &schema{name: c.Schema, fields: c.Response}
+			byName[c.Schema] = s
+			res = append(res, s)
+		}
+
+		if !reflect.DeepEqual(s.fields, c.Response) {
+			return nil, fmt.Errorf("command %s differs from schema %s", c.Name, c.Schema)
+		}
+
+		s.commands = append(s.commands, c.Name)
+	}
+
+	return res, nil
+}
+
+func generate() ([]byte, error) {
+	list, err := schemas()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "// Code generated by schemagen. DO NOT EDIT.\n\npackage protocol\n")
+
+	for _, s := range list {
+		var reads []string
+
+		fmt.Fprintf(&b, "\n// %sResponse is the response of %s.\ntype %[1]sResponse struct {\n", s.name, enumerate(s.commands))
+
+		for _, f := range s.fields {
+			k, ok := kinds[f.Kind]
+
+			if !ok {
+				return nil, fmt.Errorf("schema %s: no Go type for field %s of kind %v", s.name, f.Name, f.Kind)
+			}
+
+			fmt.Fprintf(&b, "\t%s %s\n", camel(f.Name), k.typ)
+			reads = append(reads, k.read)
+		}
+
+		fmt.Fprintf(&b, "}\n\nfunc decode%s(f *fields) interface{} {\n\treturn %[1]sResponse{%s}\n}\n", s.name, strings.Join(reads, ", "))
+	}
+
+	fmt.Fprintf(&b, "\n// schemas maps commands to the decoders of their response text.\nvar schemas = map[Command]func(f *fields) interface{}{\n")
+
+	for _, s := range list {
+		for _, c := range s.commands {
+			fmt.Fprintf(&b, "\tCommand%s: decode%s,\n", c, s.name)
+		}
+	}
+
+	fmt.Fprintf(&b, "}\n")
+
+	return format.Source(b.Bytes())
+}
+
+// camel turns a registry field name like average_thickness into
+// AverageThickness.
+func camel(name string) string {
+	parts := strings.Split(name, "_")
+
+	for i, p := range parts {
+		parts[i] = strings.ToUpper(p[:1]) + p[1:]
+	}
+
+	return strings.Join(parts, "")
+}
+
+func enumerate(names []string) string {
+	if len(names) == 1 {
+		return names[0]
+	}
+
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
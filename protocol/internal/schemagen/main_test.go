@@ -0,0 +1,25 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestSchemaUpToDate(t *testing.T) {
+	src, err := generate()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	current, err := ioutil.ReadFile("../../schema_gen.go")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(src, current) {
+		t.Fatal("schema_gen.go is out of date with the registry, run go generate in protocol")
+	}
+}
@@ -0,0 +1,200 @@
+// Package protocol holds the MM010 NRC wire constants and frame codec. It has
+// no serial or transport dependencies so it can be shared by analysis tools.
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+const (
+	RequestStart          byte = 0x04
+	ResponseStart         byte = 0x01
+	CommunicationIdentify byte = 0x30
+	TextStart             byte = 0x02
+	TextEnd               byte = 0x03
+	Ack                   byte = 0x06
+	Nack                  byte = 0x15
+	Eot                   byte = 0x04
+)
+
+type Command byte
+
+const (
+	CommandStatus                  Command = 0x40
+	CommandPurge                   Command = 0x41
+	CommandDispense                Command = 0x42
+	CommandTestDispense            Command = 0x43
+	CommandReset                   Command = 0x44
+	CommandLastStatus              Command = 0x45
+	CommandConfigurationStatus     Command = 0x46
+	CommandDoubleDetectDiagnostics Command = 0x47
+	CommandSensorDiagnostics       Command = 0x48
+	CommandSingleNoteDispense      Command = 0x4A
+	CommandSingleNoteEject         Command = 0x4B
+	CommandReadData                Command = 0x52
+	CommandTestMode                Command = 0x54
+	CommandWriteData               Command = 0x57
+)
+
+type ResponseType byte
+
+const (
+	ErrorResponse ResponseType = 0x00
+	AckResponse   ResponseType = ResponseType(Ack)
+	NackResponse  ResponseType = ResponseType(Nack)
+	EotResponse   ResponseType = ResponseType(Eot)
+)
+
+type StatusCode byte
+
+const (
+	GoodOperation        StatusCode = 0x20
+	FeedFailure          StatusCode = 0x21
+	MistrackedNoteAtExit StatusCode = 0x24
+	TooLongAtExit        StatusCode = 0x25
+	BlockedExit          StatusCode = 0x26
+	TransportError       StatusCode = 0x2A
+	DoubleDetectError    StatusCode = 0x2C
+	DivertedError        StatusCode = 0x2D
+	WrongCount           StatusCode = 0x2E
+	NoteMissingAtDD      StatusCode = 0x2F
+	RejectRateExceeded   StatusCode = 0x30
+	NonVolatileRAMError  StatusCode = 0x34
+	OperationTimeout     StatusCode = 0x36
+	InternalQueError     StatusCode = 0x37
+	InvalidCommand       StatusCode = 0x4F
+)
+
+type DataItem uint16
+
+const (
+	ProgramID                        DataItem = 100
+	MachineID                        DataItem = 101
+	MaxNumberOfNotesInOneTransaction DataItem = 104
+	Baudrate                         DataItem = 115
+	Parity                           DataItem = 116
+	DispenseCounterLifelong          DataItem = 303
+	RejectCounterLifelong            DataItem = 304
+	TotalProcessedCounterLifelong    DataItem = 305
+	DispenseCounterTrip              DataItem = 306
+	RejectCounterTrip                DataItem = 307
+	TotalProcessedCcounterTrip       DataItem = 308
+	TransactionCounterLifelong       DataItem = 313
+	TransactionCounterTrip           DataItem = 314
+	ThroatSensorCalibrationValue     DataItem = 350
+	LearningNotes                    DataItem = 392
+	RejectReasonCounter              DataItem = 501
+	ErrorStatusCounter               DataItem = 502
+	MachineStatus                    DataItem = 503
+)
+
+var (
+	ErrFrameFormat   = errors.New("Response format invalid")
+	ErrFrameChecksum = errors.New("Response verification failed")
+)
+
+// IdentityError is returned for a well-formed response carrying another
+// CommunicationIdentify than the one the unit is configured with.
+type IdentityError struct {
+	Expected byte
+	Received byte
+}
+
+func (e *IdentityError) Error() string {
+	return fmt.Sprintf("response identity %#02x, expected %#02x", e.Received, e.Expected)
+}
+
+func Checksum(data []byte) byte {
+	chksum := byte(0)
+
+	for _, b := range data {
+		chksum = chksum ^ b
+	}
+
+	return chksum
+}
+
+// EncodeRequest builds a host request frame:
+// RequestStart, identify, TextStart, command, data..., TextEnd, checksum.
+func EncodeRequest(identify byte, command Command, data ...[]byte) []byte {
+	return EncodeRequestWith(LRC, identify, command, data...)
+}
+
+// EncodeRequestWith is EncodeRequest with the block check sum.
+func EncodeRequestWith(sum ChecksumFunc, identify byte, command Command, data ...[]byte) []byte {
+	return encode(sum, RequestStart, identify, command, data...)
+}
+
+// EncodeResponse builds a device response frame, the counterpart of
+// DecodeResponse. It is mainly useful for simulators and test vectors.
+func EncodeResponse(identify byte, command Command, data []byte) []byte {
+	return EncodeResponseWith(LRC, identify, command, data)
+}
+
+func EncodeResponseWith(sum ChecksumFunc, identify byte, command Command, data []byte) []byte {
+	return encode(sum, ResponseStart, identify, command, data)
+}
+
+func encode(sum ChecksumFunc, start, identify byte, command Command, data ...[]byte) []byte {
+	buf := new(bytes.Buffer)
+
+	buf.WriteByte(start)
+	buf.WriteByte(identify)
+	buf.WriteByte(TextStart)
+	buf.WriteByte(byte(command))
+
+	for _, d := range data {
+		buf.Write(d)
+	}
+
+	buf.WriteByte(TextEnd)
+	buf.Write(sum(buf.Bytes()))
+
+	return buf.Bytes()
+}
+
+// ResponseComplete reports whether buf ends with TextEnd followed by the checksum byte.
+func ResponseComplete(buf []byte) bool {
+	return len(buf) > 2 && buf[len(buf)-2] == TextEnd
+}
+
+// DecodeResponse validates a complete device response frame and returns its
+// payload, i.e. the text between the echoed command byte and TextEnd, or the
+// data of an extended frame.
+func DecodeResponse(identify byte, frame []byte) ([]byte, error) {
+	return DecodeResponseWith(LRC, identify, frame)
+}
+
+// DecodeResponseWith is DecodeResponse for frames with the block check sum.
+func DecodeResponseWith(sum ChecksumFunc, identify byte, frame []byte) ([]byte, error) {
+	size := ChecksumSize(sum)
+
+	if len(frame) < 2+size || frame[0] != ResponseStart {
+		return nil, ErrFrameFormat
+	}
+
+	crc := frame[len(frame)-size:]
+	buf := frame[:len(frame)-size]
+
+	if !bytes.Equal(crc, sum(buf)) {
+		return nil, ErrFrameChecksum
+	}
+
+	// checked after the checksum so a corrupted frame isn't taken for
+	// another unit's
+	if buf[1] != identify {
+		return nil, &IdentityError{Expected: identify, Received: buf[1]}
+	}
+
+	if len(buf) > 2 && buf[2] == ExtendedTextStart {
+		return decodeExtended(buf)
+	}
+
+	if len(buf) < 5 || buf[2] != TextStart || buf[len(buf)-1] != TextEnd {
+		return nil, ErrFrameFormat
+	}
+
+	return buf[4 : len(buf)-1], nil
+}
@@ -0,0 +1,379 @@
+package protocol_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mm010_nrc_api/protocol"
+	"testing"
+)
+
+type vectors struct {
+	Requests []struct {
+		Name    string
+		Command byte
+		Data    string
+		Frame   string
+	}
+	Responses []struct {
+		Name    string
+		Frame   string
+		Payload string
+		Error   string
+	}
+}
+
+func loadVectors(t *testing.T) vectors {
+	raw, err := ioutil.ReadFile("testdata/vectors.json")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var v vectors
+
+	if err := json.Unmarshal(raw, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	return v
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b
+}
+
+func TestEncodeRequestVectors(t *testing.T) {
+	for _, v := range loadVectors(t).Requests {
+		frame := protocol.EncodeRequest(protocol.CommunicationIdentify, protocol.Command(v.Command), mustHex(t, v.Data))
+
+		if !bytes.Equal(frame, mustHex(t, v.Frame)) {
+			t.Errorf("%s: got %X, want %s", v.Name, frame, v.Frame)
+		}
+	}
+}
+
+func TestDecodeResponseVectors(t *testing.T) {
+	errs := map[string]error{
+		"checksum": protocol.ErrFrameChecksum,
+		"format":   protocol.ErrFrameFormat,
+	}
+
+	for _, v := range loadVectors(t).Responses {
+		payload, err := protocol.DecodeResponse(protocol.CommunicationIdentify, mustHex(t, v.Frame))
+
+		if v.Error != "" {
+			if err != errs[v.Error] {
+				t.Errorf("%s: got error %v, want %v", v.Name, err, errs[v.Error])
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: %v", v.Name, err)
+			continue
+		}
+
+		if !bytes.Equal(payload, mustHex(t, v.Payload)) {
+			t.Errorf("%s: got payload %X, want %s", v.Name, payload, v.Payload)
+		}
+	}
+}
+
+func TestCRC16(t *testing.T) {
+	if sum := protocol.CRC16([]byte("123456789")); !bytes.Equal(sum, []byte{0xBB, 0x3D}) {
+		t.Fatalf("got %X, want BB3D", sum)
+	}
+
+	frame := protocol.EncodeResponseWith(protocol.CRC16, protocol.CommunicationIdentify, protocol.CommandStatus, []byte{0x30})
+
+	payload, err := protocol.DecodeResponseWith(protocol.CRC16, protocol.CommunicationIdentify, frame)
+
+	if err != nil || !bytes.Equal(payload, []byte{0x30}) {
+		t.Fatalf("got %X, %v", payload, err)
+	}
+
+	if _, err := protocol.DecodeResponse(protocol.CommunicationIdentify, frame); err == nil {
+		t.Fatal("CRC frame decoded with LRC")
+	}
+}
+
+func TestDecodeResponseIdentity(t *testing.T) {
+	frame := protocol.EncodeResponse(0x31, protocol.CommandStatus, []byte{0x30})
+
+	if payload, err := protocol.DecodeResponse(0x31, frame); err != nil || !bytes.Equal(payload, []byte{0x30}) {
+		t.Fatalf("got %X, %v", payload, err)
+	}
+
+	_, err := protocol.DecodeResponse(protocol.CommunicationIdentify, frame)
+
+	if e, ok := err.(*protocol.IdentityError); !ok || e.Expected != 0x30 || e.Received != 0x31 {
+		t.Fatalf("expected an identity error, got %v", err)
+	}
+
+	frame[len(frame)-1]++
+
+	if _, err := protocol.DecodeResponse(protocol.CommunicationIdentify, frame); err != protocol.ErrFrameChecksum {
+		t.Fatalf("expected a corrupted frame to fail the checksum first, got %v", err)
+	}
+}
+
+func TestRegistryLookup(t *testing.T) {
+	seen := map[protocol.Command]bool{}
+
+	for _, c := range protocol.Commands() {
+		if seen[c.Code] {
+			t.Errorf("duplicate command 0x%02X", byte(c.Code))
+		}
+		seen[c.Code] = true
+
+		if c.Code.String() != c.Name {
+			t.Errorf("command 0x%02X: String() = %q, want %q", byte(c.Code), c.Code.String(), c.Name)
+		}
+	}
+
+	if s := protocol.DataItem(999).String(); s != "DataItem(999)" {
+		t.Errorf("unknown data item formatted as %q", s)
+	}
+
+	// the registry can not be changed through what it returns
+	protocol.Commands()[0].Response[0].Name = "changed"
+
+	if info, _ := protocol.LookupCommand(protocol.Commands()[0].Code); info.Response[0].Name == "changed" {
+		t.Error("Commands shares the response fields with the registry")
+	}
+}
+
+func TestDecodeText(t *testing.T) {
+	v, err := protocol.DecodeText(protocol.CommandDispense, []byte{0x20, 0x25, 0x21})
+
+	if err != nil || v != (protocol.DispenseResponse{Status: protocol.GoodOperation, Dispensed: 5, Rejected: 1}) {
+		t.Fatalf("got %+v, %v", v, err)
+	}
+
+	v, err = protocol.DecodeText(protocol.CommandReadData, []byte("0MM010"))
+
+	if r, ok := v.(protocol.DataResponse); err != nil || !ok || !r.OK() || r.Value != "MM010" {
+		t.Fatalf("got %+v, %v", v, err)
+	}
+
+	if _, err := protocol.DecodeText(protocol.CommandStatus, []byte{0x20, 0x20}); err != protocol.ErrFrameFormat {
+		t.Fatalf("short status: got %v", err)
+	}
+
+	if _, err := protocol.DecodeText(protocol.Command(0x7F), nil); err != protocol.ErrNoSchema {
+		t.Fatalf("unknown command: got %v", err)
+	}
+}
+
+func TestCountEncoding(t *testing.T) {
+	for _, n := range []int{0, 94, 95, 200, protocol.WideCount.Max()} {
+		b, err := protocol.WideCount.Encode(n)
+
+		if err != nil || len(b) != 2 || b[0] < 0x20 || b[0] > 0x7E || b[1] < 0x20 || b[1] > 0x7E || protocol.WideCount.Decode(b) != n {
+			t.Fatalf("%d encoded as % X, %v", n, b, err)
+		}
+	}
+
+	if max := protocol.WideCount.Max(); max != 0xFF {
+		t.Fatalf("expected wide counts capped at 255, got %d", max)
+	}
+
+	if _, err := protocol.WideCount.Encode(0x100); !errors.Is(err, protocol.ErrCountRange) {
+		t.Fatalf("expected ErrCountRange for 256 notes, got %v", err)
+	}
+
+	if b, err := protocol.SingleCount.Encode(94); err != nil || !bytes.Equal(b, []byte{0x7E}) {
+		t.Fatalf("94 encoded as % X, %v", b, err)
+	}
+
+	if _, err := protocol.SingleCount.Encode(95); !errors.Is(err, protocol.ErrCountRange) {
+		t.Fatalf("expected ErrCountRange, got %v", err)
+	}
+
+	v, err := protocol.DecodeTextWith(protocol.WideCount, protocol.CommandDispense, []byte{0x20, 0x21, 0x39, 0x20, 0x22})
+
+	if err != nil || v != (protocol.DispenseResponse{Status: protocol.GoodOperation, Dispensed: 120, Rejected: 2}) {
+		t.Fatalf("got %+v, %v", v, err)
+	}
+
+	if _, err := protocol.DecodeTextWith(protocol.WideCount, protocol.CommandDispense, []byte{0x20, 0x21, 0x39}); err != protocol.ErrFrameFormat {
+		t.Fatalf("short wide dispense: got %v", err)
+	}
+}
+
+func TestStatusCodeString(t *testing.T) {
+	if s := protocol.FeedFailure.String(); s != "feed failure" {
+		t.Errorf("FeedFailure formatted as %q", s)
+	}
+
+	if s := protocol.StatusCode(0x7F).String(); s != "StatusCode(0x7F)" {
+		t.Errorf("unknown status formatted as %q", s)
+	}
+
+	if protocol.GoodOperation.IsError() || !protocol.WrongCount.IsError() {
+		t.Error("IsError misclassifies status codes")
+	}
+}
+
+func TestStatusCodeText(t *testing.T) {
+	out, err := json.Marshal([]protocol.StatusCode{protocol.WrongCount, 0x7F})
+
+	if err != nil || string(out) != `["wrong_count","0x7F"]` {
+		t.Fatalf("marshaled as %s, %v", out, err)
+	}
+
+	var codes []protocol.StatusCode
+
+	if err := json.Unmarshal(out, &codes); err != nil || codes[0] != protocol.WrongCount || codes[1] != 0x7F {
+		t.Fatalf("unmarshaled as %v, %v", codes, err)
+	}
+
+	var c protocol.StatusCode
+
+	if err := c.UnmarshalText([]byte("bogus")); err == nil {
+		t.Error("accepted an unknown name")
+	}
+}
+
+// chunkReader hands out one byte per Read to exercise partial reads.
+type chunkReader struct {
+	data []byte
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+
+	p[0] = r.data[0]
+	r.data = r.data[1:]
+
+	return 1, nil
+}
+
+func TestDecoderStream(t *testing.T) {
+	response := protocol.Frame{Kind: protocol.ResponseFrame, Identify: protocol.CommunicationIdentify,
+		Command: protocol.CommandDispense, Data: []byte{0x20, 0x21, 0x20}}
+
+	var stream []byte
+	stream = append(stream, protocol.Ack)
+	stream = append(stream, protocol.Marshal(response)...)
+	stream = append(stream, protocol.Eot)
+
+	dec := protocol.NewDecoder(&chunkReader{data: stream}, protocol.FromDevice)
+
+	if f, err := dec.Decode(); err != nil || f.Kind != protocol.ControlFrame || f.Control != protocol.Ack {
+		t.Fatalf("expected ACK, got %+v %v", f, err)
+	}
+
+	f, err := dec.Decode()
+
+	if err != nil || f.Kind != protocol.ResponseFrame || f.Command != protocol.CommandDispense || !bytes.Equal(f.Data, response.Data) {
+		t.Fatalf("unexpected response frame %+v %v", f, err)
+	}
+
+	if f, err := dec.Decode(); err != nil || f.Control != protocol.Eot {
+		t.Fatalf("expected EOT, got %+v %v", f, err)
+	}
+
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Fatalf("expected io.EOF at the end of the stream, got %v", err)
+	}
+}
+
+func TestDecoderRequestsAndChecksum(t *testing.T) {
+	good := protocol.EncodeRequest(protocol.CommunicationIdentify, protocol.CommandReadData, []byte("D/101"))
+	bad := protocol.EncodeRequest(protocol.CommunicationIdentify, protocol.CommandStatus)
+	bad[len(bad)-1] ^= 0xFF
+
+	dec := protocol.NewDecoder(bytes.NewReader(append(bad, good...)), protocol.FromHost)
+
+	if _, err := dec.Decode(); err != protocol.ErrFrameChecksum {
+		t.Fatalf("expected checksum error, got %v", err)
+	}
+
+	f, err := dec.Decode()
+
+	if err != nil || f.Kind != protocol.RequestFrame || string(f.Data) != "D/101" {
+		t.Fatalf("unexpected request frame %+v %v", f, err)
+	}
+}
+
+func TestExtendedFrames(t *testing.T) {
+	data := []byte{0x30, protocol.TextEnd, 0x00, protocol.TextEnd}
+	frame := protocol.EncodeExtendedResponse(protocol.LRC, protocol.CommunicationIdentify, protocol.CommandReadData, data)
+
+	if n, ok := protocol.FrameLength(protocol.LRC, frame[:len(frame)-1]); ok {
+		t.Fatalf("incomplete frame reported as %d bytes", n)
+	}
+
+	if n, ok := protocol.FrameLength(protocol.LRC, append(frame, protocol.Eot)); !ok || n != len(frame) {
+		t.Fatalf("got frame length %d %v, want %d", n, ok, len(frame))
+	}
+
+	payload, err := protocol.DecodeResponse(protocol.CommunicationIdentify, frame)
+
+	if err != nil || !bytes.Equal(payload, data) {
+		t.Fatalf("got %X, %v", payload, err)
+	}
+
+	f, err := protocol.NewDecoder(&chunkReader{data: frame}, protocol.FromDevice).Decode()
+
+	if err != nil || !f.Extended || !bytes.Equal(f.Data, data) || !bytes.Equal(protocol.Marshal(f), frame) {
+		t.Fatalf("unexpected frame %+v %v", f, err)
+	}
+}
+
+func FuzzDecodeResponse(f *testing.F) {
+	f.Add(protocol.EncodeResponse(protocol.CommunicationIdentify, protocol.CommandStatus, []byte{0x20, 0x20, 0x25, 0x27}))
+	f.Add([]byte{protocol.ResponseStart, protocol.CommunicationIdentify})
+	f.Add([]byte{protocol.ResponseStart, protocol.CommunicationIdentify, protocol.TextStart, protocol.TextEnd})
+	f.Add(protocol.EncodeExtendedResponse(protocol.LRC, protocol.CommunicationIdentify, protocol.CommandReadData, []byte{0x30, protocol.TextEnd}))
+
+	f.Fuzz(func(t *testing.T, frame []byte) {
+		data, err := protocol.DecodeResponse(protocol.CommunicationIdentify, frame)
+
+		if err == nil && len(data) > len(frame) {
+			t.Fatalf("decoded %X from %X", data, frame)
+		}
+	})
+}
+
+func FuzzDecoder(f *testing.F) {
+	f.Add(append([]byte{protocol.Ack}, protocol.EncodeResponse(protocol.CommunicationIdentify, protocol.CommandPurge, []byte{0x20, 0x21})...))
+	f.Add([]byte{protocol.ResponseStart, 0x30, 0x02, 0x40, 0x03})
+	f.Add([]byte{protocol.Eot, 0xFF, protocol.ResponseStart})
+
+	f.Fuzz(func(t *testing.T, stream []byte) {
+		dec := protocol.NewDecoder(bytes.NewReader(stream), protocol.FromDevice)
+
+		for i := 0; i <= len(stream); i++ {
+			frame, err := dec.Decode()
+
+			switch err {
+			case nil:
+			case protocol.ErrFrameChecksum, protocol.ErrFrameFormat:
+				continue
+			default:
+				return
+			}
+
+			// a frame that decoded cleanly must survive a round trip
+			again, err := protocol.NewDecoder(bytes.NewReader(protocol.Marshal(frame)), protocol.FromDevice).Decode()
+
+			if err != nil || again.Kind != frame.Kind || again.Command != frame.Command || !bytes.Equal(again.Data, frame.Data) {
+				t.Fatalf("%+v marshaled and decoded as %+v, %v", frame, again, err)
+			}
+		}
+	})
+}
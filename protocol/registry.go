@@ -0,0 +1,255 @@
+package protocol
+
+import "fmt"
+
+type FieldKind int
+
+const (
+	// FieldCount is a single byte number offset by 0x20.
+	FieldCount FieldKind = iota
+	// FieldStatus is a StatusCode byte.
+	FieldStatus
+	// FieldFlags is a bit field byte.
+	FieldFlags
+	// FieldResult is the ReadData/WriteData result byte, 0x30 on success.
+	FieldResult
+	// FieldDataItem is a "D/nnn" data item reference.
+	FieldDataItem
+	// FieldASCII is free text running to the end of the frame.
+	FieldASCII
+	// FieldNotes is a note count in the CountEncoding of the device.
+	FieldNotes
+)
+
+var fieldKindNames = map[FieldKind]string{
+	FieldCount:    "count",
+	FieldStatus:   "status",
+	FieldFlags:    "flags",
+	FieldResult:   "result",
+	FieldDataItem: "data-item",
+	FieldASCII:    "ascii",
+	FieldNotes:    "notes",
+}
+
+func (k FieldKind) String() string {
+	if name, ok := fieldKindNames[k]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("FieldKind(%d)", int(k))
+}
+
+type Field struct {
+	Name string
+	Kind FieldKind
+}
+
+type CommandInfo struct {
+	Name     string
+	Code     Command
+	Params   []Field
+	Response []Field
+	// Schema names the response type <Schema>Response generated from
+	// Response, see schema_gen.go. Commands sharing a schema have the same
+	// response layout.
+	Schema string
+}
+
+// MinResponseLength is the number of bytes a well-formed response text has
+// at least: one per field, none for the variable length ones.
+func (c CommandInfo) MinResponseLength() int {
+	return c.MinResponseLengthWith(SingleCount)
+}
+
+// MinResponseLengthWith is MinResponseLength with the note counts in enc.
+func (c CommandInfo) MinResponseLengthWith(enc CountEncoding) int {
+	n := 0
+
+	for _, f := range c.Response {
+		switch f.Kind {
+		case FieldDataItem, FieldASCII:
+		case FieldNotes:
+			n += enc.Size()
+		default:
+			n++
+		}
+	}
+
+	return n
+}
+
+// clone copies c, so the registry can not be changed through the result.
+func (c CommandInfo) clone() CommandInfo {
+	c.Params = append([]Field(nil), c.Params...)
+	c.Response = append([]Field(nil), c.Response...)
+
+	return c
+}
+
+type Access int
+
+const (
+	ReadOnly Access = iota
+	ReadWrite
+)
+
+func (a Access) String() string {
+	if a == ReadWrite {
+		return "read-write"
+	}
+
+	return "read-only"
+}
+
+type ValueType int
+
+const (
+	ValueNumber ValueType = iota
+	ValueText
+)
+
+func (t ValueType) String() string {
+	if t == ValueText {
+		return "text"
+	}
+
+	return "number"
+}
+
+type DataItemInfo struct {
+	Name   string
+	Item   DataItem
+	Access Access
+	Type   ValueType
+	// Param is set for items read per sub-code, e.g. one counter per reject reason.
+	Param bool
+	// Values, if set, lists the only values a write may set.
+	Values []string
+	// Max bounds a written number if not 0.
+	Max uint64
+}
+
+func (d DataItemInfo) clone() DataItemInfo {
+	d.Values = append([]string(nil), d.Values...)
+
+	return d
+}
+
+var (
+	dispenseResponse    = []Field{{"status", FieldStatus}, {"dispensed", FieldNotes}, {"rejected", FieldNotes}}
+	diagnosticsResponse = []Field{{"status", FieldStatus}, {"value_1", FieldCount}, {"value_2", FieldCount}}
+	dataResponse        = []Field{{"result", FieldResult}, {"value", FieldASCII}}
+)
+
+var commands = []CommandInfo{
+	{Name: "Status", Code: CommandStatus, Schema: "Status", Response: []Field{
+		{"sensors", FieldFlags}, {"flags", FieldFlags}, {"average_thickness", FieldCount}, {"average_length", FieldCount}}},
+	{Name: "Purge", Code: CommandPurge, Schema: "Purge", Response: []Field{{"status", FieldStatus}, {"purged", FieldNotes}}},
+	{Name: "Dispense", Code: CommandDispense, Schema: "Dispense", Params: []Field{{"count", FieldNotes}}, Response: dispenseResponse},
+	{Name: "TestDispense", Code: CommandTestDispense, Schema: "Dispense", Params: []Field{{"count", FieldNotes}}, Response: dispenseResponse},
+	{Name: "Reset", Code: CommandReset},
+	{Name: "LastStatus", Code: CommandLastStatus, Schema: "Dispense", Response: dispenseResponse},
+	{Name: "ConfigurationStatus", Code: CommandConfigurationStatus, Schema: "Configuration", Response: []Field{
+		{"configuration_1", FieldCount}, {"configuration_2", FieldCount}}},
+	{Name: "DoubleDetectDiagnostics", Code: CommandDoubleDetectDiagnostics, Schema: "Diagnostics", Response: diagnosticsResponse},
+	{Name: "SensorDiagnostics", Code: CommandSensorDiagnostics, Schema: "Diagnostics", Response: diagnosticsResponse},
+	{Name: "SingleNoteDispense", Code: CommandSingleNoteDispense, Schema: "Dispense", Response: dispenseResponse},
+	{Name: "SingleNoteEject", Code: CommandSingleNoteEject, Schema: "Dispense", Response: dispenseResponse},
+	{Name: "ReadData", Code: CommandReadData, Schema: "Data", Params: []Field{{"item", FieldDataItem}, {"param", FieldASCII}},
+		Response: dataResponse},
+	{Name: "TestMode", Code: CommandTestMode, Schema: "TestMode", Response: []Field{{"status", FieldStatus}}},
+	{Name: "WriteData", Code: CommandWriteData, Schema: "Data", Params: []Field{{"item", FieldDataItem}, {"value", FieldASCII}},
+		Response: dataResponse},
+}
+
+// maxCountByte is the largest number a byte offset by 0x20 holds.
+const maxCountByte = 0xFF - countOffset
+
+// dataItems are the items of the original DataItem constants. Access and
+// Type follow from their names and from the procedures using them: lifelong
+// counters, identification and status are read-only, trip counters can be
+// reset, and the settings (SetBaudrate, SetParity, the learning note count,
+// the throat calibration value and the DispenseNotes limit) are writable. Only Baudrate
+// and Parity have known values, the rates of Baud and the codes SetParity
+// writes. No source gives units or ranges; the note count settings are only
+// bounded by what a count byte, the value offset by 0x20, can hold.
+var dataItems = []DataItemInfo{
+	{Name: "ProgramID", Item: ProgramID, Access: ReadOnly, Type: ValueText},
+	{Name: "MachineID", Item: MachineID, Access: ReadOnly, Type: ValueText},
+	{Name: "MaxNumberOfNotesInOneTransaction", Item: MaxNumberOfNotesInOneTransaction, Access: ReadWrite, Type: ValueNumber, Max: maxCountByte},
+	{Name: "Baudrate", Item: Baudrate, Access: ReadWrite, Type: ValueNumber, Values: []string{"1200", "2400", "4800", "9600"}},
+	{Name: "Parity", Item: Parity, Access: ReadWrite, Type: ValueNumber, Values: []string{"0", "1", "2"}},
+	{Name: "DispenseCounterLifelong", Item: DispenseCounterLifelong, Access: ReadOnly, Type: ValueNumber},
+	{Name: "RejectCounterLifelong", Item: RejectCounterLifelong, Access: ReadOnly, Type: ValueNumber},
+	{Name: "TotalProcessedCounterLifelong", Item: TotalProcessedCounterLifelong, Access: ReadOnly, Type: ValueNumber},
+	{Name: "DispenseCounterTrip", Item: DispenseCounterTrip, Access: ReadWrite, Type: ValueNumber},
+	{Name: "RejectCounterTrip", Item: RejectCounterTrip, Access: ReadWrite, Type: ValueNumber},
+	{Name: "TotalProcessedCounterTrip", Item: TotalProcessedCcounterTrip, Access: ReadWrite, Type: ValueNumber},
+	{Name: "TransactionCounterLifelong", Item: TransactionCounterLifelong, Access: ReadOnly, Type: ValueNumber},
+	{Name: "TransactionCounterTrip", Item: TransactionCounterTrip, Access: ReadWrite, Type: ValueNumber},
+	{Name: "ThroatSensorCalibrationValue", Item: ThroatSensorCalibrationValue, Access: ReadWrite, Type: ValueNumber},
+	{Name: "LearningNotes", Item: LearningNotes, Access: ReadWrite, Type: ValueNumber, Max: maxCountByte},
+	{Name: "RejectReasonCounter", Item: RejectReasonCounter, Access: ReadOnly, Type: ValueNumber, Param: true},
+	{Name: "ErrorStatusCounter", Item: ErrorStatusCounter, Access: ReadOnly, Type: ValueNumber, Param: true},
+	{Name: "MachineStatus", Item: MachineStatus, Access: ReadOnly, Type: ValueText},
+}
+
+// Commands returns the command registry in code order. The slice, and the
+// fields of every entry, are a copy and may be modified by the caller.
+func Commands() []CommandInfo {
+	infos := make([]CommandInfo, len(commands))
+
+	for i, c := range commands {
+		infos[i] = c.clone()
+	}
+
+	return infos
+}
+
+func LookupCommand(code Command) (CommandInfo, bool) {
+	for _, c := range commands {
+		if c.Code == code {
+			return c.clone(), true
+		}
+	}
+
+	return CommandInfo{}, false
+}
+
+// DataItems returns the data item registry in item order. The slice, and the
+// values of every entry, are a copy and may be modified by the caller.
+func DataItems() []DataItemInfo {
+	infos := make([]DataItemInfo, len(dataItems))
+
+	for i, d := range dataItems {
+		infos[i] = d.clone()
+	}
+
+	return infos
+}
+
+func LookupDataItem(item DataItem) (DataItemInfo, bool) {
+	for _, d := range dataItems {
+		if d.Item == item {
+			return d.clone(), true
+		}
+	}
+
+	return DataItemInfo{}, false
+}
+
+func (c Command) String() string {
+	if info, ok := LookupCommand(c); ok {
+		return info.Name
+	}
+
+	return fmt.Sprintf("Command(0x%02X)", byte(c))
+}
+
+func (d DataItem) String() string {
+	if info, ok := LookupDataItem(d); ok {
+		return info.Name
+	}
+
+	return fmt.Sprintf("DataItem(%d)", uint16(d))
+}
@@ -0,0 +1,86 @@
+package protocol
+
+import "errors"
+
+//go:generate go run ./internal/schemagen -o schema_gen.go
+
+var ErrNoSchema = errors.New("no response schema for command")
+
+// OK reports whether the device accepted the data item access. Value is
+// empty for WriteData.
+func (r DataResponse) OK() bool {
+	return r.Result == 0x30
+}
+
+// fields reads the fields of a response text in order. The text holds every
+// fixed field, checked against MinResponseLengthWith before decoding.
+type fields struct {
+	text []byte
+	enc  CountEncoding
+	err  error
+}
+
+func (f *fields) byte() byte {
+	b := f.text[0]
+	f.text = f.text[1:]
+
+	return b
+}
+
+func (f *fields) status() StatusCode {
+	return StatusCode(f.byte())
+}
+
+func (f *fields) count() byte {
+	return DecodeCount(f.byte())
+}
+
+// notes reads a FieldNotes count. The response types hold counts in a byte,
+// so a larger wide count fails the decode.
+func (f *fields) notes() byte {
+	n := f.enc.Decode(f.text)
+	f.text = f.text[f.enc.Size():]
+
+	if n > 0xFF {
+		f.err = ErrFrameFormat
+	}
+
+	return byte(n)
+}
+
+// rest reads the text up to the end.
+func (f *fields) rest() string {
+	s := string(f.text)
+	f.text = nil
+
+	return s
+}
+
+// DecodeText decodes the response text of command, as returned by
+// DecodeResponse, into its typed form, e.g. a DispenseResponse for
+// CommandDispense. Text missing a fixed field fails with ErrFrameFormat.
+func DecodeText(command Command, text []byte) (interface{}, error) {
+	return DecodeTextWith(SingleCount, command, text)
+}
+
+// DecodeTextWith is DecodeText for a device sending note counts in enc.
+func DecodeTextWith(enc CountEncoding, command Command, text []byte) (interface{}, error) {
+	decode, ok := schemas[command]
+
+	if !ok {
+		return nil, ErrNoSchema
+	}
+
+	if info, _ := LookupCommand(command); len(text) < info.MinResponseLengthWith(enc) {
+		return nil, ErrFrameFormat
+	}
+
+	f := &fields{text: text, enc: enc}
+	v := decode(f)
+
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	return v, nil
+}
@@ -0,0 +1,189 @@
+package protocol
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var statusDescriptions = map[StatusCode]string{
+	GoodOperation:        "good operation",
+	FeedFailure:          "feed failure",
+	MistrackedNoteAtExit: "mistracked note at exit",
+	TooLongAtExit:        "note too long at exit",
+	BlockedExit:          "blocked exit",
+	TransportError:       "transport error",
+	DoubleDetectError:    "double detect error",
+	DivertedError:        "note diverted",
+	WrongCount:           "wrong count",
+	NoteMissingAtDD:      "note missing at double detect",
+	RejectRateExceeded:   "reject rate exceeded",
+	NonVolatileRAMError:  "non-volatile RAM error",
+	OperationTimeout:     "operation timeout",
+	InternalQueError:     "internal queue error",
+	InvalidCommand:       "invalid command",
+}
+
+func (c StatusCode) String() string {
+	if d, ok := statusDescriptions[c]; ok {
+		return d
+	}
+
+	return fmt.Sprintf("StatusCode(0x%02X)", byte(c))
+}
+
+// statusNames are the symbolic codes used by MarshalText. Unlike the
+// descriptions they are part of the API and must not change.
+var statusNames = map[StatusCode]string{
+	GoodOperation:        "good_operation",
+	FeedFailure:          "feed_failure",
+	MistrackedNoteAtExit: "mistracked_note_at_exit",
+	TooLongAtExit:        "too_long_at_exit",
+	BlockedExit:          "blocked_exit",
+	TransportError:       "transport_error",
+	DoubleDetectError:    "double_detect_error",
+	DivertedError:        "diverted_error",
+	WrongCount:           "wrong_count",
+	NoteMissingAtDD:      "note_missing_at_dd",
+	RejectRateExceeded:   "reject_rate_exceeded",
+	NonVolatileRAMError:  "non_volatile_ram_error",
+	OperationTimeout:     "operation_timeout",
+	InternalQueError:     "internal_queue_error",
+	InvalidCommand:       "invalid_command",
+}
+
+// MarshalText returns the symbolic name of the code, or its hex value like
+// "0x4A" for codes without one.
+func (c StatusCode) MarshalText() ([]byte, error) {
+	if n, ok := statusNames[c]; ok {
+		return []byte(n), nil
+	}
+
+	return []byte(fmt.Sprintf("0x%02X", byte(c))), nil
+}
+
+func (c *StatusCode) UnmarshalText(text []byte) error {
+	for code, n := range statusNames {
+		if n == string(text) {
+			*c = code
+			return nil
+		}
+	}
+
+	b, err := parseHexByte(string(text))
+
+	if err != nil {
+		return fmt.Errorf("unknown status code %q", text)
+	}
+
+	*c = StatusCode(b)
+
+	return nil
+}
+
+func (c StatusCode) IsError() bool {
+	return c != GoodOperation
+}
+
+// StatusCodes lists every known status code in ascending order.
+func StatusCodes() []StatusCode {
+	res := make([]StatusCode, 0, len(statusDescriptions))
+
+	for c := range statusDescriptions {
+		res = append(res, c)
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+
+	return res
+}
+
+// RejectReason is why a note went to the reject bin. The codes are the status
+// codes of the matching failures.
+type RejectReason byte
+
+const (
+	RejectMistracked      RejectReason = RejectReason(MistrackedNoteAtExit)
+	RejectTooLong         RejectReason = RejectReason(TooLongAtExit)
+	RejectDoubleDetect    RejectReason = RejectReason(DoubleDetectError)
+	RejectDiverted        RejectReason = RejectReason(DivertedError)
+	RejectWrongCount      RejectReason = RejectReason(WrongCount)
+	RejectNoteMissingAtDD RejectReason = RejectReason(NoteMissingAtDD)
+)
+
+var rejectReasonDescriptions = map[RejectReason]string{
+	RejectMistracked:      "mistracked note",
+	RejectTooLong:         "note too long",
+	RejectDoubleDetect:    "double note",
+	RejectDiverted:        "note diverted",
+	RejectWrongCount:      "wrong count",
+	RejectNoteMissingAtDD: "note missing at double detect",
+}
+
+var rejectReasonNames = map[RejectReason]string{
+	RejectMistracked:      "mistracked",
+	RejectTooLong:         "too_long",
+	RejectDoubleDetect:    "double_detect",
+	RejectDiverted:        "diverted",
+	RejectWrongCount:      "wrong_count",
+	RejectNoteMissingAtDD: "note_missing_at_dd",
+}
+
+func (r RejectReason) MarshalText() ([]byte, error) {
+	if n, ok := rejectReasonNames[r]; ok {
+		return []byte(n), nil
+	}
+
+	return []byte(fmt.Sprintf("0x%02X", byte(r))), nil
+}
+
+func (r *RejectReason) UnmarshalText(text []byte) error {
+	for reason, n := range rejectReasonNames {
+		if n == string(text) {
+			*r = reason
+			return nil
+		}
+	}
+
+	b, err := parseHexByte(string(text))
+
+	if err != nil {
+		return fmt.Errorf("unknown reject reason %q", text)
+	}
+
+	*r = RejectReason(b)
+
+	return nil
+}
+
+func parseHexByte(s string) (byte, error) {
+	if !strings.HasPrefix(s, "0x") {
+		return 0, strconv.ErrSyntax
+	}
+
+	v, err := strconv.ParseUint(s[2:], 16, 8)
+
+	return byte(v), err
+}
+
+func (r RejectReason) String() string {
+	if d, ok := rejectReasonDescriptions[r]; ok {
+		return d
+	}
+
+	return fmt.Sprintf("RejectReason(0x%02X)", byte(r))
+}
+
+// RejectReasons lists every known reject reason in ascending order.
+func RejectReasons() []RejectReason {
+	res := make([]RejectReason, 0, len(rejectReasonDescriptions))
+
+	for r := range rejectReasonDescriptions {
+		res = append(res, r)
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+
+	return res
+}
@@ -0,0 +1,51 @@
+package mm010_nrc_api
+
+import (
+	"context"
+	"fmt"
+)
+
+// MaxNotesPerDispense is the largest count that fits the count field of a
+// dispense request in SingleCount encoding.
+const MaxNotesPerDispense = 94
+
+// DispenseOnce opens the dispenser on port, brings it up with Initialize,
+// dispenses n notes, verifies the outcome and closes the port again.
+// The port defaults to 9600 baud, use WithBaud to change it.
+func DispenseOnce(port string, n int, opts ...Option) (res DispenseResult, err error) {
+	if n < 1 || n > MaxNotesPerDispense {
+		return res, fmt.Errorf("note count %d out of range 1..%d", n, MaxNotesPerDispense)
+	}
+
+	s, err := NewConnection(port, opts...)
+
+	if err != nil {
+		return res, err
+	}
+
+	defer func() {
+		if closeErr := s.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	if _, err = s.Initialize(context.Background()); err != nil {
+		return res, err
+	}
+
+	res, err = s.DispenseContext(context.Background(), byte(n))
+
+	if err != nil {
+		return res, err
+	}
+
+	if res.Status.IsError() {
+		return res, fmt.Errorf("dispense failed: %v", res.Status)
+	}
+
+	if int(res.NotesDispensed) != n {
+		return res, fmt.Errorf("dispensed %d of %d notes", res.NotesDispensed, n)
+	}
+
+	return res, nil
+}
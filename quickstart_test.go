@@ -0,0 +1,33 @@
+package mm010_nrc_api_test
+
+import (
+	api "mm010_nrc_api"
+	"mm010_nrc_api/mm010sim"
+	"testing"
+	"time"
+)
+
+type simBackend struct {
+	sim *mm010sim.Simulator
+}
+
+func (b simBackend) Open(api.PortConfig) (api.Transport, error) {
+	return b.sim.Conn(), nil
+}
+
+func TestDispenseOnce(t *testing.T) {
+	sim := mm010sim.New()
+	defer sim.Close()
+
+	opts := []api.Option{api.WithSerialBackend(simBackend{sim}), api.WithTimeout(time.Second), api.WithGuardTime(0)}
+
+	res, err := api.DispenseOnce("/dev/ttyUSB0", 3, opts...)
+
+	if err != nil || res.NotesDispensed != 3 || sim.Notes() != 997 {
+		t.Fatalf("got %+v, %v, %d notes left", res, err, sim.Notes())
+	}
+
+	if _, err = api.DispenseOnce("/dev/ttyUSB0", 0, opts...); err == nil {
+		t.Fatal("expected a count of 0 to be refused")
+	}
+}
@@ -0,0 +1,78 @@
+package mm010_nrc_api
+
+import (
+	"fmt"
+	"time"
+
+	"mm010_nrc_api/protocol"
+)
+
+// RateLimit keeps the note moving commands within the duty cycle the vendor
+// gives for the mechanism. A command that would break it fails with
+// ErrRateLimited before anything is sent.
+type RateLimit struct {
+	// MinInterval is the rest the mechanism gets between the end of one
+	// dispense cycle and the start of the next.
+	MinInterval time.Duration
+	// NotesPerMinute caps the notes, dispensed and rejected, moved in any 60
+	// seconds. Zero means no cap.
+	NotesPerMinute int
+}
+
+func WithRateLimit(limit RateLimit) Option {
+	return func(s *MMDispenser) {
+		s.rateLimit = &limit
+	}
+}
+
+type noteCycle struct {
+	at    time.Time
+	notes int
+}
+
+// checkRateLimit runs in front of every exchange, like checkMaintenance.
+func (s *MMDispenser) checkRateLimit(command Command, data []byte) error {
+	if s.rateLimit == nil || !movesNotes(command) {
+		return nil
+	}
+
+	now := time.Now()
+
+	if wait := s.rateLimit.MinInterval - now.Sub(s.lastCycle); !s.lastCycle.IsZero() && wait > 0 {
+		return fmt.Errorf("%w: next dispense cycle in %v", ErrRateLimited, wait)
+	}
+
+	if s.rateLimit.NotesPerMinute <= 0 {
+		return nil
+	}
+
+	for len(s.cycles) > 0 && now.Sub(s.cycles[0].at) >= time.Minute {
+		s.cycles = s.cycles[1:]
+	}
+
+	moved := s.requestedNotes(command, data)
+
+	for _, c := range s.cycles {
+		moved += c.notes
+	}
+
+	if moved > s.rateLimit.NotesPerMinute {
+		return fmt.Errorf("%w: more than %d notes per minute", ErrRateLimited, s.rateLimit.NotesPerMinute)
+	}
+
+	return nil
+}
+
+// recordCycle books the notes a completed note moving command moved.
+func (s *MMDispenser) recordCycle(command Command, response []byte) {
+	if s.rateLimit == nil || !movesNotes(command) {
+		return
+	}
+
+	s.lastCycle = time.Now()
+
+	if decoded, err := s.decodeText(command, response); err == nil {
+		r := decoded.(protocol.DispenseResponse)
+		s.cycles = append(s.cycles, noteCycle{s.lastCycle, int(r.Dispensed) + int(r.Rejected)})
+	}
+}
@@ -0,0 +1,41 @@
+package mm010_nrc_api_test
+
+import (
+	"context"
+	"errors"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/mm010sim"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	sim := mm010sim.New()
+	c := api.NewTransportConnection("sim", sim.Conn(), api.WithTimeout(time.Second), api.WithGuardTime(0),
+		api.WithRateLimit(api.RateLimit{MinInterval: 100 * time.Millisecond, NotesPerMinute: 5}))
+
+	defer sim.Close()
+	defer c.Close()
+
+	if _, err := c.DispenseContext(context.Background(), 3); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.DispenseContext(context.Background(), 1); !errors.Is(err, api.ErrRateLimited) {
+		t.Fatalf("expected the interval to hold back the dispense, got %v", err)
+	}
+
+	if _, err := c.StatusContext(context.Background()); err != nil {
+		t.Fatalf("status while rate limited: %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if _, err := c.DispenseContext(context.Background(), 3); !errors.Is(err, api.ErrRateLimited) {
+		t.Fatalf("expected the notes per minute to hold back the dispense, got %v", err)
+	}
+
+	if _, err := c.DispenseContext(context.Background(), 2); err != nil || sim.Notes() != 995 {
+		t.Fatalf("dispense within the limit: %v, %d notes left", err, sim.Notes())
+	}
+}
@@ -0,0 +1,112 @@
+package mm010_nrc_api
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"mm010_nrc_api/protocol"
+)
+
+// ReadDataInt reads a numeric data item and parses its decimal ASCII value.
+// Text items fail with ErrInvalidValue.
+func (s *MMDispenser) ReadDataInt(ctx context.Context, item DataItem) (int, error) {
+	return ReadDataAs[int](ctx, s, item)
+}
+
+// ReadDataBytes reads a data item and returns its value unparsed.
+func (s *MMDispenser) ReadDataBytes(ctx context.Context, item DataItem) ([]byte, error) {
+	return ReadDataAs[[]byte](ctx, s, item)
+}
+
+// WriteDataInt writes a number to a numeric data item.
+func (s *MMDispenser) WriteDataInt(ctx context.Context, item DataItem, v int) error {
+	return WriteDataAs(ctx, s, item, v)
+}
+
+// DataValue is what ReadDataAs can parse a data item value into, and what
+// WriteDataAs can write.
+type DataValue interface {
+	int | int64 | uint64 | string | []byte
+}
+
+// ReadDataAs reads a data item and parses its value into T: a number for
+// numeric items, or the text as string or []byte for any item. Asking for a
+// number from a text item fails with ErrInvalidValue.
+func ReadDataAs[T DataValue](ctx context.Context, d Dispenser, item DataItem) (T, error) {
+	var res T
+
+	v, err := d.ReadDataContext(ctx, item, "")
+
+	if err != nil {
+		return res, err
+	}
+
+	parsed, err := parseDataValue(item, v, res)
+
+	if err != nil {
+		return res, err
+	}
+
+	return parsed.(T), nil
+}
+
+// parseDataValue parses v into the type of as.
+func parseDataValue(item DataItem, v string, as interface{}) (interface{}, error) {
+	switch as.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return []byte(v), nil
+	}
+
+	if info, ok := protocol.LookupDataItem(item); ok && info.Type != protocol.ValueNumber {
+		return nil, fmt.Errorf("data item %v is %v, not a number: %w", item, info.Type, ErrInvalidValue)
+	}
+
+	var n interface{}
+	var err error
+
+	switch as.(type) {
+	case int:
+		n, err = strconv.Atoi(strings.TrimSpace(v))
+	case int64:
+		n, err = strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	default:
+		n, err = strconv.ParseUint(strings.TrimSpace(v), 10, 64)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("data item %v: %q is not a number: %w", item, v, ErrInvalidValue)
+	}
+
+	return n, nil
+}
+
+// WriteDataAs writes v to a data item, numbers in decimal ASCII and text as
+// is. Writing a number to a text item fails with ErrInvalidValue; the value
+// is checked like any WriteData.
+func WriteDataAs[T DataValue](ctx context.Context, d Dispenser, item DataItem, v T) error {
+	var value string
+	number := true
+
+	switch v := interface{}(v).(type) {
+	case string:
+		value, number = v, false
+	case []byte:
+		value, number = string(v), false
+	case int:
+		value = strconv.Itoa(v)
+	case int64:
+		value = strconv.FormatInt(v, 10)
+	case uint64:
+		value = strconv.FormatUint(v, 10)
+	}
+
+	if info, ok := protocol.LookupDataItem(item); ok && number && info.Type != protocol.ValueNumber {
+		return fmt.Errorf("data item %v is %v, not a number: %w", item, info.Type, ErrInvalidValue)
+	}
+
+	return d.WriteDataContext(ctx, item, value)
+}
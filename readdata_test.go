@@ -0,0 +1,84 @@
+package mm010_nrc_api_test
+
+import (
+	"context"
+	"errors"
+	api "mm010_nrc_api"
+	"testing"
+)
+
+func TestWriteThenReadData(t *testing.T) {
+	_, c := connect(t)
+
+	if err := c.WriteData(api.ThroatSensorCalibrationValue, "42"); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := c.ReadData(api.ThroatSensorCalibrationValue, "")
+
+	if err != nil || v != "42" {
+		t.Fatalf("unexpected read back %q %v", v, err)
+	}
+}
+
+func TestTypedReadData(t *testing.T) {
+	_, c := connect(t)
+	ctx := context.Background()
+
+	if n, err := c.ReadDataInt(ctx, api.MaxNumberOfNotesInOneTransaction); err != nil || n != 50 {
+		t.Fatalf("got %d, %v", n, err)
+	}
+
+	if b, err := c.ReadDataBytes(ctx, api.ProgramID); err != nil || string(b) != "MM010SIM" {
+		t.Fatalf("got %q, %v", b, err)
+	}
+
+	if n, err := api.ReadDataAs[uint64](ctx, c, api.TransactionCounterLifelong); err != nil || n != 0 {
+		t.Fatalf("got %d, %v", n, err)
+	}
+
+	if _, err := api.ReadDataAs[int](ctx, c, api.ProgramID); !errors.Is(err, api.ErrInvalidValue) {
+		t.Fatalf("expected ErrInvalidValue for a text item, got %v", err)
+	}
+}
+
+func TestTypedWriteData(t *testing.T) {
+	sim, c := connect(t)
+	ctx := context.Background()
+
+	if err := c.WriteDataInt(ctx, api.DispenseCounterTrip, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := api.WriteDataAs(ctx, c, api.ThroatSensorCalibrationValue, uint64(42)); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, _ := sim.Data(api.ThroatSensorCalibrationValue); v != "42" {
+		t.Fatalf("written as %q", v)
+	}
+
+	if err := api.WriteDataAs(ctx, c, api.MachineID, 7); !errors.Is(err, api.ErrInvalidValue) {
+		t.Fatalf("expected ErrInvalidValue for a number to a text item, got %v", err)
+	}
+}
+
+func TestReadDataBatch(t *testing.T) {
+	sim, c := connect(t)
+	ctx := context.Background()
+
+	sim.SetData(api.ProgramID, "MM010")
+	sim.SetData(api.MachineID, "1234")
+
+	values, err := c.ReadDataBatch(ctx, []api.DataItem{api.ProgramID, api.MachineID})
+
+	if err != nil || values[api.ProgramID] != "MM010" || values[api.MachineID] != "1234" {
+		t.Fatalf("unexpected batch result %v %v", values, err)
+	}
+
+	values, err = c.ReadDataBatch(ctx, []api.DataItem{api.ProgramID, api.DataItem(99), api.MachineID})
+
+	if !errors.Is(err, api.ErrIllegalCommand) || len(values) != 1 {
+		t.Fatalf("expected the items before the failing one and ErrIllegalCommand, got %v %v", values, err)
+	}
+}
@@ -0,0 +1,107 @@
+package mm010_nrc_api
+
+import (
+	"io"
+	"sync"
+)
+
+// portReader owns the reads of one port. A single goroutine blocks in Read
+// and hands every chunk over a channel, so waiting for a response costs no
+// CPU, and a wait that timed out does not leave a read behind that takes the
+// bytes of the next response.
+type portReader struct {
+	port   Transport
+	chunks chan chunk
+	done   chan struct{}
+	once   sync.Once
+}
+
+// newPortReader starts reading port. serial tells that port is a serial port,
+// which reports its own read timeout as an empty read with io.EOF.
+func newPortReader(port Transport, serial bool) *portReader {
+	r := &portReader{port: port, chunks: make(chan chunk, 16), done: make(chan struct{})}
+
+	go r.run(serial)
+
+	return r
+}
+
+func (r *portReader) run(serial bool) {
+	for {
+		buf := make([]byte, 256)
+		n, err := r.port.Read(buf)
+
+		if n == 0 && (err == nil || (err == io.EOF && serial)) {
+			continue
+		}
+
+		select {
+		case r.chunks <- chunk{data: buf[:n], err: err}:
+		case <-r.done:
+			return
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// stop abandons the reader. The goroutine ends once the pending Read
+// returns, which for a closed port is right away.
+func (r *portReader) stop() {
+	r.once.Do(func() { close(r.done) })
+}
+
+// discard drops the chunks read but not taken yet.
+func (r *portReader) discard() []byte {
+	var dropped []byte
+
+	for {
+		select {
+		case c := <-r.chunks:
+			if c.err != nil {
+				// keep the error for the next read
+				r.chunks <- c
+				return dropped
+			}
+
+			dropped = append(dropped, c.data...)
+		default:
+			return dropped
+		}
+	}
+}
+
+// reader returns the reader of the current port, replacing the one of a port
+// that was closed and reopened since. Bus members share the reader of the bus.
+func (s *MMDispenser) reader() *portReader {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := &s.portReader
+
+	if s.bus != nil {
+		current = &s.bus.reader
+	}
+
+	if *current == nil || (*current).port != s.port {
+		if *current != nil {
+			(*current).stop()
+		}
+
+		*current = newPortReader(s.port, s.config != nil)
+	}
+
+	return *current
+}
+
+func (s *MMDispenser) stopReader() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.portReader != nil {
+		s.portReader.stop()
+		s.portReader = nil
+	}
+}
@@ -0,0 +1,33 @@
+package mm010_nrc_api_test
+
+import (
+	"context"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/protocol"
+	"testing"
+	"time"
+)
+
+func TestLateResponseIsDiscarded(t *testing.T) {
+	port := newFakePort(func(p []byte) [][]byte { return nil })
+	c := api.NewTransportConnection("late", port, api.WithTimeout(50*time.Millisecond))
+
+	if _, err := c.StatusContext(context.Background()); err != api.ErrReadTimeout {
+		t.Fatalf("expected ErrReadTimeout, got %v", err)
+	}
+
+	// the answer to the timed out request arrives after all
+	port.rx <- append([]byte{protocol.Ack}, protocol.EncodeResponse(protocol.CommunicationIdentify, protocol.CommandStatus,
+		[]byte{0x20, 0x20, 0x2F, 0x2F})...)
+	time.Sleep(10 * time.Millisecond)
+
+	port.mu.Lock()
+	port.reply = answer(statusPayload)
+	port.mu.Unlock()
+
+	status, err := c.StatusContext(context.Background())
+
+	if err != nil || status.AverageThickness != 5 {
+		t.Fatalf("expected the fresh status, got %+v %v", status, err)
+	}
+}
@@ -0,0 +1,170 @@
+package mm010_nrc_api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+type ConnectionState int
+
+const (
+	Connected ConnectionState = iota
+	Disconnected
+	Reconnecting
+)
+
+func (c ConnectionState) String() string {
+	switch c {
+	case Connected:
+		return "connected"
+	case Disconnected:
+		return "disconnected"
+	case Reconnecting:
+		return "reconnecting"
+	}
+
+	return "unknown"
+}
+
+func (c ConnectionState) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+func (c *ConnectionState) UnmarshalText(text []byte) error {
+	v, err := parseEnum(text, "connection state", int(Reconnecting), func(i int) string { return ConnectionState(i).String() })
+
+	if err != nil {
+		return err
+	}
+
+	*c = ConnectionState(v)
+
+	return nil
+}
+
+// ReconnectPolicy controls reopening of a link that failed with an I/O error.
+// The n-th attempt waits Delay * Multiplier^n, capped at MaxDelay.
+type ReconnectPolicy struct {
+	Delay      time.Duration
+	Multiplier float64
+	MaxDelay   time.Duration
+	// Dial opens a new link. When nil, the serial port the connection was
+	// created with is reopened.
+	Dial func() (Transport, error)
+}
+
+var DefaultReconnectPolicy = ReconnectPolicy{Delay: 500 * time.Millisecond, Multiplier: 2, MaxDelay: 30 * time.Second}
+
+// WithAutoReconnect makes commands recover from a failed link, e.g. an
+// unplugged USB adapter. A failed command that does not move notes reopens
+// the link and is sent once more; a failed note moving command just returns
+// its error. The next command then keeps trying to reconnect until its
+// context is done.
+func WithAutoReconnect(policy ReconnectPolicy) Option {
+	return func(s *MMDispenser) {
+		s.reconnect = &policy
+	}
+}
+
+// WithConnectionStateHandler registers fn to be called on every connection
+// state change. Like observers it is called while the link is held.
+func WithConnectionStateHandler(fn func(ConnectionState)) Option {
+	return func(s *MMDispenser) {
+		s.stateHandlers = append(s.stateHandlers, fn)
+	}
+}
+
+func (s *MMDispenser) setState(state ConnectionState) {
+	if s.state == state {
+		return
+	}
+
+	s.state = state
+
+	for _, fn := range s.stateHandlers {
+		fn(state)
+	}
+}
+
+// linkFailed drops the port after an I/O error, so the next command dials a
+// new one.
+func (s *MMDispenser) linkFailed(err error) bool {
+	if s.reconnect == nil || !isLinkError(err) {
+		return false
+	}
+
+	s.log().Errorf("link failed: %v", err)
+
+	if s.port != nil {
+		s.port.Close()
+	}
+
+	s.setPort(s.port, false, true)
+	s.setState(Disconnected)
+
+	return true
+}
+
+func (s *MMDispenser) ensureLink(ctx context.Context) error {
+	if s.reconnect == nil || !s.isLost() {
+		return nil
+	}
+
+	s.setState(Reconnecting)
+
+	backoff := RetryPolicy{Delay: s.reconnect.Delay, Multiplier: s.reconnect.Multiplier, MaxDelay: s.reconnect.MaxDelay}
+
+	for attempt := 0; ; attempt++ {
+		port, err := s.dial()
+
+		if err == nil {
+			s.setPort(port, true, false)
+			s.echo = nil
+			s.setState(Connected)
+			s.log().Infof("link reconnected")
+
+			return nil
+		}
+
+		s.log().Debugf("reconnect attempt %d: %v", attempt+1, err)
+
+		if err = sleep(ctx, backoff.delay(attempt)); err != nil {
+			s.setState(Disconnected)
+			return err
+		}
+	}
+}
+
+func (s *MMDispenser) dial() (Transport, error) {
+	if s.reconnect.Dial != nil {
+		return s.reconnect.Dial()
+	}
+
+	if s.config == nil {
+		return nil, ErrReopenNotSupported
+	}
+
+	return s.openPort()
+}
+
+// isLinkError tells errors of the port itself apart from protocol level
+// failures, timeouts and cancellation.
+func isLinkError(err error) bool {
+	var (
+		protoErr *ProtocolError
+		hookErr  *HookError
+	)
+
+	switch {
+	case err == nil, err == io.EOF, errors.As(err, &protoErr), errors.As(err, &hookErr):
+		return false
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return false
+	case err == ErrNack, err == ErrReadTimeout, err == ErrInterByteTimeout, err == ErrPortClosed, err == ErrAborted:
+		return false
+	}
+
+	return true
+}
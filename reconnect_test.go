@@ -0,0 +1,79 @@
+package mm010_nrc_api_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/protocol"
+	"testing"
+	"time"
+)
+
+// unpluggedPort fails every write like a removed USB adapter.
+type unpluggedPort struct {
+	closed bool
+}
+
+func (u *unpluggedPort) Read(p []byte) (int, error)  { return 0, io.EOF }
+func (u *unpluggedPort) Write(p []byte) (int, error) { return 0, errors.New("input/output error") }
+func (u *unpluggedPort) Close() error                { u.closed = true; return nil }
+
+func TestAutoReconnect(t *testing.T) {
+	unplugged := &unpluggedPort{}
+	dials := 0
+	var states []api.ConnectionState
+
+	policy := api.ReconnectPolicy{Delay: time.Millisecond, Multiplier: 2, Dial: func() (api.Transport, error) {
+		dials++
+
+		if dials < 3 {
+			return nil, errors.New("no such device")
+		}
+
+		return newFakePort(answer(statusPayload)), nil
+	}}
+
+	c := api.NewTransportConnection("replug", unplugged, api.WithTimeout(time.Second), api.WithAutoReconnect(policy),
+		api.WithConnectionStateHandler(func(state api.ConnectionState) {
+			states = append(states, state)
+		}))
+
+	if _, err := c.Status(); err != nil {
+		t.Fatalf("expected status to succeed after reconnect, got %v", err)
+	}
+
+	if !unplugged.closed || dials != 3 {
+		t.Fatalf("expected the failed port to be closed and 3 dials, got %v %d", unplugged.closed, dials)
+	}
+
+	expected := []api.ConnectionState{api.Disconnected, api.Reconnecting, api.Connected}
+
+	if fmt.Sprint(states) != fmt.Sprint(expected) {
+		t.Fatalf("expected states %v, got %v", expected, states)
+	}
+}
+
+func TestAutoReconnectDoesNotRepeatDispense(t *testing.T) {
+	dials := 0
+	policy := api.ReconnectPolicy{Dial: func() (api.Transport, error) {
+		dials++
+		return newFakePort(answer(func(cmd protocol.Command) []byte { return []byte{0x30, 0x21, 0x20} })), nil
+	}}
+
+	c := api.NewTransportConnection("replug", &unpluggedPort{}, api.WithTimeout(time.Second),
+		api.WithAutoReconnect(policy), api.WithBlockedSensorPolicy(api.IgnoreBlockedSensor))
+
+	if _, err := c.DispenseContext(context.Background(), 1); err == nil {
+		t.Fatal("expected the dispense on the failed link to fail")
+	}
+
+	if dials != 0 {
+		t.Fatalf("expected the failed dispense not to reconnect and resend, got %d dials", dials)
+	}
+
+	if _, err := c.DispenseContext(context.Background(), 1); err != nil || dials != 1 {
+		t.Fatalf("expected dispense on a new link to succeed, got %v after %d dials", err, dials)
+	}
+}
@@ -0,0 +1,119 @@
+package mm010_nrc_api
+
+import (
+	"context"
+	"fmt"
+
+	"mm010_nrc_api/protocol"
+)
+
+// RecoveryPolicy has Dispense and TestDispense recover on their own from the
+// status codes in Statuses: they Purge, Reset, wait for the device to come
+// back and then retry the notes still missing once.
+type RecoveryPolicy struct {
+	Statuses []StatusCode
+}
+
+var DefaultRecoveryPolicy = RecoveryPolicy{Statuses: []StatusCode{FeedFailure, MistrackedNoteAtExit, BlockedExit}}
+
+func WithRecoveryPolicy(policy RecoveryPolicy) Option {
+	return func(s *MMDispenser) {
+		s.recovery = &policy
+	}
+}
+
+// RecoveryStep is one command of an automatic recovery, starting with the
+// dispense that failed. Notes are the notes dispensed or purged.
+type RecoveryStep struct {
+	Command string     `json:"command"`
+	Status  StatusCode `json:"status,omitempty"`
+	Notes   byte       `json:"notes,omitempty"`
+	Error   string     `json:"error,omitempty"`
+}
+
+func (p *RecoveryPolicy) covers(status StatusCode) bool {
+	for _, s := range p.Statuses {
+		if s == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dispense is DispenseContext and TestDispenseContext with the recovery
+// policy applied. After a recovery the result is that of the retry with the
+// notes of both attempts added up, and the steps taken in RecoveryTrail.
+func (s *MMDispenser) dispense(ctx context.Context, command Command, count byte) (DispenseResult, error) {
+	res, err := s.dispenseOnce(ctx, command, count)
+
+	if err != nil || s.recovery == nil || !s.recovery.covers(res.Status) || res.NotesDispensed >= count {
+		return res, err
+	}
+
+	s.log().Infof("recovering from %v after %v", res.Status, command)
+
+	trail := []RecoveryStep{{Command: command.String(), Status: res.Status, Notes: res.NotesDispensed}}
+
+	fail := func(step RecoveryStep, err error) (DispenseResult, error) {
+		step.Error = err.Error()
+		res.RecoveryTrail = append(trail, step)
+
+		return res, fmt.Errorf("recovery after %v: %w", res.Status, err)
+	}
+
+	status, purged, err := s.PurgeContext(ctx)
+	step := RecoveryStep{Command: protocol.CommandPurge.String(), Status: status, Notes: purged}
+
+	if err != nil {
+		return fail(step, err)
+	}
+
+	trail = append(trail, step)
+	step = RecoveryStep{Command: protocol.CommandReset.String()}
+
+	if err = s.ResetContext(ctx); err == nil {
+		trail = append(trail, step)
+		step = RecoveryStep{Command: protocol.CommandStatus.String()}
+		_, _, err = s.awaitReset(ctx)
+	}
+
+	if err != nil {
+		return fail(step, err)
+	}
+
+	trail = append(trail, step)
+
+	retry, err := s.dispenseOnce(ctx, command, count-res.NotesDispensed)
+	step = RecoveryStep{Command: command.String(), Status: retry.Status, Notes: retry.NotesDispensed}
+
+	if err != nil {
+		return fail(step, err)
+	}
+
+	retry.NotesDispensed += res.NotesDispensed
+	retry.NotesRejected += res.NotesRejected
+	retry.RecoveryTrail = append(trail, step)
+
+	return retry, nil
+}
+
+func (s *MMDispenser) dispenseOnce(ctx context.Context, command Command, count byte) (DispenseResult, error) {
+	if err := s.checkSensors(ctx); err != nil {
+		return DispenseResult{}, err
+	}
+
+	data, err := s.countEncoding.Encode(int(count))
+
+	if err != nil {
+		return DispenseResult{}, err
+	}
+
+	res, err := s.dispenseCommand(ctx, command, data)
+
+	if err == nil {
+		s.sampleNotes(ctx, res)
+	}
+
+	return res, err
+}
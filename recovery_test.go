@@ -0,0 +1,45 @@
+package mm010_nrc_api_test
+
+import (
+	"context"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/mm010sim"
+	"mm010_nrc_api/protocol"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecoveryPolicy(t *testing.T) {
+	sim := mm010sim.New()
+	c := api.NewTransportConnection("sim", sim.Conn(), api.WithTimeout(time.Second), api.WithGuardTime(0),
+		api.WithPollInterval(10*time.Millisecond), api.WithRecoveryPolicy(api.DefaultRecoveryPolicy))
+
+	defer sim.Close()
+	defer c.Close()
+
+	sim.FailNext(protocol.CommandDispense, api.FeedFailure)
+
+	res, err := c.DispenseContext(context.Background(), 3)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var steps []string
+
+	for _, step := range res.RecoveryTrail {
+		steps = append(steps, step.Command)
+	}
+
+	if want := "Dispense Purge Reset Status Dispense"; strings.Join(steps, " ") != want ||
+		res.Status != api.GoodOperation || res.NotesDispensed != 3 || sim.Notes() != 997 {
+		t.Fatalf("got %+v, %d notes left", res, sim.Notes())
+	}
+
+	sim.FailNext(protocol.CommandDispense, api.DoubleDetectError)
+
+	if res, err = c.DispenseContext(context.Background(), 1); err != nil || res.Status != api.DoubleDetectError || res.RecoveryTrail != nil {
+		t.Fatalf("expected no recovery from DoubleDetectError, got %+v, %v", res, err)
+	}
+}
@@ -0,0 +1,115 @@
+package mm010_nrc_api
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"mm010_nrc_api/protocol"
+)
+
+// Redactor returns the text of a request or response of command as it may
+// be logged. request is the request text in both cases, so a response can be
+// judged by what was asked for; response is nil while the request is logged.
+// It must not modify its arguments.
+type Redactor func(command Command, request, response []byte) []byte
+
+// WithRedactor masks frame payloads in the log output, e.g. for deployments
+// under PCI audit that log frames. Redactors run in the order they were
+// added. Frames that could not be decoded are then logged by length only.
+// Traces and the raw bytes of a ProtocolError are not redacted.
+func WithRedactor(fn Redactor) Option {
+	return func(s *MMDispenser) {
+		s.redactors = append(s.redactors, fn)
+	}
+}
+
+// RedactDataItems masks the values of items in ReadData and WriteData
+// requests and responses.
+func RedactDataItems(items ...DataItem) Redactor {
+	return func(command Command, request, response []byte) []byte {
+		if command != protocol.CommandReadData && command != protocol.CommandWriteData {
+			return textOf(request, response)
+		}
+
+		// request text is "D/nnn" with an optional "/value" or "/param"
+		if len(request) < 5 || !containsItem(items, request[2:5]) {
+			return textOf(request, response)
+		}
+
+		if response == nil {
+			return mask(request, 5, len(request))
+		}
+
+		return mask(response, 1, len(response))
+	}
+}
+
+// RedactRange masks length bytes from offset on in the request text of
+// command, or in its response text if response is set.
+func RedactRange(command Command, response bool, offset, length int) Redactor {
+	return func(c Command, req, resp []byte) []byte {
+		text := textOf(req, resp)
+
+		if c != command || response != (resp != nil) {
+			return text
+		}
+
+		return mask(text, offset, offset+length)
+	}
+}
+
+func textOf(request, response []byte) []byte {
+	if response != nil {
+		return response
+	}
+
+	return request
+}
+
+func containsItem(items []DataItem, ref []byte) bool {
+	n, err := strconv.Atoi(strings.TrimSpace(string(ref)))
+
+	if err != nil {
+		return false
+	}
+
+	for _, item := range items {
+		if int(item) == n {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mask returns a copy of text with the bytes from start to end replaced.
+func mask(text []byte, start, end int) []byte {
+	if end > len(text) {
+		end = len(text)
+	}
+
+	if start >= end {
+		return text
+	}
+
+	res := append([]byte(nil), text...)
+	copy(res[start:end], bytes.Repeat([]byte{'*'}, end-start))
+
+	return res
+}
+
+// redact runs the redactors over a request, or a response if one is given.
+func (s *MMDispenser) redact(command Command, response []byte) []byte {
+	text := textOf(s.request, response)
+
+	for _, fn := range s.redactors {
+		if response == nil {
+			text = fn(command, text, nil)
+		} else {
+			text = fn(command, s.request, text)
+		}
+	}
+
+	return text
+}
@@ -0,0 +1,31 @@
+package mm010_nrc_api_test
+
+import (
+	"fmt"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/protocol"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedactor(t *testing.T) {
+	logger := &recordingLogger{}
+	secret := func(cmd protocol.Command) []byte { return []byte("0SECRET") }
+	c := api.NewTransportConnection("log", newFakePort(answer(secret)), api.WithTimeout(time.Second), api.WithLogger(logger),
+		api.WithRedactor(api.RedactDataItems(api.MachineID)))
+
+	if v, err := c.ReadData(api.MachineID, ""); err != nil || v != "SECRET" {
+		t.Fatalf("got %q, %v", v, err)
+	}
+
+	if _, err := c.ReadData(api.ProgramID, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	log := strings.Join(logger.lines, "\n")
+
+	if strings.Count(log, fmt.Sprintf("%X", "SECRET")) != 1 || !strings.Contains(log, fmt.Sprintf("%X", "0******")) {
+		t.Fatalf("MachineID not masked, or ProgramID masked:\n%s", log)
+	}
+}
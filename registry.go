@@ -0,0 +1,75 @@
+package mm010_nrc_api
+
+import (
+	"fmt"
+	"strconv"
+
+	"mm010_nrc_api/protocol"
+)
+
+type CommandInfo = protocol.CommandInfo
+
+type DataItemInfo = protocol.DataItemInfo
+
+func ListCommands() []CommandInfo {
+	return protocol.Commands()
+}
+
+func ListDataItems() []DataItemInfo {
+	return protocol.DataItems()
+}
+
+func LookupDataItem(item DataItem) (DataItemInfo, bool) {
+	return protocol.LookupDataItem(item)
+}
+
+// validateWrite checks a WriteData value against the item metadata. Values
+// must be printable ASCII, which also keeps TextEnd out of the frame; beyond
+// that items missing from the registry are passed through unchecked.
+func validateWrite(item DataItem, value string) error {
+	for i := 0; i < len(value); i++ {
+		if value[i] < 0x20 || value[i] > 0x7E {
+			return fmt.Errorf("%v: %q is not printable ASCII: %w", item, value, ErrInvalidValue)
+		}
+	}
+
+	info, ok := protocol.LookupDataItem(item)
+
+	if !ok {
+		return nil
+	}
+
+	if info.Access != protocol.ReadWrite {
+		return fmt.Errorf("%v: %w", item, ErrItemReadOnly)
+	}
+
+	if len(info.Values) > 0 && !contains(info.Values, value) {
+		return fmt.Errorf("%v: %q is not one of %v: %w", item, value, info.Values, ErrValueOutOfRange)
+	}
+
+	if info.Type != protocol.ValueNumber {
+		return nil
+	}
+
+	n, err := strconv.ParseUint(value, 10, 64)
+
+	if err != nil {
+		return fmt.Errorf("%v: %q is not a number: %w", item, value, ErrInvalidValue)
+	}
+
+	if info.Max > 0 && n > info.Max {
+		return fmt.Errorf("%v: %d is above %d: %w", item, n, info.Max, ErrValueOutOfRange)
+	}
+
+	return nil
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+
+	return false
+}
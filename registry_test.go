@@ -0,0 +1,44 @@
+package mm010_nrc_api_test
+
+import (
+	"context"
+	"errors"
+	api "mm010_nrc_api"
+	"testing"
+)
+
+func TestWriteDataValidation(t *testing.T) {
+	_, c := connect(t)
+	ctx := context.Background()
+
+	if err := c.WriteDataContext(ctx, api.DispenseCounterLifelong, "0"); !errors.Is(err, api.ErrItemReadOnly) {
+		t.Fatalf("expected ErrItemReadOnly, got %v", err)
+	}
+
+	if err := c.WriteDataContext(ctx, api.DispenseCounterTrip, "ten"); !errors.Is(err, api.ErrInvalidValue) {
+		t.Fatalf("expected ErrInvalidValue, got %v", err)
+	}
+
+	for item, value := range map[api.DataItem]string{
+		api.Baudrate:                         "19200",
+		api.Parity:                           "3",
+		api.MaxNumberOfNotesInOneTransaction: "224",
+		api.LearningNotes:                    "300",
+	} {
+		if err := c.WriteDataContext(ctx, item, value); !errors.Is(err, api.ErrValueOutOfRange) {
+			t.Fatalf("%v = %s: expected ErrValueOutOfRange, got %v", item, value, err)
+		}
+	}
+
+	if err := c.WriteDataContext(ctx, api.MachineID, "A\x03B"); !errors.Is(err, api.ErrInvalidValue) {
+		t.Fatalf("expected ErrInvalidValue for a control byte, got %v", err)
+	}
+
+	if err := c.WriteDataContext(ctx, api.DispenseCounterTrip, "0"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.WriteDataContext(ctx, api.MaxNumberOfNotesInOneTransaction, "223"); err != nil {
+		t.Fatal(err)
+	}
+}
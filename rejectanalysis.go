@@ -0,0 +1,123 @@
+package mm010_nrc_api
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultRejectRateWarning is the share of rejected notes at which
+// RejectAnalysis warns that the device will soon stop with
+// RejectRateExceeded.
+const DefaultRejectRateWarning = 0.1
+
+// WithRejectRateWarning sets the reject rate at which RejectAnalysis sets
+// Warning.
+func WithRejectRateWarning(rate float64) Option {
+	return func(s *MMDispenser) {
+		s.rejectRateWarning = rate
+	}
+}
+
+type rejectSession struct {
+	started  time.Time
+	counters Counters
+	reasons  map[RejectReason]uint64
+}
+
+// RejectAnalysis is what the reject counters did since BeginRejectSession.
+type RejectAnalysis struct {
+	Dispensed uint64                  `json:"dispensed"`
+	Rejected  uint64                  `json:"rejected"`
+	Processed uint64                  `json:"processed"`
+	Reasons   map[RejectReason]uint64 `json:"reasons"`
+	// Rate is Rejected out of Processed.
+	Rate float64 `json:"rate"`
+	// Warning is set once Rate reaches the warning rate or the device reported
+	// RejectRateExceeded during the session.
+	Warning  bool          `json:"warning"`
+	Duration time.Duration `json:"duration"`
+}
+
+// BeginRejectSession takes the trip and per reason reject counters as the
+// baseline of RejectAnalysis, e.g. before a batch of dispenses. A new
+// session replaces the previous one.
+func (s *MMDispenser) BeginRejectSession(ctx context.Context) error {
+	session := &rejectSession{started: time.Now()}
+	var err error
+
+	if session.counters, err = s.Counters(ctx); err != nil {
+		return err
+	}
+
+	if session.reasons, err = s.RejectReasonReport(ctx); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rejectSession = session
+	s.sessionRejectRateExceeded = s.rejectRateExceeded
+
+	return nil
+}
+
+// RejectAnalysis compares the counters with the baseline of the session
+// begun last. Trip counters cleared during the session count from zero.
+func (s *MMDispenser) RejectAnalysis(ctx context.Context) (RejectAnalysis, error) {
+	s.mu.Lock()
+	session, exceeded := s.rejectSession, s.rejectRateExceeded-s.sessionRejectRateExceeded
+	s.mu.Unlock()
+
+	if session == nil {
+		return RejectAnalysis{}, ErrNoRejectSession
+	}
+
+	counters, err := s.Counters(ctx)
+
+	if err != nil {
+		return RejectAnalysis{}, err
+	}
+
+	reasons, err := s.RejectReasonReport(ctx)
+
+	if err != nil {
+		return RejectAnalysis{}, err
+	}
+
+	res := RejectAnalysis{
+		Dispensed: counterDelta(session.counters.DispenseTrip, counters.DispenseTrip),
+		Rejected:  counterDelta(session.counters.RejectTrip, counters.RejectTrip),
+		Processed: counterDelta(session.counters.TotalProcessedTrip, counters.TotalProcessedTrip),
+		Reasons:   make(map[RejectReason]uint64, len(reasons)),
+		Duration:  time.Since(session.started),
+	}
+
+	for reason, n := range reasons {
+		if d := counterDelta(session.reasons[reason], n); d > 0 {
+			res.Reasons[reason] = d
+		}
+	}
+
+	if res.Processed > 0 {
+		res.Rate = float64(res.Rejected) / float64(res.Processed)
+	}
+
+	warning := s.rejectRateWarning
+
+	if warning <= 0 {
+		warning = DefaultRejectRateWarning
+	}
+
+	res.Warning = exceeded > 0 || (res.Processed > 0 && res.Rate >= warning)
+
+	return res, nil
+}
+
+func counterDelta(before, after uint64) uint64 {
+	if after < before {
+		return after
+	}
+
+	return after - before
+}
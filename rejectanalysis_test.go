@@ -0,0 +1,38 @@
+package mm010_nrc_api_test
+
+import (
+	"context"
+	"errors"
+	api "mm010_nrc_api"
+	"testing"
+)
+
+func TestRejectAnalysis(t *testing.T) {
+	sim, c := connect(t)
+	ctx := context.Background()
+
+	if _, err := c.RejectAnalysis(ctx); !errors.Is(err, api.ErrNoRejectSession) {
+		t.Fatalf("expected ErrNoRejectSession, got %v", err)
+	}
+
+	sim.SetParamData(api.RejectReasonCounter, string(api.RejectTooLong), "2")
+
+	if err := c.BeginRejectSession(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.DispenseContext(ctx, 18); err != nil {
+		t.Fatal(err)
+	}
+
+	sim.SetData(api.RejectCounterTrip, "2")
+	sim.SetData(api.TotalProcessedCcounterTrip, "20")
+	sim.SetParamData(api.RejectReasonCounter, string(api.RejectDoubleDetect), "2")
+
+	res, err := c.RejectAnalysis(ctx)
+
+	if err != nil || res.Dispensed != 18 || res.Rejected != 2 || res.Processed != 20 || res.Rate != 0.1 || !res.Warning ||
+		len(res.Reasons) != 1 || res.Reasons[api.RejectDoubleDetect] != 2 {
+		t.Fatalf("unexpected analysis %+v %v", res, err)
+	}
+}
@@ -0,0 +1,25 @@
+package mm010_nrc_api
+
+type DispenseResult struct {
+	Status           StatusCode `json:"status"`
+	NotesDispensed   byte       `json:"dispensed"`
+	NotesRejected    byte       `json:"rejected"`
+	IsFatal          bool       `json:"is_fatal"`
+	RetryRecommended bool       `json:"retry_recommended"`
+	Description      string     `json:"description"`
+	// RecoveryTrail lists what the RecoveryPolicy did, if anything.
+	RecoveryTrail []RecoveryStep `json:"recovery_trail,omitempty"`
+}
+
+func newDispenseResult(status StatusCode, dispensed, rejected byte) DispenseResult {
+	info := LookupStatus(status)
+
+	return DispenseResult{
+		Status:           status,
+		NotesDispensed:   dispensed,
+		NotesRejected:    rejected,
+		IsFatal:          info.Fatal,
+		RetryRecommended: info.Retry,
+		Description:      info.Description,
+	}
+}
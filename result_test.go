@@ -0,0 +1,31 @@
+package mm010_nrc_api_test
+
+import (
+	"encoding/json"
+	api "mm010_nrc_api"
+	"strings"
+	"testing"
+)
+
+func TestResultJSON(t *testing.T) {
+	report := api.DiagnosticsReport{
+		SensorStatus: api.GoodOperation,
+		Findings:     []api.Finding{{Check: "sensor", Severity: api.SeverityWarning}},
+	}
+
+	out, err := json.Marshal(report)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(out), `"sensor_status":"good_operation"`) || !strings.Contains(string(out), `"severity":"warning"`) {
+		t.Fatalf("unexpected JSON %s", out)
+	}
+
+	var back api.DiagnosticsReport
+
+	if err := json.Unmarshal(out, &back); err != nil || back.Findings[0].Severity != api.SeverityWarning {
+		t.Fatalf("round trip gave %+v, %v", back, err)
+	}
+}
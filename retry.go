@@ -0,0 +1,57 @@
+package mm010_nrc_api
+
+import (
+	"context"
+	"time"
+)
+
+// RetryPolicy controls retransmission of a request the device answered with
+// NAK. The n-th retry waits Delay * Multiplier^n; a Multiplier below 1 keeps
+// the delay constant.
+type RetryPolicy struct {
+	MaxRetries int
+	Delay      time.Duration
+	Multiplier float64
+	MaxDelay   time.Duration
+}
+
+var (
+	DefaultRetryPolicy = RetryPolicy{MaxRetries: 3, Delay: 100 * time.Millisecond}
+	NoRetry            = RetryPolicy{}
+)
+
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(s *MMDispenser) {
+		s.retry = policy
+	}
+}
+
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.Delay
+
+	for i := 0; i < attempt && p.Multiplier > 1; i++ {
+		d = time.Duration(float64(d) * p.Multiplier)
+
+		if p.MaxDelay > 0 && d > p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+
+	return d
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,78 @@
+package mm010_nrc_api
+
+import (
+	"context"
+	"fmt"
+)
+
+// BlockedSensorPolicy decides what Dispense and TestDispense do when the feed
+// or exit sensor is already blocked before any note is moved.
+type BlockedSensorPolicy int
+
+const (
+	FailOnBlockedSensor BlockedSensorPolicy = iota
+	PurgeOnBlockedSensor
+	IgnoreBlockedSensor
+)
+
+type BlockedSensorError struct {
+	Status Status
+}
+
+func (e *BlockedSensorError) Error() string {
+	return fmt.Sprintf("sensor blocked before dispense (feed: %v, exit: %v)",
+		e.Status.FeedSensorBlocked, e.Status.ExitSensorBlocked)
+}
+
+func WithBlockedSensorPolicy(policy BlockedSensorPolicy) Option {
+	return func(s *MMDispenser) {
+		s.blockedSensorPolicy = policy
+	}
+}
+
+func (s *MMDispenser) SetBlockedSensorPolicy(policy BlockedSensorPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.blockedSensorPolicy = policy
+}
+
+func (s *MMDispenser) checkSensors(ctx context.Context) error {
+	s.mu.Lock()
+	policy := s.blockedSensorPolicy
+	s.mu.Unlock()
+
+	if policy == IgnoreBlockedSensor {
+		return nil
+	}
+
+	status, err := s.StatusContext(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	if !sensorsBlocked(status) {
+		return nil
+	}
+
+	if policy == PurgeOnBlockedSensor {
+		if _, _, err = s.PurgeContext(ctx); err != nil {
+			return err
+		}
+
+		if status, err = s.StatusContext(ctx); err != nil {
+			return err
+		}
+
+		if !sensorsBlocked(status) {
+			return nil
+		}
+	}
+
+	return &BlockedSensorError{Status: status}
+}
+
+func sensorsBlocked(status Status) bool {
+	return status.FeedSensorBlocked || status.ExitSensorBlocked
+}
@@ -0,0 +1,166 @@
+package mm010_nrc_api
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/tarm/serial"
+)
+
+// lineSwitchDelay is how long the device gets to change its line settings
+// after the Baudrate or Parity data item was written.
+const lineSwitchDelay = 500 * time.Millisecond
+
+var supportedBauds = []Baud{Baud9600, Baud4800, Baud2400, Baud1200}
+
+// SetBaudrate switches the device to baud through the Baudrate data item,
+// reopens the serial port at the new speed and checks that the device answers
+// a status poll there. It is not supported on transport connections.
+func (s *MMDispenser) SetBaudrate(ctx context.Context, baud Baud) error {
+	if s.config == nil {
+		return ErrReopenNotSupported
+	}
+
+	if !baudSupported(baud) {
+		return fmt.Errorf("baud rate %d: %w", baud, ErrInvalidValue)
+	}
+
+	if err := s.WriteDataContext(ctx, Baudrate, strconv.Itoa(int(baud))); err != nil {
+		return err
+	}
+
+	if err := sleep(ctx, lineSwitchDelay); err != nil {
+		return err
+	}
+
+	if err := s.reopenAt(ctx, baud); err != nil {
+		return err
+	}
+
+	_, err := s.StatusContext(ctx)
+
+	return err
+}
+
+// parityValues is how the Parity data item encodes each parity mode.
+var parityValues = map[ParityMode]string{
+	ParityNone: "0",
+	ParityOdd:  "1",
+	ParityEven: "2",
+}
+
+// SetParity switches the device to parity through the Parity data item,
+// reopens the serial port with the same parity and checks that the device
+// answers a status poll. The data bits stay as set with WithDataBits. It is
+// not supported on transport connections.
+func (s *MMDispenser) SetParity(ctx context.Context, parity ParityMode) error {
+	if s.config == nil {
+		return ErrReopenNotSupported
+	}
+
+	value, ok := parityValues[parity]
+
+	if !ok {
+		return fmt.Errorf("parity %q: %w", parity, ErrInvalidValue)
+	}
+
+	if err := s.WriteDataContext(ctx, Parity, value); err != nil {
+		return err
+	}
+
+	if err := sleep(ctx, lineSwitchDelay); err != nil {
+		return err
+	}
+
+	err := s.reopenWith(ctx, func(c *serial.Config) {
+		c.Parity = serial.Parity(parity)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	_, err = s.StatusContext(ctx)
+
+	return err
+}
+
+// AutoDetectBaud probes 9600, 4800, 2400 and 1200 baud with a status poll and
+// leaves the serial port at the first speed the device answers. If none does,
+// the port is put back to the speed it had.
+func (s *MMDispenser) AutoDetectBaud(ctx context.Context) (Baud, error) {
+	if s.config == nil {
+		return 0, ErrReopenNotSupported
+	}
+
+	previous := Baud(s.config.Baud)
+
+	for _, baud := range supportedBauds {
+		if err := s.reopenAt(ctx, baud); err != nil {
+			return 0, err
+		}
+
+		_, err := s.StatusContext(ctx)
+
+		if err == nil {
+			return baud, nil
+		}
+
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+
+		s.log().Debugf("no answer at %d baud: %v", baud, err)
+	}
+
+	if err := s.reopenAt(ctx, previous); err != nil {
+		return 0, err
+	}
+
+	return 0, ErrBaudNotDetected
+}
+
+func (s *MMDispenser) reopenAt(ctx context.Context, baud Baud) error {
+	return s.reopenWith(ctx, func(c *serial.Config) {
+		c.Baud = int(baud)
+	})
+}
+
+// reopenWith closes the serial port, applies change to its configuration and
+// opens it again.
+func (s *MMDispenser) reopenWith(ctx context.Context, change func(c *serial.Config)) error {
+	if err := s.acquire(ctx); err != nil {
+		return err
+	}
+	defer s.release()
+
+	if s.isOpen() {
+		s.port.Close()
+		s.setPort(s.port, false, false)
+	}
+
+	change(s.config)
+
+	p, err := s.openPort()
+
+	if err != nil {
+		return err
+	}
+
+	s.setPort(p, true, false)
+	s.echo = nil
+
+	return nil
+}
+
+func baudSupported(baud Baud) bool {
+	for _, b := range supportedBauds {
+		if b == baud {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,29 @@
+package mm010_nrc_api_test
+
+import (
+	"context"
+	api "mm010_nrc_api"
+	"testing"
+	"time"
+)
+
+func TestLineSettingsNeedSerialPort(t *testing.T) {
+	port := newFakePort(answer(statusPayload))
+	c := api.NewTransportConnection("fake", port, api.WithTimeout(time.Second))
+
+	if err := c.SetBaudrate(context.Background(), api.Baud4800); err != api.ErrReopenNotSupported {
+		t.Fatalf("expected ErrReopenNotSupported, got %v", err)
+	}
+
+	if _, err := c.AutoDetectBaud(context.Background()); err != api.ErrReopenNotSupported {
+		t.Fatalf("expected ErrReopenNotSupported, got %v", err)
+	}
+
+	if err := c.SetParity(context.Background(), api.ParityNone); err != api.ErrReopenNotSupported {
+		t.Fatalf("expected ErrReopenNotSupported, got %v", err)
+	}
+
+	if len(port.written) != 0 {
+		t.Fatalf("expected nothing to be sent, got %X", port.written)
+	}
+}
@@ -0,0 +1,98 @@
+package mm010_nrc_api
+
+import (
+	"context"
+	"time"
+
+	"mm010_nrc_api/protocol"
+)
+
+// ShutdownPolicy decides what Shutdown does with a command still running.
+type ShutdownPolicy int
+
+const (
+	// WaitForCommand lets the command finish, or aborts it once the context
+	// of Shutdown is done.
+	WaitForCommand ShutdownPolicy = iota
+	// AbortCommand aborts it right away, see Abort.
+	AbortCommand
+)
+
+func WithShutdownPolicy(policy ShutdownPolicy) Option {
+	return func(s *MMDispenser) {
+		s.shutdownPolicy = policy
+	}
+}
+
+// Shutdown takes the link from the command in flight as the ShutdownPolicy
+// says, ends the transaction on the device's side with EOT, flushes the
+// buffers and closes the port. Callers still waiting for the link fail once
+// it is closed. Like Close, a dispenser on a bus only detaches from it.
+//
+// If ctx is done before the command finished, the command is aborted and
+// Shutdown returns the context's error after closing.
+func (s *MMDispenser) Shutdown(ctx context.Context) error {
+	if !s.isOpen() {
+		return ErrPortClosed
+	}
+
+	waitErr := s.stop(ctx)
+
+	if waitErr != nil && s.bus != nil {
+		// the link may be held by another dispenser of the bus, which can't
+		// be aborted from here
+		s.Close()
+		return waitErr
+	}
+
+	defer s.release()
+
+	if s.bus == nil && !s.isLost() {
+		s.log().Debugf("-> EOT (shutdown)")
+
+		if _, err := s.write([]byte{protocol.Eot}); err != nil {
+			s.log().Errorf("send EOT on shutdown: %v", err)
+		}
+
+		if err := s.flush(); err != nil {
+			s.log().Errorf("flush on shutdown: %v", err)
+		}
+	}
+
+	if err := s.Close(); err != nil && err != ErrPortClosed {
+		return err
+	}
+
+	return waitErr
+}
+
+// stop acquires the link, aborting the command holding it as the policy and
+// ctx demand. Abort only reaches an exchange on the wire, so it is repeated
+// until the command gave the link up.
+func (s *MMDispenser) stop(ctx context.Context) error {
+	abort := s.shutdownPolicy == AbortCommand
+
+	var err error
+
+	tick := time.NewTicker(10 * time.Millisecond)
+	defer tick.Stop()
+
+	for {
+		if abort {
+			_ = s.Abort()
+		}
+
+		select {
+		case s.lock <- struct{}{}:
+			return err
+		case <-ctx.Done():
+			if s.bus != nil {
+				return ctx.Err()
+			}
+
+			s.log().Infof("shutdown: aborting the command in flight")
+			abort, err, ctx = true, ctx.Err(), context.Background()
+		case <-tick.C:
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package mm010_nrc_api_test
+
+import (
+	"bytes"
+	"context"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/protocol"
+	"testing"
+	"time"
+)
+
+func TestShutdown(t *testing.T) {
+	slow := func(p []byte) [][]byte {
+		if len(p) > 3 && p[0] == protocol.RequestStart {
+			// the response is delivered by the test
+			return [][]byte{{protocol.Ack}}
+		}
+
+		return answer(statusPayload)(p)
+	}
+
+	port := newFakePort(slow)
+	c := api.NewTransportConnection("shutdown", port, api.WithTimeout(time.Second))
+
+	done := make(chan error, 1)
+
+	go func() {
+		_, err := c.StatusContext(context.Background())
+		done <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		port.rx <- protocol.EncodeResponse(protocol.CommunicationIdentify, protocol.CommandStatus, statusPayload(protocol.CommandStatus))
+	}()
+
+	if err := c.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("expected the command in flight to complete, got %v", err)
+	}
+
+	if last := port.written[len(port.written)-1]; !bytes.Equal(last, []byte{protocol.Eot}) || !port.closed {
+		t.Fatalf("expected EOT and a closed port, got %X, closed %v", last, port.closed)
+	}
+
+	if err := c.Shutdown(context.Background()); err != api.ErrPortClosed {
+		t.Fatalf("expected ErrPortClosed, got %v", err)
+	}
+
+	// a command not finishing in time is aborted
+	port = newFakePort(slow)
+	c = api.NewTransportConnection("shutdown", port, api.WithTimeout(time.Second),
+		api.WithBlockedSensorPolicy(api.IgnoreBlockedSensor))
+
+	go func() {
+		_, err := c.DispenseContext(context.Background(), 20)
+		done <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	if err := c.Shutdown(ctx); err != context.DeadlineExceeded || time.Since(start) > 500*time.Millisecond {
+		t.Fatalf("expected DeadlineExceeded right after the deadline, got %v after %v", err, time.Since(start))
+	}
+
+	if err := <-done; err != api.ErrAborted {
+		t.Fatalf("expected the dispense to be aborted, got %v", err)
+	}
+
+	if !port.closed {
+		t.Fatal("expected the port to be closed")
+	}
+
+	// or right away under AbortCommand
+	port = newFakePort(slow)
+	c = api.NewTransportConnection("shutdown", port, api.WithTimeout(time.Second),
+		api.WithBlockedSensorPolicy(api.IgnoreBlockedSensor), api.WithShutdownPolicy(api.AbortCommand))
+
+	go func() {
+		_, err := c.DispenseContext(context.Background(), 20)
+		done <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+
+	if err := c.Shutdown(context.Background()); err != nil || !port.closed {
+		t.Fatalf("got %v, closed %v", err, port.closed)
+	}
+
+	if err := <-done; err != api.ErrAborted {
+		t.Fatalf("expected the dispense to be aborted, got %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package mm010_nrc_api
+
+import (
+	"errors"
+	"time"
+
+	"mm010_nrc_api/protocol"
+)
+
+// Stats are line counters kept since the connection was created. Rising
+// retries, NAKs or checksum errors at a steady command rate point to a
+// degrading cable or adapter before dispenses start to fail.
+type Stats struct {
+	Commands uint64 `json:"commands"`
+	// Retries counts retransmissions of a request after a NAK.
+	Retries        uint64        `json:"retries"`
+	Nacks          uint64        `json:"nacks"`
+	ChecksumErrors uint64        `json:"checksum_errors"`
+	Errors         uint64        `json:"errors"`
+	BytesOut       uint64        `json:"bytes_out"`
+	BytesIn        uint64        `json:"bytes_in"`
+	AverageLatency time.Duration `json:"average_latency"`
+}
+
+// Stats returns a snapshot of the line counters. It does not wait for a
+// command in flight.
+func (s *MMDispenser) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.stats
+
+	if stats.Commands > 0 {
+		stats.AverageLatency = s.latency / time.Duration(stats.Commands)
+	}
+
+	return stats
+}
+
+func (s *MMDispenser) countCommand(latency time.Duration, retries int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stats.Commands++
+	s.stats.Retries += uint64(retries)
+	s.latency += latency
+
+	if err != nil {
+		s.stats.Errors++
+	}
+
+	if errors.Is(err, protocol.ErrFrameChecksum) {
+		s.stats.ChecksumErrors++
+	}
+}
+
+func (s *MMDispenser) countBytes(in, out int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stats.BytesIn += uint64(in)
+	s.stats.BytesOut += uint64(out)
+}
+
+func (s *MMDispenser) countNack() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stats.Nacks++
+}
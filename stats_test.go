@@ -0,0 +1,29 @@
+package mm010_nrc_api_test
+
+import (
+	"errors"
+	api "mm010_nrc_api"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	sim, c := connect(t)
+	sim.NakNext(1)
+
+	if _, err := c.Status(); err != nil {
+		t.Fatal(err)
+	}
+
+	sim.GarbleNext(1)
+
+	if _, err := c.Status(); !errors.Is(err, api.ErrChecksumMismatch) {
+		t.Fatalf("expected checksum error, got %v", err)
+	}
+
+	stats := c.Stats()
+
+	if stats.Commands != 2 || stats.Retries != 1 || stats.Nacks != 1 || stats.ChecksumErrors != 1 || stats.Errors != 1 ||
+		stats.BytesIn == 0 || stats.BytesOut == 0 || stats.AverageLatency <= 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
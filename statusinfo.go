@@ -0,0 +1,92 @@
+package mm010_nrc_api
+
+// Recovery is the command that usually brings the dispenser back after a
+// status, once the operator action, if any, was taken.
+type Recovery int
+
+const (
+	NoRecovery Recovery = iota
+	PurgeRecovery
+	ResetRecovery
+)
+
+func (r Recovery) String() string {
+	switch r {
+	case NoRecovery:
+		return "none"
+	case PurgeRecovery:
+		return "purge"
+	case ResetRecovery:
+		return "reset"
+	}
+
+	return "unknown"
+}
+
+func (r Recovery) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// StatusInfo tells a UI what a status code means and what to do about it.
+type StatusInfo struct {
+	Code        StatusCode `json:"code"`
+	Description string     `json:"description"`
+	Severity    Severity   `json:"severity"`
+	// Action is what an operator has to do, empty if nothing.
+	Action string `json:"action,omitempty"`
+	// Fatal codes need the operator before the unit can dispense again.
+	Fatal bool `json:"fatal"`
+	// Retry codes are usually cleared by Recovery and another attempt.
+	Retry    bool     `json:"retry"`
+	Recovery Recovery `json:"recovery"`
+}
+
+var statusCatalog = map[StatusCode]StatusInfo{
+	GoodOperation: {Severity: SeverityInfo},
+	FeedFailure: {Severity: SeverityWarning, Retry: true, Recovery: PurgeRecovery,
+		Action: "refill the cassette or clear a feed jam"},
+	MistrackedNoteAtExit: {Severity: SeverityWarning, Retry: true, Recovery: PurgeRecovery},
+	TooLongAtExit: {Severity: SeverityWarning, Retry: true, Recovery: PurgeRecovery,
+		Action: "check the cassette for taped or folded notes"},
+	BlockedExit: {Severity: SeverityError, Fatal: true, Recovery: ResetRecovery,
+		Action: "clear the jam at the exit"},
+	TransportError: {Severity: SeverityError, Fatal: true, Recovery: ResetRecovery,
+		Action: "open the transport path and remove jammed notes"},
+	DoubleDetectError: {Severity: SeverityWarning, Retry: true, Recovery: PurgeRecovery,
+		Action: "recalibrate the double detect if this repeats"},
+	DivertedError: {Severity: SeverityWarning, Retry: true, Recovery: PurgeRecovery},
+	WrongCount:    {Severity: SeverityWarning, Retry: true, Recovery: PurgeRecovery},
+	NoteMissingAtDD: {Severity: SeverityWarning, Retry: true, Recovery: PurgeRecovery,
+		Action: "check the note path at the double detect"},
+	RejectRateExceeded: {Severity: SeverityError, Fatal: true, Recovery: ResetRecovery,
+		Action: "empty the reject bin and check the note quality"},
+	NonVolatileRAMError: {Severity: SeverityError, Fatal: true,
+		Action: "call service, the controller memory failed"},
+	OperationTimeout: {Severity: SeverityWarning, Retry: true, Recovery: ResetRecovery,
+		Action: "check the motors and the note path if this repeats"},
+	InternalQueError: {Severity: SeverityError, Fatal: true, Recovery: ResetRecovery,
+		Action: "reset the dispenser"},
+	InvalidCommand: {Severity: SeverityError,
+		Action: "check the host software, the device rejected a command"},
+}
+
+// LookupStatus returns the catalog entry of code. Codes missing from the
+// catalog are reported as fatal errors that need service.
+func LookupStatus(code StatusCode) StatusInfo {
+	info, ok := statusCatalog[code]
+
+	if !ok {
+		info = StatusInfo{Severity: SeverityError, Fatal: true, Action: "call service, the status code is unknown"}
+	}
+
+	info.Code = code
+	info.Description = code.String()
+
+	return info
+}
+
+// LookupRejectReason returns the catalog entry of the status a reject reason
+// stands for.
+func LookupRejectReason(r RejectReason) StatusInfo {
+	return LookupStatus(StatusCode(r))
+}
@@ -0,0 +1,29 @@
+package mm010_nrc_api_test
+
+import (
+	api "mm010_nrc_api"
+	"mm010_nrc_api/protocol"
+	"testing"
+)
+
+func TestStatusCatalog(t *testing.T) {
+	for _, code := range protocol.StatusCodes() {
+		info := api.LookupStatus(code)
+
+		if code.IsError() && info.Severity == api.SeverityInfo {
+			t.Errorf("%v: error code catalogued as info", code)
+		}
+
+		if info.Fatal && info.Retry {
+			t.Errorf("%v: fatal and retry recommended", code)
+		}
+	}
+
+	if info := api.LookupStatus(api.BlockedExit); !info.Fatal || info.Recovery != api.ResetRecovery || info.Action == "" {
+		t.Fatalf("unexpected entry %+v", info)
+	}
+
+	if info := api.LookupStatus(0x7E); !info.Fatal || info.Severity != api.SeverityError {
+		t.Fatalf("unknown code should need service, got %+v", info)
+	}
+}
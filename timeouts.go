@@ -0,0 +1,121 @@
+package mm010_nrc_api
+
+import (
+	"time"
+
+	"mm010_nrc_api/protocol"
+)
+
+// CommandClass groups commands by how long the device may take to answer.
+type CommandClass int
+
+const (
+	// QuickCommand is answered from the device's memory: Status, LastStatus,
+	// ConfigurationStatus, ReadData, WriteData and unknown commands.
+	QuickCommand CommandClass = iota
+	// MechanicalCommand runs motors or sensors for a short while: Purge, Reset,
+	// the single note commands, the diagnostics and TestMode.
+	MechanicalCommand
+	// DispenseCommand moves up to MaxNotesPerDispense notes: Dispense and
+	// TestDispense.
+	DispenseCommand
+)
+
+var defaultClassTimeouts = map[CommandClass]time.Duration{
+	MechanicalCommand: 15 * time.Second,
+	DispenseCommand:   60 * time.Second,
+}
+
+// DefaultClassTimeouts returns a copy of the response timeouts of the command
+// classes. QuickCommand has none and uses the timeout set with WithTimeout;
+// for the others the longer of the two applies.
+func DefaultClassTimeouts() map[CommandClass]time.Duration {
+	timeouts := make(map[CommandClass]time.Duration, len(defaultClassTimeouts))
+
+	for class, d := range defaultClassTimeouts {
+		timeouts[class] = d
+	}
+
+	return timeouts
+}
+
+func ClassOf(command Command) CommandClass {
+	switch command {
+	case protocol.CommandDispense, protocol.CommandTestDispense:
+		return DispenseCommand
+	case protocol.CommandPurge, protocol.CommandReset, protocol.CommandSingleNoteDispense,
+		protocol.CommandSingleNoteEject, protocol.CommandDoubleDetectDiagnostics,
+		protocol.CommandSensorDiagnostics, protocol.CommandTestMode:
+		return MechanicalCommand
+	}
+
+	return QuickCommand
+}
+
+// WithClassTimeout sets the response timeout of every command in class.
+// WithCommandTimeout takes precedence for single commands.
+func WithClassTimeout(class CommandClass, timeout time.Duration) Option {
+	return func(s *MMDispenser) {
+		if s.classTimeouts == nil {
+			s.classTimeouts = map[CommandClass]time.Duration{}
+		}
+
+		s.classTimeouts[class] = timeout
+	}
+}
+
+// WithCommandTimeout overrides the response timeout for one command, e.g. to
+// give a large Dispense more time than a Status poll. The timeout covers the
+// whole response: ACK, text and EOT.
+func WithCommandTimeout(command Command, timeout time.Duration) Option {
+	return func(s *MMDispenser) {
+		if s.commandTimeouts == nil {
+			s.commandTimeouts = map[Command]time.Duration{}
+		}
+
+		s.commandTimeouts[command] = timeout
+	}
+}
+
+// WithInterByteTimeout limits the silence allowed between two reads once a
+// response text started arriving. Zero, the default, disables the check.
+func WithInterByteTimeout(timeout time.Duration) Option {
+	return func(s *MMDispenser) {
+		s.interByteTimeout = timeout
+	}
+}
+
+func (s *MMDispenser) commandTimeout(command Command) time.Duration {
+	if d, ok := s.commandTimeouts[command]; ok && d > 0 {
+		return d
+	}
+
+	class := ClassOf(command)
+
+	if d, ok := s.classTimeouts[class]; ok && d > 0 {
+		return d
+	}
+
+	if d := defaultClassTimeouts[class]; d > s.timeout {
+		return d
+	}
+
+	return s.timeout
+}
+
+// deadline is the point in time by which the response to command, just sent,
+// must have been read completely.
+func (s *MMDispenser) deadline(command Command) time.Time {
+	return time.Now().Add(s.commandTimeout(command))
+}
+
+// WithGuardTime sets how long the host waits after the EOT of an exchange
+// before it sends the next request, DefaultGuardTime unless set. Only back to
+// back commands wait; zero disables the wait, e.g. for a simulator.
+func WithGuardTime(d time.Duration) Option {
+	return func(s *MMDispenser) {
+		if d >= 0 {
+			s.link.SetGuardTime(d)
+		}
+	}
+}
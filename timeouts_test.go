@@ -0,0 +1,74 @@
+package mm010_nrc_api_test
+
+import (
+	"context"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/protocol"
+	"testing"
+	"time"
+)
+
+func TestCommandAndInterByteTimeouts(t *testing.T) {
+	silent := newFakePort(func(p []byte) [][]byte { return nil })
+	c := api.NewTransportConnection("silent", silent, api.WithTimeout(time.Minute),
+		api.WithCommandTimeout(protocol.CommandStatus, 50*time.Millisecond))
+
+	start := time.Now()
+
+	if _, err := c.StatusContext(context.Background()); err != api.ErrReadTimeout || time.Since(start) > time.Second {
+		t.Fatalf("expected a quick ErrReadTimeout, got %v after %v", err, time.Since(start))
+	}
+
+	truncated := newFakePort(func(p []byte) [][]byte {
+		if p[0] != protocol.RequestStart {
+			return nil
+		}
+
+		frame := protocol.EncodeResponse(protocol.CommunicationIdentify, protocol.CommandStatus, []byte{0x20, 0x20, 0x20, 0x20})
+
+		return [][]byte{{protocol.Ack}, frame[:4]}
+	})
+	c = api.NewTransportConnection("truncated", truncated, api.WithTimeout(time.Minute),
+		api.WithInterByteTimeout(50*time.Millisecond))
+
+	if _, err := c.StatusContext(context.Background()); err != api.ErrInterByteTimeout {
+		t.Fatalf("expected ErrInterByteTimeout, got %v", err)
+	}
+
+	c = api.NewTransportConnection("silent", silent, api.WithTimeout(time.Minute),
+		api.WithClassTimeout(api.MechanicalCommand, 50*time.Millisecond))
+	start = time.Now()
+
+	if _, _, err := c.PurgeContext(context.Background()); err != api.ErrReadTimeout || time.Since(start) > time.Second {
+		t.Fatalf("expected a quick ErrReadTimeout for the class, got %v after %v", err, time.Since(start))
+	}
+
+	if api.ClassOf(protocol.CommandDispense) != api.DispenseCommand || api.ClassOf(protocol.CommandStatus) != api.QuickCommand {
+		t.Fatal("commands sorted into the wrong class")
+	}
+
+	defaults := api.DefaultClassTimeouts()
+	defaults[api.DispenseCommand] = 0
+
+	if api.DefaultClassTimeouts()[api.DispenseCommand] != time.Minute {
+		t.Fatal("DefaultClassTimeouts handed out the shared map")
+	}
+}
+
+func TestGuardTimeBetweenCommands(t *testing.T) {
+	for _, guard := range []time.Duration{0, 150 * time.Millisecond} {
+		c := api.NewTransportConnection("guard", newFakePort(answer(statusPayload)), api.WithTimeout(time.Second),
+			api.WithGuardTime(guard))
+		start := time.Now()
+
+		for i := 0; i < 2; i++ {
+			if _, err := c.StatusContext(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if elapsed := time.Since(start); elapsed < guard || elapsed > guard+100*time.Millisecond {
+			t.Errorf("guard time %v: two commands took %v", guard, elapsed)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package mm010_nrc_api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	TraceTx = "tx"
+	TraceRx = "rx"
+)
+
+// TraceEntry is one line of a trace: the bytes of a single write to or read
+// from the port, as they were on the wire.
+type TraceEntry struct {
+	Time time.Time `json:"time"`
+	Dir  string    `json:"dir"`
+	Data string    `json:"data"`
+}
+
+func (e TraceEntry) Bytes() ([]byte, error) {
+	return hex.DecodeString(e.Data)
+}
+
+// TraceRecorder writes every transmitted and received chunk as a JSON line.
+type TraceRecorder struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+func NewTraceRecorder(w io.Writer) *TraceRecorder {
+	return &TraceRecorder{enc: json.NewEncoder(w)}
+}
+
+// WithTrace records the traffic of the connection to r.
+func WithTrace(r *TraceRecorder) Option {
+	return func(s *MMDispenser) {
+		s.trace = r
+	}
+}
+
+// Err returns the first error writing the trace. Recording stops after it.
+func (r *TraceRecorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
+
+func (r *TraceRecorder) record(dir string, p []byte) {
+	if len(p) == 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err == nil {
+		r.err = r.enc.Encode(TraceEntry{Time: time.Now(), Dir: dir, Data: hex.EncodeToString(p)})
+	}
+}
+
+// Replay is a Transport that plays a recorded trace back. Every write must
+// match the next recorded transmission; the chunks received after it in the
+// recording then become readable. Timing is not reproduced.
+type Replay struct {
+	mu      sync.Mutex
+	entries []TraceEntry
+	next    int
+	rx      chan []byte
+	pending []byte
+	closed  bool
+}
+
+func NewReplay(r io.Reader) (*Replay, error) {
+	var entries []TraceEntry
+	scanner := bufio.NewScanner(r)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var e TraceEntry
+
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("trace line %d: %v", line, err)
+		}
+
+		if _, err := e.Bytes(); err != nil {
+			return nil, fmt.Errorf("trace line %d: %v", line, err)
+		}
+
+		entries = append(entries, e)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	res := &Replay{entries: entries, rx: make(chan []byte, len(entries)+1)}
+	res.deliver()
+
+	return res, nil
+}
+
+func (r *Replay) Read(p []byte) (int, error) {
+	if len(r.pending) == 0 {
+		chunk, ok := <-r.rx
+
+		if !ok {
+			return 0, io.EOF
+		}
+
+		r.pending = chunk
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+
+	return n, nil
+}
+
+func (r *Replay) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return 0, ErrPortClosed
+	}
+
+	if r.next >= len(r.entries) {
+		return 0, fmt.Errorf("%w: write of [% X] after the end of the trace", ErrReplayMismatch, p)
+	}
+
+	expected, _ := r.entries[r.next].Bytes()
+
+	if !bytes.Equal(expected, p) {
+		return 0, fmt.Errorf("%w: entry %d: expected [% X], got [% X]", ErrReplayMismatch, r.next+1, expected, p)
+	}
+
+	r.next++
+	r.deliver()
+
+	return len(p), nil
+}
+
+func (r *Replay) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.closed {
+		r.closed = true
+		close(r.rx)
+	}
+
+	return nil
+}
+
+// deliver queues the received chunks up to the next transmission.
+func (r *Replay) deliver() {
+	for r.next < len(r.entries) && r.entries[r.next].Dir == TraceRx {
+		data, _ := r.entries[r.next].Bytes()
+		r.rx <- data
+		r.next++
+	}
+}
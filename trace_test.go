@@ -0,0 +1,40 @@
+package mm010_nrc_api_test
+
+import (
+	"bytes"
+	"errors"
+	api "mm010_nrc_api"
+	"testing"
+	"time"
+)
+
+func TestTraceRecordAndReplay(t *testing.T) {
+	var trace bytes.Buffer
+	rec := api.NewTraceRecorder(&trace)
+
+	live := api.NewTransportConnection("live", newFakePort(answer(statusPayload)), api.WithTimeout(time.Second),
+		api.WithTrace(rec))
+	recorded, err := live.Status()
+
+	if err != nil || rec.Err() != nil {
+		t.Fatal(err, rec.Err())
+	}
+
+	replay, err := api.NewReplay(bytes.NewReader(trace.Bytes()))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replayed, err := api.NewTransportConnection("replay", replay, api.WithTimeout(time.Second)).Status()
+
+	if err != nil || replayed != recorded {
+		t.Fatalf("expected the replay to reproduce %+v, got %+v %v", recorded, replayed, err)
+	}
+
+	replay, _ = api.NewReplay(bytes.NewReader(trace.Bytes()))
+
+	if _, _, err := api.NewTransportConnection("replay", replay, api.WithTimeout(time.Second)).Purge(); !errors.Is(err, api.ErrReplayMismatch) {
+		t.Fatalf("expected a replay mismatch for a different command, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+// Package tracing records dispenser commands as OpenTelemetry spans, so the
+// time spent at the dispenser shows up in traces of a withdrawal.
+//
+//	tracer := otel.Tracer("mm010")
+//	c, err := mm010_nrc_api.NewConnection("/dev/ttyUSB0", mm010_nrc_api.WithInterceptor(tracing.Interceptor(tracer)))
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/protocol"
+)
+
+const (
+	AttrCommand     = "mm010.command"
+	AttrCommandCode = "mm010.command.code"
+	AttrRetries     = "mm010.retries"
+	AttrBytesOut    = "mm010.bytes_out"
+	AttrBytesIn     = "mm010.bytes_in"
+	AttrStatusCode  = "mm010.status_code"
+)
+
+// Interceptor returns an api.Interceptor that wraps each command exchange in
+// a client span named after the command. The span carries the command code,
+// the NAK retries, the bytes on the line and, for commands that report one,
+// the device status code.
+func Interceptor(tracer trace.Tracer) api.Interceptor {
+	return func(ctx context.Context, command api.Command, data []byte, next api.Invoker) ([]byte, error) {
+		ctx, span := tracer.Start(ctx, "mm010 "+command.String(),
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String(AttrCommand, command.String()),
+				attribute.Int(AttrCommandCode, int(command)),
+			))
+		defer span.End()
+
+		ctx, info := api.WithExchangeInfo(ctx)
+		response, err := next(ctx, command, data)
+
+		span.SetAttributes(
+			attribute.Int(AttrRetries, info.Retries),
+			attribute.Int(AttrBytesOut, info.BytesOut),
+			attribute.Int(AttrBytesIn, info.BytesIn),
+		)
+
+		if status, ok := statusCode(command, response); ok {
+			span.SetAttributes(attribute.Int(AttrStatusCode, int(status)))
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+
+		return response, err
+	}
+}
+
+func statusCode(command api.Command, response []byte) (protocol.StatusCode, bool) {
+	decoded, err := protocol.DecodeText(command, response)
+
+	if err != nil {
+		return 0, false
+	}
+
+	switch r := decoded.(type) {
+	case protocol.PurgeResponse:
+		return r.Status, true
+	case protocol.DispenseResponse:
+		return r.Status, true
+	case protocol.DiagnosticsResponse:
+		return r.Status, true
+	case protocol.TestModeResponse:
+		return r.Status, true
+	}
+
+	return 0, false
+}
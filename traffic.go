@@ -0,0 +1,77 @@
+package mm010_nrc_api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// WithRecentTraffic keeps the last n frames and control bytes sent and
+// received, for DumpRecentTraffic. Like traces they are not redacted.
+func WithRecentTraffic(n int) Option {
+	return func(s *MMDispenser) {
+		s.traffic = nil
+
+		if n > 0 {
+			s.traffic = &trafficRing{entries: make([]TraceEntry, n)}
+		}
+	}
+}
+
+// DumpRecentTraffic writes the frames kept by WithRecentTraffic as JSON
+// lines, oldest first, e.g. for a support bundle. The format is that of a
+// trace, so the dump can be read by NewReplay. Received bytes that were
+// discarded, not being part of a frame, are kept as they came. Without
+// WithRecentTraffic nothing is written.
+func (s *MMDispenser) DumpRecentTraffic(w io.Writer) error {
+	if s.traffic == nil {
+		return nil
+	}
+
+	enc := json.NewEncoder(w)
+
+	for _, e := range s.traffic.snapshot() {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// trafficRing holds the last entries, guarded by its own lock so it can be
+// dumped while a command runs.
+type trafficRing struct {
+	mu      sync.Mutex
+	entries []TraceEntry
+	next    int
+	full    bool
+}
+
+func (r *trafficRing) add(dir string, p []byte) {
+	if r == nil || len(p) == 0 {
+		return
+	}
+
+	e := TraceEntry{Time: time.Now(), Dir: dir, Data: hex.EncodeToString(p)}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % len(r.entries)
+	r.full = r.full || r.next == 0
+}
+
+func (r *trafficRing) snapshot() []TraceEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.full {
+		return append([]TraceEntry(nil), r.entries[:r.next]...)
+	}
+
+	return append(append([]TraceEntry(nil), r.entries[r.next:]...), r.entries[:r.next]...)
+}
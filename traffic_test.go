@@ -0,0 +1,59 @@
+package mm010_nrc_api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/protocol"
+	"testing"
+	"time"
+)
+
+func TestDumpRecentTraffic(t *testing.T) {
+	port := newFakePort(answer(statusPayload))
+	c := api.NewTransportConnection("fake", port, api.WithTimeout(time.Second), api.WithRecentTraffic(3))
+
+	if _, err := c.Status(); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	if err := c.DumpRecentTraffic(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []api.TraceEntry
+
+	for dec := json.NewDecoder(&buf); dec.More(); {
+		var e api.TraceEntry
+
+		if err := dec.Decode(&e); err != nil {
+			t.Fatal(err)
+		}
+
+		entries = append(entries, e)
+	}
+
+	response := protocol.EncodeResponse(protocol.CommunicationIdentify, protocol.CommandStatus, statusPayload(protocol.CommandStatus))
+	expected := []struct {
+		dir  string
+		data []byte
+	}{{api.TraceRx, response}, {api.TraceTx, []byte{protocol.Ack}}, {api.TraceRx, []byte{protocol.Eot}}}
+
+	if len(entries) != len(expected) {
+		t.Fatalf("expected the last %d frames, got %+v", len(expected), entries)
+	}
+
+	for i, e := range entries {
+		if data, _ := e.Bytes(); e.Dir != expected[i].dir || !bytes.Equal(data, expected[i].data) {
+			t.Fatalf("entry %d: got %s %X, expected %s %X", i, e.Dir, data, expected[i].dir, expected[i].data)
+		}
+	}
+
+	buf.Reset()
+
+	if err := api.NewTransportConnection("fake", port).DumpRecentTraffic(&buf); err != nil || buf.Len() != 0 {
+		t.Fatalf("expected no traffic without WithRecentTraffic, got %q %v", buf.String(), err)
+	}
+}
@@ -0,0 +1,172 @@
+package mm010_nrc_api
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type TransactionPolicy struct {
+	// MaxAttempts is the total number of dispense commands one transaction may
+	// issue. Partial dispenses are retried for the remainder only while the
+	// status code recommends a retry. Zero means a single attempt.
+	MaxAttempts int
+}
+
+type Transaction struct {
+	Requested int              `json:"requested"`
+	Dispensed int              `json:"dispensed"`
+	Rejected  int              `json:"rejected"`
+	Attempts  []DispenseResult `json:"attempts"`
+	// Status is the device status polled after the last attempt.
+	Status   Status    `json:"status"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+}
+
+// DispenseTransaction dispenses count notes, cross-checks every dispense
+// response with LastStatus, retries partial dispenses according to policy and
+// finishes with a status poll. The returned Transaction is filled in as far as
+// the flow got, also when an error is returned.
+func (s *MMDispenser) DispenseTransaction(ctx context.Context, count byte, policy TransactionPolicy) (tx Transaction, err error) {
+	tx = Transaction{Requested: int(count), Started: time.Now()}
+
+	defer func() {
+		tx.Finished = time.Now()
+	}()
+
+	attempts := policy.MaxAttempts
+
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	for len(tx.Attempts) < attempts && tx.Dispensed < tx.Requested {
+		var res, last DispenseResult
+
+		res, err = s.DispenseContext(ctx, byte(tx.Requested-tx.Dispensed))
+
+		if err != nil {
+			return tx, err
+		}
+
+		last, err = s.LastStatusContext(ctx)
+
+		if err != nil {
+			return tx, err
+		}
+
+		tx.Attempts = append(tx.Attempts, res)
+
+		if last.Status != res.Status || last.NotesDispensed != res.NotesDispensed || last.NotesRejected != res.NotesRejected {
+			return tx, fmt.Errorf("%w: dispense reported %d/%d (%v), last status %d/%d (%v)", ErrVerificationFailed,
+				res.NotesDispensed, res.NotesRejected, res.Status, last.NotesDispensed, last.NotesRejected, last.Status)
+		}
+
+		tx.Dispensed += int(res.NotesDispensed)
+		tx.Rejected += int(res.NotesRejected)
+
+		if tx.Dispensed < tx.Requested && !res.RetryRecommended {
+			break
+		}
+	}
+
+	if tx.Status, err = s.StatusContext(ctx); err != nil {
+		return tx, err
+	}
+
+	if tx.Dispensed != tx.Requested {
+		last := tx.Attempts[len(tx.Attempts)-1]
+		return tx, fmt.Errorf("%w: dispensed %d of %d notes, last status %v", ErrPartialDispense, tx.Dispensed, tx.Requested, last.Status)
+	}
+
+	return tx, nil
+}
+
+// WithRejectCap makes DispenseNotes give up on the remaining cycles once the
+// cycles in a row that rejected notes rejected more than n of them, so a
+// worn pick roller does not reject its way through the cassette. Zero, the
+// default, disables the cap.
+func WithRejectCap(n int) Option {
+	return func(s *MMDispenser) {
+		s.rejectCap = n
+	}
+}
+
+// DispenseNotes dispenses total notes in as many dispense cycles as the
+// device's MaxNumberOfNotesInOneTransaction allows and sums up the results
+// in the returned Transaction, one attempt per cycle. It stops at the first
+// cycle that fails or falls short, and when the reject cap is exceeded with
+// cycles left, reporting RejectCapExceededEvent to Watch.
+func (s *MMDispenser) DispenseNotes(ctx context.Context, total int) (tx Transaction, err error) {
+	tx = Transaction{Requested: total, Started: time.Now()}
+
+	defer func() {
+		tx.Finished = time.Now()
+	}()
+
+	if total < 1 {
+		return tx, fmt.Errorf("note count %d out of range", total)
+	}
+
+	limit, err := s.ReadCounter(ctx, MaxNumberOfNotesInOneTransaction)
+
+	if err != nil {
+		return tx, err
+	}
+
+	if limit == 0 || limit > uint64(s.maxNotes()) {
+		limit = uint64(s.maxNotes())
+	}
+
+	consecutive := 0
+
+	for tx.Dispensed < tx.Requested {
+		n := tx.Requested - tx.Dispensed
+
+		if n > int(limit) {
+			n = int(limit)
+		}
+
+		var res DispenseResult
+
+		if res, err = s.DispenseContext(ctx, byte(n)); err != nil {
+			return tx, err
+		}
+
+		tx.Attempts = append(tx.Attempts, res)
+		tx.Dispensed += int(res.NotesDispensed)
+		tx.Rejected += int(res.NotesRejected)
+
+		if res.Status.IsError() || int(res.NotesDispensed) != n {
+			break
+		}
+
+		if res.NotesRejected == 0 {
+			consecutive = 0
+			continue
+		}
+
+		if consecutive += int(res.NotesRejected); s.rejectCap > 0 && consecutive > s.rejectCap && tx.Dispensed < tx.Requested {
+			s.observeRejectCap()
+
+			if tx.Status, err = s.StatusContext(ctx); err != nil {
+				return tx, err
+			}
+
+			return tx, fmt.Errorf("%w: %d notes rejected in a row, dispensed %d of %d notes", ErrRejectCapExceeded,
+				consecutive, tx.Dispensed, tx.Requested)
+		}
+	}
+
+	if tx.Status, err = s.StatusContext(ctx); err != nil {
+		return tx, err
+	}
+
+	if tx.Dispensed != tx.Requested {
+		last := tx.Attempts[len(tx.Attempts)-1]
+		return tx, fmt.Errorf("%w: dispensed %d of %d notes, last status %v", ErrPartialDispense, tx.Dispensed, tx.Requested, last.Status)
+	}
+
+	return tx, nil
+}
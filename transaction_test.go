@@ -0,0 +1,84 @@
+package mm010_nrc_api_test
+
+import (
+	"context"
+	"errors"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/mm010sim"
+	"mm010_nrc_api/protocol"
+	"testing"
+	"time"
+)
+
+func TestDispenseTransactionRetriesPartialDispense(t *testing.T) {
+	sim, c := connect(t)
+	ctx := context.Background()
+
+	sim.FailNext(protocol.CommandDispense, api.DoubleDetectError)
+
+	tx, err := c.DispenseTransaction(ctx, 4, api.TransactionPolicy{MaxAttempts: 2})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tx.Dispensed != 4 || len(tx.Attempts) != 2 || tx.Finished.Before(tx.Started) {
+		t.Fatalf("unexpected transaction %+v", tx)
+	}
+
+	sim.SetNotes(1)
+
+	tx, err = c.DispenseTransaction(ctx, 3, api.TransactionPolicy{})
+
+	if !errors.Is(err, api.ErrPartialDispense) || tx.Dispensed != 1 {
+		t.Fatalf("expected partial dispense of 1 note, got %+v %v", tx, err)
+	}
+}
+
+func TestDispenseNotesSplitsIntoCycles(t *testing.T) {
+	sim, c := connect(t)
+
+	tx, err := c.DispenseNotes(context.Background(), 120)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tx.Attempts) != 3 || tx.Attempts[2].NotesDispensed != 20 || tx.Dispensed != 120 || sim.Notes() != 880 {
+		t.Fatalf("expected cycles of 50, 50 and 20 notes, got %+v", tx)
+	}
+}
+
+func TestDispenseNotesRejectCap(t *testing.T) {
+	sim := mm010sim.New()
+	defer sim.Close()
+
+	c := api.NewTransportConnection("sim", sim.Conn(), api.WithTimeout(time.Second), api.WithGuardTime(0),
+		api.WithRejectCap(5), api.WithPollInterval(10*time.Millisecond))
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	events, err := c.Watch(ctx)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sim.SetRejects(3)
+
+	tx, err := c.DispenseNotes(ctx, 120)
+
+	if !errors.Is(err, api.ErrRejectCapExceeded) || len(tx.Attempts) != 2 || tx.Dispensed != 100 || tx.Rejected != 6 {
+		t.Fatalf("expected the third cycle to be abandoned, got %+v, %v", tx, err)
+	}
+
+	for e := range events {
+		if e.Kind == api.RejectCapExceededEvent {
+			return
+		}
+	}
+
+	t.Fatal("no RejectCapExceededEvent")
+}
@@ -0,0 +1,344 @@
+package mm010_nrc_api_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/mm010sim"
+	"mm010_nrc_api/protocol"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePort plays the device side: every host write is handed to reply, and
+// the chunks it returns are delivered to the host one Read at a time.
+type fakePort struct {
+	mu      sync.Mutex
+	rx      chan []byte
+	pending []byte
+	written [][]byte
+	reply   func(p []byte) [][]byte
+	closed  bool
+}
+
+func newFakePort(reply func(p []byte) [][]byte) *fakePort {
+	return &fakePort{rx: make(chan []byte, 64), reply: reply}
+}
+
+func (f *fakePort) Read(p []byte) (int, error) {
+	if len(f.pending) == 0 {
+		chunk, ok := <-f.rx
+
+		if !ok {
+			return 0, io.EOF
+		}
+
+		f.pending = chunk
+	}
+
+	n := copy(p, f.pending)
+	f.pending = f.pending[n:]
+
+	return n, nil
+}
+
+func (f *fakePort) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.written = append(f.written, append([]byte(nil), p...))
+
+	for _, chunk := range f.reply(p) {
+		f.rx <- chunk
+	}
+
+	return len(p), nil
+}
+
+func (f *fakePort) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.closed {
+		f.closed = true
+		close(f.rx)
+	}
+
+	return nil
+}
+
+// answer replies to every request frame with ACK and the given payload, and to
+// the host ACK with EOT.
+func answer(payload func(cmd protocol.Command) []byte) func(p []byte) [][]byte {
+	return func(p []byte) [][]byte {
+		if len(p) > 3 && p[0] == protocol.RequestStart {
+			cmd := protocol.Command(p[3])
+			return [][]byte{{protocol.Ack}, protocol.EncodeResponse(protocol.CommunicationIdentify, cmd, payload(cmd))}
+		}
+
+		if len(p) == 1 && p[0] == protocol.Ack {
+			return [][]byte{{protocol.Eot}}
+		}
+
+		return nil
+	}
+}
+
+func statusPayload(cmd protocol.Command) []byte {
+	return []byte{0x20 | 0x01, 0x20, 0x20 + 5, 0x20 + 7}
+}
+
+// connect returns a connection to a fresh simulator, both closed when the
+// test ends.
+func connect(t *testing.T) (*mm010sim.Simulator, *api.MMDispenser) {
+	sim := mm010sim.New()
+	c := api.NewTransportConnection("sim", sim.Conn(), api.WithTimeout(time.Second), api.WithGuardTime(0))
+
+	t.Cleanup(func() {
+		_ = c.Close()
+		_ = sim.Close()
+	})
+
+	return sim, c
+}
+
+func TestTransportConnectionStatus(t *testing.T) {
+	port := newFakePort(answer(statusPayload))
+	c := api.NewTransportConnection("fake", port, api.WithTimeout(time.Second))
+
+	status, err := c.Status()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !status.FeedSensorBlocked || status.ExitSensorBlocked || status.AverageThickness != 5 || status.AverageLength != 7 ||
+		status.Sensors != 0x21 || status.Flags != 0x20 {
+		t.Fatalf("unexpected status %+v", status)
+	}
+
+	if !bytes.Equal(port.written[0], protocol.EncodeRequest(protocol.CommunicationIdentify, protocol.CommandStatus)) {
+		t.Fatalf("unexpected request %X", port.written[0])
+	}
+}
+
+func TestCommunicationIdentify(t *testing.T) {
+	device := func(p []byte) [][]byte {
+		if len(p) > 3 && p[0] == protocol.RequestStart {
+			return [][]byte{{protocol.Ack}, protocol.EncodeResponse(0x35, protocol.Command(p[3]), statusPayload(protocol.Command(p[3])))}
+		}
+
+		if len(p) == 1 && p[0] == protocol.Ack {
+			return [][]byte{{protocol.Eot}}
+		}
+
+		return nil
+	}
+
+	c := api.NewTransportConnection("fake", newFakePort(device), api.WithTimeout(time.Second), api.WithCommunicationIdentify(0x35))
+
+	if status, err := c.Status(); err != nil || status.AverageThickness != 5 {
+		t.Fatalf("got %+v, %v", status, err)
+	}
+
+	c = api.NewTransportConnection("fake", newFakePort(device), api.WithTimeout(time.Second))
+
+	_, err := c.Status()
+
+	var identityErr *api.IdentityError
+
+	if !errors.As(err, &identityErr) || identityErr.Expected != 0x30 || identityErr.Received != 0x35 {
+		t.Fatalf("expected an identity mismatch, got %v", err)
+	}
+}
+
+func TestTransportConnectionCanNotReopen(t *testing.T) {
+	c := api.NewTransportConnection("fake", newFakePort(answer(statusPayload)), api.WithTimeout(time.Second))
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Open(); err == nil {
+		t.Fatal("expected reopen of a transport connection to fail")
+	}
+}
+
+func TestContextCancelsPendingCommand(t *testing.T) {
+	silent := newFakePort(func(p []byte) [][]byte { return nil })
+	c := api.NewTransportConnection("silent", silent, api.WithTimeout(time.Minute))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.StatusContext(ctx)
+
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+
+	if time.Since(start) > time.Second {
+		t.Fatal("context deadline was not honoured")
+	}
+}
+
+func TestDispenseFailsOnBlockedSensor(t *testing.T) {
+	port := newFakePort(answer(func(cmd protocol.Command) []byte {
+		if cmd == protocol.CommandStatus {
+			return []byte{0x20 | 0x02, 0x20, 0x20, 0x20}
+		}
+		return []byte{byte(protocol.GoodOperation), 0x21, 0x20}
+	}))
+	c := api.NewTransportConnection("blocked", port, api.WithTimeout(time.Second))
+
+	_, _, _, err := c.Dispense(1)
+
+	var blocked *api.BlockedSensorError
+
+	if !errors.As(err, &blocked) || !blocked.Status.ExitSensorBlocked {
+		t.Fatalf("expected BlockedSensorError, got %v", err)
+	}
+
+	for _, w := range port.written {
+		if len(w) > 3 && protocol.Command(w[3]) == protocol.CommandDispense {
+			t.Fatal("dispense was sent with a blocked sensor")
+		}
+	}
+}
+
+func TestTypedProtocolErrors(t *testing.T) {
+	nack := newFakePort(func(p []byte) [][]byte { return [][]byte{{protocol.Nack}} })
+
+	if _, err := api.NewTransportConnection("nack", nack, api.WithTimeout(time.Second)).Status(); !errors.Is(err, api.ErrNack) {
+		t.Fatalf("expected ErrNack, got %v", err)
+	}
+
+	garbled := newFakePort(func(p []byte) [][]byte {
+		if p[0] != protocol.RequestStart {
+			return nil
+		}
+
+		frame := protocol.EncodeResponse(protocol.CommunicationIdentify, protocol.CommandStatus, []byte{0x20, 0x20, 0x20, 0x20})
+		frame[len(frame)-1] ^= 0xFF
+
+		return [][]byte{{protocol.Ack}, frame}
+	})
+
+	_, err := api.NewTransportConnection("garbled", garbled, api.WithTimeout(time.Second)).Status()
+
+	var protoErr *api.ProtocolError
+
+	if !errors.Is(err, api.ErrChecksumMismatch) || !errors.As(err, &protoErr) || len(protoErr.Frame) == 0 {
+		t.Fatalf("expected checksum ProtocolError with frame, got %v", err)
+	}
+
+	closed := api.NewTransportConnection("closed", newFakePort(answer(statusPayload)), api.WithTimeout(time.Second))
+	_ = closed.Close()
+
+	if _, err := closed.Status(); !errors.Is(err, api.ErrPortClosed) {
+		t.Fatalf("expected ErrPortClosed, got %v", err)
+	}
+}
+
+func TestDispenseContextResult(t *testing.T) {
+	port := newFakePort(answer(func(cmd protocol.Command) []byte {
+		if cmd == protocol.CommandStatus {
+			return []byte{0x20, 0x20, 0x20, 0x20}
+		}
+		return []byte{byte(protocol.FeedFailure), 0x20 + 1, 0x20 + 2}
+	}))
+	c := api.NewTransportConnection("result", port, api.WithTimeout(time.Second))
+
+	res, err := c.DispenseContext(context.Background(), 3)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Status != api.FeedFailure || res.NotesDispensed != 1 || res.NotesRejected != 2 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+
+	if !res.RetryRecommended || res.IsFatal || res.Description != "feed failure" {
+		t.Fatalf("unexpected classification %+v", res)
+	}
+}
+
+func TestConcurrentCommandsDoNotInterleave(t *testing.T) {
+	c := api.NewTransportConnection("concurrent", newFakePort(answer(statusPayload)), api.WithTimeout(time.Second))
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 3)
+
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.Status()
+			errs <- err
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestCloseDuringCommands is meant for the race detector: Close and the
+// setters may run while other goroutines issue commands.
+func TestCloseDuringCommands(t *testing.T) {
+	c := api.NewTransportConnection("concurrent", newFakePort(answer(statusPayload)), api.WithTimeout(time.Second))
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				if _, err := c.StatusContext(context.Background()); err == api.ErrPortClosed {
+					return
+				}
+			}
+		}()
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	c.SetEchoSuppression(false)
+	c.SetBlockedSensorPolicy(api.IgnoreBlockedSensor)
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	wg.Wait()
+
+	if _, err := c.StatusContext(context.Background()); err != api.ErrPortClosed {
+		t.Fatalf("expected ErrPortClosed, got %v", err)
+	}
+}
+
+func TestShortResponsesAreRejected(t *testing.T) {
+	port := newFakePort(answer(func(cmd protocol.Command) []byte {
+		return []byte{byte(api.GoodOperation)}
+	}))
+	c := api.NewTransportConnection("short", port, api.WithTimeout(time.Second))
+
+	if _, _, err := c.PurgeContext(context.Background()); !errors.Is(err, api.ErrResponseFormat) {
+		t.Fatalf("expected ErrResponseFormat for Purge, got %v", err)
+	}
+
+	if _, err := c.StatusContext(context.Background()); !errors.Is(err, api.ErrResponseFormat) {
+		t.Fatalf("expected ErrResponseFormat for Status, got %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+package mm010_nrc_api
+
+import (
+	"context"
+	"time"
+)
+
+type StatusEventKind int
+
+const (
+	FeedSensorBlockedEvent StatusEventKind = iota
+	FeedSensorClearedEvent
+	ExitSensorBlockedEvent
+	ExitSensorClearedEvent
+	ResetEvent
+	RejectRateExceededEvent
+	PollErrorEvent
+	// RejectCapExceededEvent tells that DispenseNotes gave up because of the
+	// reject cap, see WithRejectCap. The device wants maintenance.
+	RejectCapExceededEvent
+)
+
+var statusEventNames = map[StatusEventKind]string{
+	FeedSensorBlockedEvent:  "feed sensor blocked",
+	FeedSensorClearedEvent:  "feed sensor cleared",
+	ExitSensorBlockedEvent:  "exit sensor blocked",
+	ExitSensorClearedEvent:  "exit sensor cleared",
+	ResetEvent:              "reset",
+	RejectRateExceededEvent: "reject rate exceeded",
+	PollErrorEvent:          "poll error",
+	RejectCapExceededEvent:  "reject cap exceeded",
+}
+
+func (k StatusEventKind) String() string {
+	return statusEventNames[k]
+}
+
+type StatusEvent struct {
+	Kind   StatusEventKind
+	Time   time.Time
+	Status Status
+	// Err is set for PollErrorEvent.
+	Err error
+}
+
+func WithPollInterval(interval time.Duration) Option {
+	return func(s *MMDispenser) {
+		if interval > 0 {
+			s.pollInterval = interval
+		}
+	}
+}
+
+// Watch polls Status every poll interval (1s unless set with WithPollInterval)
+// and emits an event for every sensor change, device reset and failed poll.
+// RejectRateExceeded reported by a dispense on this connection, and the
+// reject cap of DispenseNotes being hit, are emitted on the next poll. The
+// channel is closed once ctx is done.
+func (s *MMDispenser) Watch(ctx context.Context) (<-chan StatusEvent, error) {
+	if !s.isOpen() {
+		return nil, ErrPortClosed
+	}
+
+	interval := s.pollInterval
+
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	events := make(chan StatusEvent, 16)
+
+	go func() {
+		defer close(events)
+
+		var prev Status
+		rejectSeq, capSeq := s.rejectSeqs()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			for _, e := range s.poll(ctx, &prev, &rejectSeq, &capSeq) {
+				select {
+				case events <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+func (s *MMDispenser) poll(ctx context.Context, prev *Status, rejectSeq, capSeq *uint64) []StatusEvent {
+	now := time.Now()
+	status, err := s.StatusContext(ctx)
+
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		return []StatusEvent{{Kind: PollErrorEvent, Time: now, Err: err}}
+	}
+
+	var events []StatusEvent
+
+	add := func(kind StatusEventKind) {
+		events = append(events, StatusEvent{Kind: kind, Time: now, Status: status})
+	}
+
+	if status.FeedSensorBlocked != prev.FeedSensorBlocked {
+		if status.FeedSensorBlocked {
+			add(FeedSensorBlockedEvent)
+		} else {
+			add(FeedSensorClearedEvent)
+		}
+	}
+
+	if status.ExitSensorBlocked != prev.ExitSensorBlocked {
+		if status.ExitSensorBlocked {
+			add(ExitSensorBlockedEvent)
+		} else {
+			add(ExitSensorClearedEvent)
+		}
+	}
+
+	if status.ResetSinceLastStatusMessage {
+		add(ResetEvent)
+	}
+
+	rate, capped := s.rejectSeqs()
+
+	if rate != *rejectSeq {
+		*rejectSeq = rate
+		add(RejectRateExceededEvent)
+	}
+
+	if capped != *capSeq {
+		*capSeq = capped
+		add(RejectCapExceededEvent)
+	}
+
+	*prev = status
+
+	return events
+}
+
+func (s *MMDispenser) rejectSeqs() (rate, capped uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.rejectRateExceeded, s.rejectCapExceeded
+}
+
+func (s *MMDispenser) observeResult(res DispenseResult) {
+	if res.Status != RejectRateExceeded {
+		return
+	}
+
+	s.mu.Lock()
+	s.rejectRateExceeded++
+	s.mu.Unlock()
+}
+
+func (s *MMDispenser) observeRejectCap() {
+	s.log().Errorf("reject cap of %d notes exceeded, remaining dispense cycles abandoned", s.rejectCap)
+
+	s.mu.Lock()
+	s.rejectCapExceeded++
+	s.mu.Unlock()
+}
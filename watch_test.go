@@ -0,0 +1,41 @@
+package mm010_nrc_api_test
+
+import (
+	"context"
+	api "mm010_nrc_api"
+	"mm010_nrc_api/mm010sim"
+	"testing"
+	"time"
+)
+
+func TestWatchEmitsSensorAndResetEvents(t *testing.T) {
+	sim := mm010sim.New()
+	defer sim.Close()
+
+	c := api.NewTransportConnection("sim", sim.Conn(), api.WithTimeout(time.Second), api.WithPollInterval(10*time.Millisecond))
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := c.Watch(ctx)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e := <-events; e.Kind != api.ResetEvent {
+		t.Fatalf("expected initial reset event, got %v", e.Kind)
+	}
+
+	sim.SetSensors(false, true)
+
+	if e := <-events; e.Kind != api.ExitSensorBlockedEvent || !e.Status.ExitSensorBlocked {
+		t.Fatalf("expected exit sensor blocked event, got %v", e.Kind)
+	}
+
+	cancel()
+
+	for range events {
+	}
+}
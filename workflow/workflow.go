@@ -0,0 +1,359 @@
+// Package workflow implements the usual cash withdrawal on top of a
+// dispenser, so every ATM application does not have to get it right again.
+//
+//	w := &workflow.Withdrawal{Dispenser: c, Cassette: monitor,
+//		Signer: workflow.HMACSigner{Key: key}, Journal: workflow.NewJournal(f)}
+//	rec, err := w.Run(ctx, "tx-42", 5)
+package workflow
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	api "mm010_nrc_api"
+)
+
+var (
+	ErrCassetteEmpty     = errors.New("cassette is empty")
+	ErrInsufficientNotes = errors.New("cassette holds fewer notes than requested")
+	ErrBadSignature      = errors.New("record signature does not match")
+)
+
+// Outcome is how a withdrawal ended.
+type Outcome int
+
+const (
+	// Completed means every note was dispensed and the device confirmed it.
+	Completed Outcome = iota
+	// Refused means a precondition failed and no note was moved.
+	Refused
+	// Failed means the dispense fell short or was not confirmed; the notes
+	// left in the transport path were purged.
+	Failed
+	// InDoubt means the dispense was sent but its result never arrived.
+	InDoubt
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case Completed:
+		return "completed"
+	case Refused:
+		return "refused"
+	case Failed:
+		return "failed"
+	case InDoubt:
+		return "in doubt"
+	}
+
+	return "unknown"
+}
+
+func (o Outcome) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+func (o *Outcome) UnmarshalText(text []byte) error {
+	for v := Completed; v <= InDoubt; v++ {
+		if v.String() == string(text) {
+			*o = v
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown outcome %q", text)
+}
+
+// Step is one command of a withdrawal.
+type Step struct {
+	Command string `json:"command"`
+	// Status is nil for commands without a status code.
+	Status *api.StatusCode `json:"status,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// Record is the audit record of a withdrawal, written whatever its outcome.
+type Record struct {
+	ID        string    `json:"id"`
+	Device    string    `json:"device,omitempty"`
+	Started   time.Time `json:"started"`
+	Finished  time.Time `json:"finished"`
+	Requested int       `json:"requested"`
+	Dispensed int       `json:"dispensed"`
+	Rejected  int       `json:"rejected"`
+	Purged    int       `json:"purged"`
+	Outcome   Outcome   `json:"outcome"`
+	Steps     []Step    `json:"steps"`
+	Error     string    `json:"error,omitempty"`
+	// Signature is the hex encoded signature of the record without it.
+	Signature string `json:"signature,omitempty"`
+}
+
+// Payload is what gets signed: the record as JSON without its signature.
+func (r Record) Payload() ([]byte, error) {
+	r.Signature = ""
+	return json.Marshal(r)
+}
+
+type Signer interface {
+	Sign(payload []byte) ([]byte, error)
+}
+
+// HMACSigner signs records with HMAC-SHA256.
+type HMACSigner struct {
+	Key []byte
+}
+
+func (h HMACSigner) Sign(payload []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, h.Key)
+	mac.Write(payload)
+
+	return mac.Sum(nil), nil
+}
+
+// Verify returns ErrBadSignature unless r was signed with the key of h.
+func (h HMACSigner) Verify(r Record) error {
+	payload, err := r.Payload()
+
+	if err != nil {
+		return err
+	}
+
+	sig, err := hex.DecodeString(r.Signature)
+	expected, _ := h.Sign(payload)
+
+	if err != nil || !hmac.Equal(sig, expected) {
+		return ErrBadSignature
+	}
+
+	return nil
+}
+
+// Journal keeps the records, e.g. in a file that survives restarts.
+type Journal interface {
+	Append(r Record) error
+}
+
+// JSONJournal appends one JSON object per record to a writer.
+type JSONJournal struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func NewJournal(w io.Writer) *JSONJournal {
+	return &JSONJournal{enc: json.NewEncoder(w)}
+}
+
+func (j *JSONJournal) Append(r Record) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.enc.Encode(r)
+}
+
+// Withdrawal dispenses the notes of one withdrawal at a time. Run checks
+// that the dispenser is ready, dispenses, verifies the result with
+// LastStatus and purges the transport path if anything went wrong. Each run
+// ends with a Record, signed by Signer and appended to Journal if those are
+// set.
+type Withdrawal struct {
+	Dispenser api.Dispenser
+	// Device names the dispenser in the records.
+	Device string
+	// Cassette, if set, must hold the notes requested.
+	Cassette *api.CassetteMonitor
+	Signer   Signer
+	Journal  Journal
+}
+
+// Run dispenses notes for the withdrawal id. The record is returned also with
+// an error, which is that of the run or else of signing or journaling it.
+func (w *Withdrawal) Run(ctx context.Context, id string, notes byte) (Record, error) {
+	rec := Record{ID: id, Device: w.Device, Started: time.Now(), Requested: int(notes)}
+
+	err := w.run(ctx, &rec)
+
+	if err != nil {
+		rec.Error = err.Error()
+	}
+
+	rec.Finished = time.Now()
+
+	if finishErr := w.finish(&rec); err == nil {
+		err = finishErr
+	}
+
+	return rec, err
+}
+
+func (w *Withdrawal) run(ctx context.Context, rec *Record) error {
+	rec.Outcome = Refused
+
+	if err := w.ready(ctx, rec); err != nil {
+		return err
+	}
+
+	res, err := w.Dispenser.DispenseContext(ctx, byte(rec.Requested))
+
+	if len(res.RecoveryTrail) > 0 {
+		for _, t := range res.RecoveryTrail {
+			status := t.Status
+			rec.Steps = append(rec.Steps, Step{Command: t.Command, Status: &status, Error: t.Error})
+		}
+	} else {
+		rec.step("Dispense", &res.Status, err)
+	}
+
+	if err != nil && refusedBeforeSend(err) {
+		return err
+	}
+
+	if err != nil {
+		// the device may have moved notes, only a purge makes sure none is
+		// left in the path
+		rec.Outcome = InDoubt
+		w.purge(ctx, rec)
+
+		return fmt.Errorf("%w: %v", api.ErrDispenseInDoubt, err)
+	}
+
+	rec.Dispensed, rec.Rejected = int(res.NotesDispensed), int(res.NotesRejected)
+	rec.Outcome = Failed
+	err = w.verify(ctx, rec, res)
+
+	if err == nil && (res.Status != api.GoodOperation || rec.Dispensed != rec.Requested) {
+		err = fmt.Errorf("%w: dispensed %d of %d notes, status %v", api.ErrPartialDispense, rec.Dispensed, rec.Requested, res.Status)
+	}
+
+	if err != nil {
+		w.purge(ctx, rec)
+		return err
+	}
+
+	rec.Outcome = Completed
+
+	return nil
+}
+
+// ready checks the preconditions: no maintenance, clear sensors and enough
+// notes in the cassette as far as the monitor knows.
+func (w *Withdrawal) ready(ctx context.Context, rec *Record) error {
+	if w.Dispenser.InMaintenanceMode() {
+		return api.ErrMaintenanceMode
+	}
+
+	if w.Cassette != nil {
+		switch remaining := w.Cassette.Remaining(); {
+		case remaining == 0:
+			return ErrCassetteEmpty
+		case remaining < rec.Requested:
+			return fmt.Errorf("%w: %d left", ErrInsufficientNotes, remaining)
+		}
+	}
+
+	status, err := w.Dispenser.StatusContext(ctx)
+	rec.step("Status", nil, err)
+
+	if err != nil {
+		return err
+	}
+
+	if status.FeedSensorBlocked || status.ExitSensorBlocked {
+		return &api.BlockedSensorError{Status: status}
+	}
+
+	return nil
+}
+
+// verify cross-checks the dispense response with LastStatus.
+func (w *Withdrawal) verify(ctx context.Context, rec *Record, res api.DispenseResult) error {
+	last, err := w.Dispenser.LastStatusContext(ctx)
+	rec.step("LastStatus", &last.Status, err)
+
+	if err != nil {
+		return err
+	}
+
+	status, dispensed, rejected := res.Status, res.NotesDispensed, res.NotesRejected
+
+	if n := len(res.RecoveryTrail); n > 0 {
+		// after a recovery res adds up both attempts, LastStatus knows the
+		// retry only, and not how many of its notes were rejected
+		status, dispensed, rejected = res.RecoveryTrail[n-1].Status, res.RecoveryTrail[n-1].Notes, last.NotesRejected
+	}
+
+	if last.Status != status || last.NotesDispensed != dispensed || last.NotesRejected != rejected {
+		return fmt.Errorf("%w: dispense reported %d/%d (%v), last status %d/%d (%v)", api.ErrVerificationFailed,
+			dispensed, rejected, status, last.NotesDispensed, last.NotesRejected, last.Status)
+	}
+
+	return nil
+}
+
+// refusedBeforeSend tells the errors of DispenseContext that stop it before
+// the dispense command is sent.
+func refusedBeforeSend(err error) bool {
+	var (
+		blocked *api.BlockedSensorError
+		hook    *api.HookError
+	)
+
+	return errors.As(err, &blocked) || errors.As(err, &hook) || errors.Is(err, api.ErrMaintenanceMode) ||
+		errors.Is(err, api.ErrRateLimited) || errors.Is(err, api.ErrCountRange)
+}
+
+// purge clears the transport path after a failure. Its own failure is only
+// recorded, the withdrawal failed already.
+func (w *Withdrawal) purge(ctx context.Context, rec *Record) {
+	status, purged, err := w.Dispenser.PurgeContext(ctx)
+	rec.step("Purge", &status, err)
+	rec.Purged = int(purged)
+}
+
+func (w *Withdrawal) finish(rec *Record) error {
+	if w.Signer != nil {
+		payload, err := rec.Payload()
+
+		if err != nil {
+			return err
+		}
+
+		sig, err := w.Signer.Sign(payload)
+
+		if err != nil {
+			return fmt.Errorf("sign record %s: %w", rec.ID, err)
+		}
+
+		rec.Signature = hex.EncodeToString(sig)
+	}
+
+	if w.Journal != nil {
+		if err := w.Journal.Append(*rec); err != nil {
+			return fmt.Errorf("journal record %s: %w", rec.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// step records a command, with status unless it failed.
+func (r *Record) step(command string, status *api.StatusCode, err error) {
+	s := Step{Command: command}
+
+	if err != nil {
+		s.Error = err.Error()
+	} else if status != nil {
+		code := *status
+		s.Status = &code
+	}
+
+	r.Steps = append(r.Steps, s)
+}
@@ -0,0 +1,88 @@
+package workflow_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	api "mm010_nrc_api"
+	"mm010_nrc_api/mm010sim"
+	"mm010_nrc_api/protocol"
+	"mm010_nrc_api/workflow"
+)
+
+func TestWithdrawal(t *testing.T) {
+	sim := mm010sim.New()
+	defer sim.Close()
+
+	c := api.NewTransportConnection("sim", sim.Conn(), api.WithTimeout(time.Second), api.WithGuardTime(0))
+	defer c.Close()
+
+	var journal bytes.Buffer
+
+	signer := workflow.HMACSigner{Key: []byte("secret")}
+	w := &workflow.Withdrawal{Dispenser: c, Device: "atm-1", Signer: signer, Journal: workflow.NewJournal(&journal)}
+	ctx := context.Background()
+
+	rec, err := w.Run(ctx, "tx-1", 5)
+
+	if err != nil || rec.Outcome != workflow.Completed || rec.Dispensed != 5 || sim.Notes() != 995 {
+		t.Fatalf("got %+v, %v", rec, err)
+	}
+
+	if err := signer.Verify(rec); err != nil {
+		t.Fatal(err)
+	}
+
+	var stored workflow.Record
+
+	if err := json.Unmarshal(journal.Bytes(), &stored); err != nil || signer.Verify(stored) != nil {
+		t.Fatalf("journaled record does not verify: %+v %v", stored, err)
+	}
+
+	stored.Dispensed = 4
+
+	if err := signer.Verify(stored); err != workflow.ErrBadSignature {
+		t.Fatalf("expected a tampered record to fail, got %v", err)
+	}
+
+	// a failed dispense is purged
+	sim.FailNext(protocol.CommandDispense, protocol.FeedFailure)
+
+	rec, err = w.Run(ctx, "tx-2", 5)
+
+	if !errors.Is(err, api.ErrPartialDispense) || rec.Outcome != workflow.Failed || rec.Steps[len(rec.Steps)-1].Command != "Purge" {
+		t.Fatalf("expected a purged failure, got %+v, %v", rec, err)
+	}
+
+	if signer.Verify(rec) != nil || rec.Error == "" {
+		t.Fatalf("expected a signed record of the failure, got %+v", rec)
+	}
+
+	// blocked sensors refuse the withdrawal before anything moves
+	sim.SetSensors(false, true)
+
+	rec, err = w.Run(ctx, "tx-3", 5)
+
+	var blocked *api.BlockedSensorError
+
+	if !errors.As(err, &blocked) || rec.Outcome != workflow.Refused || len(rec.Steps) != 1 || sim.Notes() != 995 {
+		t.Fatalf("expected a refusal, got %+v, %v", rec, err)
+	}
+
+	sim.SetSensors(false, false)
+
+	monitor, _ := api.NewCassetteMonitor(api.CassetteConfig{})
+	w.Cassette = monitor
+
+	if _, err := w.Run(ctx, "tx-4", 5); err != workflow.ErrCassetteEmpty {
+		t.Fatalf("expected ErrCassetteEmpty, got %v", err)
+	}
+
+	if n := bytes.Count(journal.Bytes(), []byte("\n")); n != 4 {
+		t.Fatalf("expected a journal record per withdrawal, got %d", n)
+	}
+}